@@ -13,13 +13,17 @@ package appsec
 
 import (
 	"context"
+	"net/http"
 	"sync"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/httpsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/usersec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf/actions"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/listener/waf"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
 
@@ -41,6 +45,68 @@ func MonitorParsedHTTPBody(ctx context.Context, body any) error {
 	return httpsec.MonitorParsedBody(ctx, body)
 }
 
+// MonitorHTTPResponseBody runs the security monitoring rules on the given *parsed*
+// HTTP response body, such as the value about to be serialized by the handler, and
+// returns if the HTTP request is suspicious and configured to be blocked.
+// It is meant for frameworks serializing the responses themselves, whose response
+// bodies are not monitored automatically.
+// The given context must be the HTTP request context as returned
+// by the Context() method of an HTTP request. Calls to this function are ignored if
+// AppSec is disabled or the given context is incorrect.
+// This function always returns nil when appsec is disabled.
+func MonitorHTTPResponseBody(ctx context.Context, body any) error {
+	if !appsec.Enabled() {
+		appsecDisabledLog.Do(func() { log.Warn("appsec: not enabled. Response body blocking checks won't be performed.") })
+		return nil
+	}
+	return httpsec.MonitorResponseBody(ctx, body)
+}
+
+// OnSecurityEvent registers fn to be called with every security event detected
+// by appsec, such as attacks, along with the rule that matched, the client IP,
+// the user and the trace and span IDs of the request. It allows forwarding the
+// security events to a SIEM pipeline, using for example an events.JSONLinesWriter:
+//
+//	w := events.NewJSONLinesWriter(file)
+//	appsec.OnSecurityEvent(w.Export)
+//
+// The security events are reported when the monitoring of the request ends,
+// regardless of the sampling decision of its trace and of the limits on the
+// number of security events reported in traces. fn is called synchronously
+// from the request goroutine and must therefore return quickly. The returned
+// function unregisters fn.
+func OnSecurityEvent(fn func(*events.SecurityEvent)) (unregister func()) {
+	return waf.AddEventExporter(fn)
+}
+
+// SetHTTPBlockHandler registers h to write the responses of the HTTP requests
+// blocked by appsec that match the given pattern, in place of the default
+// blocking responses, for example to show a branded error page or an API error
+// envelope. info describes the blocking, including the security response ID to
+// show to the blocked user. Patterns use the http.ServeMux syntax, such as
+// "/api/" or "GET /users/{id}", and the empty pattern registers h for every
+// request not matching another pattern. A nil h unregisters the handler of the
+// pattern. It panics when the pattern is invalid or conflicts with an already
+// registered pattern, as http.ServeMux does. An http.Handler can be registered
+// using a function calling its ServeHTTP method.
+// Custom blocking handlers apply to every HTTP integration relying on appsec to
+// block requests, such as net/http, gin, echo and chi.
+func SetHTTPBlockHandler(pattern string, h func(w http.ResponseWriter, r *http.Request, info *events.BlockInfo)) {
+	actions.SetHTTPBlockHandler(pattern, h)
+}
+
+// SetGRPCBlockHandler registers h to return the errors of the gRPC calls blocked
+// by appsec whose full method name matches the given pattern, in place of the
+// default blocking status. The returned error should be a gRPC status error,
+// such as one created with google.golang.org/grpc/status, allowing custom status
+// codes and details. When h returns nil, the default blocking status is used.
+// Patterns use the path.Match syntax, such as "/package.Service/*", and the
+// empty pattern registers h for every call not matching another pattern. A nil h
+// unregisters the handler of the pattern.
+func SetGRPCBlockHandler(pattern string, h func(ctx context.Context, method string, info *events.BlockInfo) error) {
+	actions.SetGRPCBlockHandler(pattern, h)
+}
+
 // SetUser wraps tracer.SetUser() and extends it with user blocking.
 // On top of associating the authenticated user information to the service entry span,
 // it checks whether the given user ID is blocked or not by returning an error when it is.
@@ -48,6 +114,10 @@ func MonitorParsedHTTPBody(ctx context.Context, body any) error {
 // When an error is returned, the caller must immediately abort its execution and the
 // request handler's. The blocking response will be automatically sent by the
 // APM tracer middleware on use according to your blocking configuration.
+// User blocking is supported by the HTTP, gRPC and GraphQL integrations: gRPC calls
+// are answered with the blocking status code, while GraphQL integrations return an
+// error for the blocking resolver and skip the fields resolved afterward when
+// they can.
 // This function always returns nil when appsec is disabled and doesn't block users.
 func SetUser(ctx context.Context, id string, opts ...tracer.UserMonitoringOption) error {
 	s, ok := tracer.SpanFromContext(ctx)
@@ -30,3 +30,21 @@ func IsSecurityError(err error) bool {
 	var secErr *BlockingSecurityEvent
 	return errors.As(err, &secErr)
 }
+
+// BlockInfo describes a request blocked by appsec. It is passed to the custom
+// blocking handlers to let them write the blocking response.
+type BlockInfo struct {
+	// RuleID is the identifier of the security rule that blocked the request, if known.
+	RuleID string
+	// SecurityResponseID is the unique identifier of the blocking response, to be
+	// shown to the blocked user so that the block can be looked up: it is set in
+	// the appsec.security_response_id tag of the service entry span of the request.
+	SecurityResponseID string
+	// StatusCode is the HTTP status code the blocking action is configured with.
+	StatusCode int
+	// GRPCStatusCode is the gRPC status code the blocking action is configured with.
+	GRPCStatusCode int
+	// Type is the response type the blocking action is configured with: "auto",
+	// "json" or "html".
+	Type string
+}
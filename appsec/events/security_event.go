@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// SecurityEvent is a security rule match detected by appsec while monitoring a request.
+type SecurityEvent struct {
+	// Time is the time at which the monitoring of the request ended.
+	Time time.Time `json:"time"`
+	// RuleID is the identifier of the security rule that matched.
+	RuleID string `json:"rule_id"`
+	// RuleName is the name of the security rule that matched.
+	RuleName string `json:"rule_name,omitempty"`
+	// Tags are the tags of the security rule, such as its type and category.
+	Tags map[string]string `json:"tags,omitempty"`
+	// Actions are the actions the security rule is configured to trigger, such as "block".
+	Actions []string `json:"actions,omitempty"`
+	// Parameters are the request values that matched the security rule.
+	Parameters []SecurityEventParameter `json:"parameters,omitempty"`
+	// ClientIP is the IP address of the client that sent the request, if known.
+	ClientIP string `json:"client_ip,omitempty"`
+	// UserID is the identifier of the user associated to the request, if known.
+	UserID string `json:"user_id,omitempty"`
+	// Blocked is true when the request was blocked.
+	Blocked bool `json:"blocked"`
+	// SecurityResponseID is the identifier of the blocking response of the request, if
+	// blocked. It is shown to the blocked user and is the same as BlockInfo.SecurityResponseID.
+	SecurityResponseID string `json:"security_response_id,omitempty"`
+	// TraceID is the identifier of the trace of the request, if traced.
+	TraceID uint64 `json:"trace_id,omitempty"`
+	// SpanID is the identifier of the service entry span of the request, if traced.
+	SpanID uint64 `json:"span_id,omitempty"`
+}
+
+// SecurityEventParameter is a request value that matched a security rule.
+type SecurityEventParameter struct {
+	// Address is the address of the request value, such as "server.request.query".
+	Address string `json:"address"`
+	// KeyPath is the path of the value in the address data, made of map keys and slice indexes.
+	KeyPath []any `json:"key_path,omitempty"`
+	// Value is the value that matched, possibly obfuscated.
+	Value string `json:"value,omitempty"`
+	// Highlight are the parts of the value that matched.
+	Highlight []string `json:"highlight,omitempty"`
+}
+
+// JSONLinesWriter writes security events to an io.Writer as JSON lines, one event per line.
+// It is safe for concurrent use.
+type JSONLinesWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewJSONLinesWriter returns a JSONLinesWriter writing to w. Its Export method can be passed
+// to appsec.OnSecurityEvent to forward the security events to a file or a log pipeline.
+func NewJSONLinesWriter(w io.Writer) *JSONLinesWriter {
+	return &JSONLinesWriter{enc: json.NewEncoder(w)}
+}
+
+// Export writes the security event e as a JSON line. Write errors are reported by Err.
+func (w *JSONLinesWriter) Export(e *SecurityEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.enc.Encode(e); err != nil {
+		w.err = err
+	}
+}
+
+// Err returns the last error that occurred while writing a security event, if any.
+func (w *JSONLinesWriter) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
@@ -9,8 +9,10 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"os"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
 	echotrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/labstack/echo.v4"
 	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
 
@@ -61,11 +63,65 @@ func ExampleMonitorParsedHTTPBody_customContext() {
 	r.Start(":8080")
 }
 
+// Monitor HTTP response bodies serialized by a framework
+func ExampleMonitorHTTPResponseBody() {
+	r := echo.New()
+	r.Use(echotrace.Middleware())
+	r.GET("/user", func(c echo.Context) error {
+		user := map[string]string{"id": c.QueryParam("id")}
+		// Use the SDK to monitor the response body before it is serialized
+		if err := appsec.MonitorHTTPResponseBody(c.Request().Context(), user); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, user)
+	})
+
+	r.Start(":8080")
+}
+
 func userIDFromRequest(r *http.Request) string {
 	return r.Header.Get("user-id")
 }
 
 // Monitor and block requests depending on user ID
+// Forward the security events to a local SIEM pipeline as JSON lines
+func ExampleOnSecurityEvent() {
+	f, err := os.OpenFile("/var/log/appsec-events.jsonl", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return
+	}
+	w := events.NewJSONLinesWriter(f)
+	unregister := appsec.OnSecurityEvent(w.Export)
+	defer func() {
+		unregister()
+		f.Close()
+	}()
+
+	mux := httptrace.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World!\n"))
+	})
+	http.ListenAndServe(":8080", mux)
+}
+
+// Write branded blocking responses for the API routes
+func ExampleSetHTTPBlockHandler() {
+	appsec.SetHTTPBlockHandler("/api/", func(w http.ResponseWriter, _ *http.Request, info *events.BlockInfo) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(info.StatusCode)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":       "access denied",
+			"support_ref": info.SecurityResponseID,
+		})
+	})
+
+	mux := httptrace.NewServeMux()
+	mux.HandleFunc("/api/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]\n"))
+	})
+	http.ListenAndServe(":8080", mux)
+}
+
 func ExampleSetUser() {
 	mux := httptrace.NewServeMux()
 	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
@@ -21,6 +21,7 @@ import (
 	"github.com/vektah/gqlparser/v2"
 	"github.com/vektah/gqlparser/v2/ast"
 	"github.com/vektah/gqlparser/v2/gqlerror"
+	pappsec "gopkg.in/DataDog/dd-trace-go.v1/appsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
 )
@@ -134,6 +135,136 @@ func TestAppSec(t *testing.T) {
 	})
 }
 
+// Test that appsec.SetUser blocks the GraphQL request when the user is blocked, and that the fields
+// resolved afterward are not.
+func TestUserBlocking(t *testing.T) {
+	t.Setenv("DD_APPSEC_RULES", "../../../internal/appsec/testdata/blocking.json")
+	appsec.Start()
+	defer appsec.Stop()
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `type Query {
+		login(id: String!): String!
+		profile: String!
+	}`})
+	var profileResolved bool
+	server := handler.New(&graphql.ExecutableSchemaMock{
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			op := graphql.GetOperationContext(ctx)
+			return func(ctx context.Context) *graphql.Response {
+				var (
+					val    = map[string]any{}
+					errors gqlerror.List
+				)
+				for _, field := range graphql.CollectFields(op, op.Operation.SelectionSet, []string{"Query"}) {
+					ctx := graphql.WithFieldContext(ctx, &graphql.FieldContext{
+						Object:     "Query",
+						Field:      field,
+						Args:       field.ArgumentMap(op.Variables),
+						IsResolver: true,
+					})
+					fieldVal, err := op.ResolverMiddleware(ctx, func(ctx context.Context) (any, error) {
+						switch field.Name {
+						case "login":
+							id := field.ArgumentMap(op.Variables)["id"].(string)
+							return id, pappsec.SetUser(ctx, id)
+						case "profile":
+							profileResolved = true
+							return "profile", nil
+						default:
+							return nil, fmt.Errorf("unknown field: %s", field.Name)
+						}
+					})
+					if err != nil {
+						errors = append(errors, gqlerror.Errorf("%v", err))
+						continue
+					}
+					val[field.Alias] = fieldVal
+				}
+				data, _ := json.Marshal(val)
+				return &graphql.Response{Data: data, Errors: errors}
+			}
+		},
+		SchemaFunc: func() *ast.Schema { return schema },
+	})
+	server.Use(NewTracer())
+	server.AddTransport(transport.POST{})
+	c := client.New(server)
+
+	for name, tc := range map[string]struct {
+		userID  string
+		blocked bool
+	}{
+		"blocked":     {userID: "blocked-user-1", blocked: true},
+		"not-blocked": {userID: "legit-user-1"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+			profileResolved = false
+
+			var resp map[string]any
+			err := c.Post(`query TestQuery($id: String!) { login(id: $id) profile }`, &resp, client.Var("id", tc.userID))
+
+			spans := mt.FinishedSpans()
+			require.NotEmpty(t, spans)
+			root := spans[len(spans)-1]
+			require.Equal(t, tc.userID, root.Tag("usr.id"))
+
+			if !tc.blocked {
+				require.NoError(t, err)
+				require.True(t, profileResolved)
+				require.Nil(t, root.Tag("appsec.blocked"))
+				return
+			}
+			require.Error(t, err)
+			require.False(t, profileResolved)
+			require.Equal(t, true, root.Tag("appsec.blocked"))
+			events, _ := root.Tag("_dd.appsec.json").(string)
+			require.Contains(t, events, "blk-001-002")
+		})
+	}
+}
+
+// Test that API Security extracts the schemas of the GraphQL resolvers
+func TestAPISecurity(t *testing.T) {
+	t.Setenv("DD_API_SECURITY_ENABLED", "true")
+	t.Setenv("DD_API_SECURITY_REQUEST_SAMPLE_RATE", "1.0")
+	appsec.Start()
+	defer appsec.Stop()
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `type Query {
+		topLevel(id: String!): TopLevel!
+	}
+
+	type TopLevel {
+		nested(id: String!): String!
+	}`})
+	server := handler.New(&graphql.ExecutableSchemaMock{
+		ExecFunc:   execFunc,
+		SchemaFunc: func() *ast.Schema { return schema },
+	})
+	server.Use(NewTracer())
+	server.AddTransport(transport.POST{})
+	c := client.New(server)
+
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	var resp map[string]any
+	err := c.Post(`query { topLevel(id: "top") { nested(id: "nested") } }`, &resp)
+	require.NoError(t, err)
+
+	spans := mt.FinishedSpans()
+	require.NotEmpty(t, spans)
+	require.NotNil(t, spans[len(spans)-1].Tag("_dd.appsec.s.graphql.resolver"))
+}
+
 type appSecQuery struct{}
 
 func (q *appSecQuery) TopLevel(_ context.Context, args struct{ ID string }) (*appSecTopLevel, error) {
@@ -181,6 +181,12 @@ func (t *gqlTracer) InterceptField(ctx context.Context, next graphql.Resolver) (
 	})
 	defer func() { op.Finish(graphqlsec.ResolveOperationRes{Data: res, Error: err}) }()
 
+	// Don't resolve the field when the request was blocked by AppSec, such as by the user blocking
+	// of a previous resolver calling appsec.SetUser
+	if err = op.BlockingError(); err != nil {
+		return nil, err
+	}
+
 	res, err = next(ctx)
 	return
 }
@@ -80,6 +80,9 @@ func WrapPartitionConsumer(pc sarama.PartitionConsumer, opts ...Option) sarama.P
 			// reinject the span context so consumers can pick it up
 			tracer.Inject(next.Context(), carrier)
 			setConsumeCheckpoint(cfg.dataStreamsEnabled, cfg.groupID, msg)
+			if cfg.dataStreamsEnabled {
+				datastreams.SetSchemaTags(next, ext.SchemaOperationDeserialization, msg.Topic, msg.Value)
+			}
 
 			wrapped.messages <- msg
 
@@ -305,6 +308,14 @@ func startProducerSpan(cfg *config, version sarama.KafkaVersion, msg *sarama.Pro
 		// re-inject the span context so consumers can pick it up
 		tracer.Inject(span.Context(), carrier)
 	}
+	if cfg.dataStreamsEnabled && msg.Value != nil {
+		// only encode the value of the messages whose schema is sampled
+		if weight := datastreams.SampleSchema(ext.SchemaOperationSerialization, msg.Topic); weight > 0 {
+			if value, err := msg.Value.Encode(); err == nil {
+				datastreams.SetSampledSchemaTags(span, ext.SchemaOperationSerialization, msg.Topic, value, weight)
+			}
+		}
+	}
 	return span
 }
 
@@ -8,6 +8,7 @@ package tracing
 import (
 	"math"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/datastreams"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -82,5 +83,8 @@ func (tr *KafkaTracer) StartConsumeSpan(msg Message) ddtrace.Span {
 	span, _ := tracer.StartSpanFromContext(tr.ctx, tr.consumerSpanName, opts...)
 	// reinject the span context so consumers can pick it up
 	tracer.Inject(span.Context(), carrier)
+	if tr.dsmEnabled {
+		datastreams.SetSchemaTags(span, ext.SchemaOperationDeserialization, msg.GetTopicPartition().GetTopic(), msg.GetValue())
+	}
 	return span
 }
@@ -8,6 +8,7 @@ package tracing
 import (
 	"math"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/datastreams"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -72,6 +73,9 @@ func (tr *KafkaTracer) StartProduceSpan(msg Message) ddtrace.Span {
 	span, _ := tracer.StartSpanFromContext(tr.ctx, tr.producerSpanName, opts...)
 	// inject the span context so consumers can pick it up
 	tracer.Inject(span.Context(), carrier)
+	if tr.dsmEnabled {
+		datastreams.SetSchemaTags(span, ext.SchemaOperationSerialization, msg.GetTopicPartition().GetTopic(), msg.GetValue())
+	}
 	return span
 }
 
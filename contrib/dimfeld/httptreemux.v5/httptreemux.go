@@ -49,13 +49,14 @@ func New(opts ...RouterOption) *Router {
 // ServeHTTP implements http.Handler.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	resource := r.config.resourceNamer(r.TreeMux, w, req)
-	route, _ := getRoute(r.TreeMux, w, req)
+	route, params, _ := getRoute(r.TreeMux, w, req)
 	// pass r.TreeMux to avoid a circular reference panic on calling r.ServeHTTP
 	httptrace.TraceAndServe(r.TreeMux, w, req, &httptrace.ServeConfig{
-		Service:  r.config.serviceName,
-		Resource: resource,
-		SpanOpts: r.config.spanOpts,
-		Route:    route,
+		Service:     r.config.serviceName,
+		Resource:    resource,
+		SpanOpts:    r.config.spanOpts,
+		Route:       route,
+		RouteParams: params,
 	})
 }
 
@@ -84,13 +85,14 @@ func NewWithContext(opts ...RouterOption) *ContextRouter {
 // ServeHTTP implements http.Handler.
 func (r *ContextRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	resource := r.config.resourceNamer(r.TreeMux, w, req)
-	route, _ := getRoute(r.TreeMux, w, req)
+	route, params, _ := getRoute(r.TreeMux, w, req)
 	// pass r.TreeMux to avoid a circular reference panic on calling r.ServeHTTP
 	httptrace.TraceAndServe(r.TreeMux, w, req, &httptrace.ServeConfig{
-		Service:  r.config.serviceName,
-		Resource: resource,
-		SpanOpts: r.config.spanOpts,
-		Route:    route,
+		Service:     r.config.serviceName,
+		Resource:    resource,
+		SpanOpts:    r.config.spanOpts,
+		Route:       route,
+		RouteParams: params,
 	})
 }
 
@@ -99,18 +101,19 @@ func (r *ContextRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 // route from the request. If the lookup fails to find a match the route is set
 // to "unknown".
 func defaultResourceNamer(router *httptreemux.TreeMux, w http.ResponseWriter, req *http.Request) string {
-	route, ok := getRoute(router, w, req)
+	route, _, ok := getRoute(router, w, req)
 	if !ok {
 		route = "unknown"
 	}
 	return req.Method + " " + route
 }
 
-func getRoute(router *httptreemux.TreeMux, w http.ResponseWriter, req *http.Request) (string, bool) {
+// getRoute returns the route matched by the request, along with its route parameters.
+func getRoute(router *httptreemux.TreeMux, w http.ResponseWriter, req *http.Request) (string, map[string]string, bool) {
 	route := req.URL.Path
 	lr, found := router.Lookup(w, req)
 	if !found {
-		return "", false
+		return "", nil, false
 	}
 	routeLen := len(route)
 	trailingSlash := route[routeLen-1] == '/' && routeLen > 1
@@ -147,7 +150,7 @@ func getRoute(router *httptreemux.TreeMux, w http.ResponseWriter, req *http.Requ
 		newP = "/:" + k
 		route = strings.Replace(route, oldP, newP, 1)
 	}
-	return route, true
+	return route, lr.Params, true
 }
 
 // isSupportedRedirectStatus checks if the given HTTP status code is a supported redirect status.
@@ -0,0 +1,153 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+// Package zap provides functions to correlate logs and traces using the go.uber.org/zap package (https://github.com/uber-go/zap).
+package zap // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/go.uber.org/zap"
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/logtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const componentName = "go.uber.org/zap"
+
+func init() {
+	telemetry.LoadIntegration(componentName)
+	tracer.MarkIntegrationImported("go.uber.org/zap")
+}
+
+// contextKey is the key of the fields created by Context.
+const contextKey = "dd.context"
+
+// TraceFields returns the fields correlating a log entry with the span found in ctx:
+// trace and span IDs, service, environment and version. It returns nil if there is
+// no span in ctx.
+func TraceFields(ctx context.Context) []zap.Field {
+	f, ok := logtrace.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	fields := make([]zap.Field, 0, 5)
+	if f.TraceID128 != "" {
+		fields = append(fields, zap.String(ext.LogKeyTraceID, f.TraceID128))
+	} else {
+		fields = append(fields, zap.Uint64(ext.LogKeyTraceID, f.TraceID))
+	}
+	fields = append(fields, zap.Uint64(ext.LogKeySpanID, f.SpanID))
+	if f.Service != "" {
+		fields = append(fields, zap.String(logtrace.KeyService, f.Service))
+	}
+	if f.Env != "" {
+		fields = append(fields, zap.String(logtrace.KeyEnv, f.Env))
+	}
+	if f.Version != "" {
+		fields = append(fields, zap.String(logtrace.KeyVersion, f.Version))
+	}
+	return fields
+}
+
+// Context returns a field carrying ctx. A core returned by WrapCore replaces it with
+// the TraceFields of ctx; other cores ignore it.
+func Context(ctx context.Context) zap.Field {
+	return zap.Field{Key: contextKey, Type: zapcore.SkipType, Interface: ctx}
+}
+
+// WrapCore returns a core attaching tracing information to the entries logged with
+// a Context field.
+func WrapCore(c zapcore.Core) zapcore.Core {
+	return &core{c}
+}
+
+type core struct {
+	zapcore.Core
+}
+
+// With adds structured context to the core, resolving Context fields.
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{c.Core.With(expandFields(fields))}
+}
+
+// Check adds the core to the checked entry if the wrapped core is enabled at
+// the level of the entry, so that the entry is written through Write.
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Core.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write writes the entry, resolving Context fields. The entry is checked by
+// the wrapped core first, so that the cores filtering entries in Check, such
+// as samplers or the cores of a tee with different levels, keep doing so.
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ce := c.Core.Check(ent, nil)
+	if ce == nil {
+		return nil
+	}
+	var out errorOutput
+	ce.ErrorOutput = &out
+	// Write returns the checked entry to its pool
+	ce.Write(expandFields(fields)...)
+	if len(out) > 0 {
+		return errors.New(strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// errorOutput records the errors of the wrapped cores reported by
+// zapcore.CheckedEntry.Write.
+type errorOutput []byte
+
+func (o *errorOutput) Write(p []byte) (int, error) {
+	*o = append(*o, p...)
+	return len(p), nil
+}
+
+func (*errorOutput) Sync() error { return nil }
+
+// expandFields replaces the fields created by Context with the trace fields of
+// their context. fields is returned as is if it has no such field.
+func expandFields(fields []zapcore.Field) []zapcore.Field {
+	i := indexContextField(fields)
+	if i < 0 {
+		return fields
+	}
+	out := make([]zapcore.Field, 0, len(fields)+4)
+	out = append(out, fields[:i]...)
+	for _, f := range fields[i:] {
+		if ctx, ok := contextOf(f); ok {
+			out = append(out, TraceFields(ctx)...)
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+func indexContextField(fields []zapcore.Field) int {
+	for i, f := range fields {
+		if _, ok := contextOf(f); ok {
+			return i
+		}
+	}
+	return -1
+}
+
+func contextOf(f zapcore.Field) (context.Context, bool) {
+	if f.Key != contextKey || f.Type != zapcore.SkipType {
+		return nil, false
+	}
+	ctx, ok := f.Interface.(context.Context)
+	return ctx, ok
+}
@@ -0,0 +1,163 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package zap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	internallog "gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+func newLogger(b *bytes.Buffer) *zap.Logger {
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	return zap.New(WrapCore(zapcore.NewCore(enc, zapcore.AddSync(b), zapcore.InfoLevel)))
+}
+
+func decodeLogs(t *testing.T, b *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var logs []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimRight(b.String(), "\n"), "\n") {
+		var data map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &data))
+		logs = append(logs, data)
+	}
+	return logs
+}
+
+func TestWrapCore(t *testing.T) {
+	t.Setenv("DD_ENV", "test-env")
+	t.Setenv("DD_VERSION", "1.2.3")
+	tracer.Start(tracer.WithLogger(internallog.DiscardLogger{}), tracer.WithService("test-service"))
+	defer tracer.Stop()
+
+	var b bytes.Buffer
+	logger := newLogger(&b)
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "test")
+	defer span.Finish()
+
+	logger.Info("with context", Context(ctx))
+	logger.With(Context(ctx)).Error("with child logger")
+	logger.Info("without context", zap.String("key", "value"))
+
+	logs := decodeLogs(t, &b)
+	require.Len(t, logs, 3)
+	for _, data := range logs[:2] {
+		assert.Equal(t, float64(span.Context().TraceID()), data[ext.LogKeyTraceID])
+		assert.Equal(t, float64(span.Context().SpanID()), data[ext.LogKeySpanID])
+		assert.Equal(t, "test-service", data["dd.service"])
+		assert.Equal(t, "test-env", data["dd.env"])
+		assert.Equal(t, "1.2.3", data["dd.version"])
+		assert.NotContains(t, data, contextKey)
+	}
+	assert.NotContains(t, logs[2], ext.LogKeyTraceID)
+	assert.Equal(t, "value", logs[2]["key"])
+}
+
+func TestWrapCoreTracerConfig(t *testing.T) {
+	t.Setenv("DD_ENV", "test-env")
+	t.Setenv("DD_VERSION", "1.2.3")
+	tracer.Start(tracer.WithLogger(internallog.DiscardLogger{}), tracer.WithEnv("tracer-env"), tracer.WithServiceVersion("4.5.6"))
+	defer tracer.Stop()
+
+	var b bytes.Buffer
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "test")
+	defer span.Finish()
+	newLogger(&b).Info("with context", Context(ctx))
+
+	logs := decodeLogs(t, &b)
+	require.Len(t, logs, 1)
+	// The configuration of the tracer takes precedence over the environment variables
+	assert.Equal(t, "tracer-env", logs[0]["dd.env"])
+	assert.Equal(t, "4.5.6", logs[0]["dd.version"])
+}
+
+func TestWrapCoreSampler(t *testing.T) {
+	var b bytes.Buffer
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	// log the first entry of each message per minute only
+	sampler := zapcore.NewSamplerWithOptions(zapcore.NewCore(enc, zapcore.AddSync(&b), zapcore.InfoLevel), time.Minute, 1, 0)
+	logger := zap.New(WrapCore(sampler))
+
+	for i := 0; i < 5; i++ {
+		logger.Info("sampled", Context(context.Background()))
+	}
+	logger.Debug("disabled")
+
+	logs := decodeLogs(t, &b)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "sampled", logs[0]["msg"])
+}
+
+func TestWrapCoreTee(t *testing.T) {
+	tracer.Start(tracer.WithLogger(internallog.DiscardLogger{}))
+	defer tracer.Stop()
+
+	var info, errs bytes.Buffer
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	tee := zapcore.NewTee(
+		zapcore.NewCore(enc, zapcore.AddSync(&info), zapcore.InfoLevel),
+		zapcore.NewCore(enc.Clone(), zapcore.AddSync(&errs), zapcore.ErrorLevel),
+	)
+	logger := zap.New(WrapCore(tee))
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "test")
+	defer span.Finish()
+	logger.Info("info", Context(ctx))
+	logger.Error("error", Context(ctx))
+
+	// Each core of the tee keeps applying its own level
+	infoLogs := decodeLogs(t, &info)
+	require.Len(t, infoLogs, 2)
+	errLogs := decodeLogs(t, &errs)
+	require.Len(t, errLogs, 1)
+	assert.Equal(t, "error", errLogs[0]["msg"])
+	for _, l := range append(infoLogs, errLogs...) {
+		assert.Equal(t, float64(span.Context().SpanID()), l[ext.LogKeySpanID])
+		assert.NotContains(t, l, contextKey)
+	}
+}
+
+func TestTraceFields(t *testing.T) {
+	tracer.Start(tracer.WithLogger(internallog.DiscardLogger{}))
+	defer tracer.Stop()
+
+	assert.Nil(t, TraceFields(context.Background()))
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "test")
+	defer span.Finish()
+
+	t.Run("64-bit", func(t *testing.T) {
+		fields := TraceFields(ctx)
+		require.NotEmpty(t, fields)
+		assert.Equal(t, zap.Uint64(ext.LogKeyTraceID, span.Context().TraceID()), fields[0])
+		assert.Equal(t, zap.Uint64(ext.LogKeySpanID, span.Context().SpanID()), fields[1])
+	})
+
+	t.Run("128-bit", func(t *testing.T) {
+		t.Setenv("DD_TRACE_128_BIT_TRACEID_LOGGING_ENABLED", "true")
+		var b bytes.Buffer
+		newLogger(&b).Info("message", TraceFields(ctx)...)
+
+		logs := decodeLogs(t, &b)
+		require.Len(t, logs, 1)
+		traceID, ok := logs[0][ext.LogKeyTraceID].(string)
+		require.True(t, ok)
+		assert.Len(t, traceID, 32)
+	})
+}
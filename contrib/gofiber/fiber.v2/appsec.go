@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package fiber
+
+import (
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/httpsec"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// pathParamsMonitoredKey is the key of the request local value set once its
+// path parameters were monitored by AppSecPathParams.
+const pathParamsMonitoredKey = "dd-trace-go:appsec:path-params-monitored"
+
+// AppSecPathParams returns a route handler monitoring the path parameters of
+// the request with AppSec before the following handlers of the route run, so
+// that requests can be blocked according to them. It must be registered in a
+// route, before its handlers, as fiber only resolves the path parameters once
+// the request is routed:
+//
+//	app.Get("/users/:id", fibertrace.AppSecPathParams(), handler)
+//
+// Without it, the Middleware monitors the path parameters after the handlers
+// of the route ran: a request blocked according to them gets the blocking
+// response, but its handlers were already executed.
+func AppSecPathParams() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !appsec.Enabled() {
+			return c.Next()
+		}
+		c.Locals(pathParamsMonitoredKey, true)
+		if err := httpsec.MonitorPathParams(c.UserContext(), c.AllParams()); err != nil {
+			// The blocking response is written by the Middleware
+			return nil
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,201 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package fiber
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pappsec "gopkg.in/DataDog/dd-trace-go.v1/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppSec(t *testing.T) {
+	t.Setenv("DD_APPSEC_WAF_TIMEOUT", "1h") // Functionally unlimited
+	appsec.Start()
+	defer appsec.Stop()
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.All("/lfi/*", func(c *fiber.Ctx) error {
+		return c.SendString("Hello World!\n")
+	})
+	app.All("/path0.0/:myPathParam0/path0.1/:myPathParam1/path0.2/:myPathParam2", func(c *fiber.Ctx) error {
+		return c.SendString("Hello Params!\n")
+	})
+	app.All("/body", func(c *fiber.Ctx) error {
+		pappsec.MonitorParsedHTTPBody(c.UserContext(), "$globals")
+		return c.SendString("Hello Body!\n")
+	})
+
+	for _, tc := range []struct {
+		name   string
+		url    string
+		body   string
+		rule   string
+		status int
+	}{
+		{
+			// Send an LFI attack (according to appsec rule id crs-930-110)
+			name:   "request-uri",
+			url:    "/lfi/../../../secret.txt",
+			body:   "Hello World!\n",
+			rule:   "crs-930-110",
+			status: http.StatusOK,
+		},
+		{
+			// Send a security scanner attack (according to appsec rule id crs-913-120)
+			name:   "path-params",
+			url:    "/path0.0/param0/path0.1/param1/path0.2/appscan_fingerprint",
+			body:   "Hello Params!\n",
+			rule:   "crs-913-120",
+			status: http.StatusOK,
+		},
+		{
+			// Send a PHP injection attack via the request parsed body
+			name:   "SDK-body",
+			url:    "/body",
+			body:   "Hello Body!\n",
+			rule:   "crs-933-130",
+			status: http.StatusOK,
+		},
+		{
+			name:   "status-code",
+			url:    "/etc/",
+			rule:   "nfd-000-001",
+			status: http.StatusNotFound,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			res, err := app.Test(httptest.NewRequest("POST", tc.url, nil))
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.Equal(t, tc.status, res.StatusCode)
+			if tc.body != "" {
+				b, err := io.ReadAll(res.Body)
+				require.NoError(t, err)
+				require.Equal(t, tc.body, string(b))
+			}
+
+			finished := mt.FinishedSpans()
+			require.Len(t, finished, 1)
+			event, _ := finished[0].Tag("_dd.appsec.json").(string)
+			require.Contains(t, event, tc.rule)
+		})
+	}
+}
+
+// Test that IP and user blocking work by using custom rules/rules data
+func TestBlocking(t *testing.T) {
+	t.Setenv("DD_APPSEC_RULES", "../../../internal/appsec/testdata/blocking.json")
+	appsec.Start()
+	defer appsec.Stop()
+	if !appsec.Enabled() {
+		t.Skip("AppSec needs to be enabled for this test")
+	}
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.All("/", func(c *fiber.Ctx) error {
+		return c.SendString("Hello World!\n")
+	})
+	app.All("/user", func(c *fiber.Ctx) error {
+		if err := pappsec.SetUser(c.UserContext(), c.Get("user-id")); err != nil {
+			return nil
+		}
+		return c.SendString("Hello User!\n")
+	})
+	app.All("/params/:name", AppSecPathParams(), func(c *fiber.Ctx) error {
+		return c.SendString("Hello Params!\n")
+	})
+	var unmonitoredCalls int
+	app.All("/unmonitored-params/:name", func(c *fiber.Ctx) error {
+		unmonitoredCalls++
+		return c.SendString("Hello Params!\n")
+	})
+
+	for _, tc := range []struct {
+		name    string
+		url     string
+		headers map[string]string
+		status  int
+		body    string
+	}{
+		{
+			name:    "ip-block",
+			url:     "/",
+			headers: map[string]string{"x-forwarded-for": "1.2.3.4"},
+			status:  http.StatusForbidden,
+		},
+		{
+			name:    "user-block",
+			url:     "/user",
+			headers: map[string]string{"user-id": "blocked-user-1"},
+			status:  http.StatusForbidden,
+		},
+		{
+			// The path parameters are monitored before the route handler
+			name:   "path-params-block",
+			url:    "/params/$globals",
+			status: http.StatusForbidden,
+		},
+		{
+			// Without AppSecPathParams, the path parameters are monitored once the route handler ran:
+			// its response is replaced by the blocking response
+			name:   "path-params-block-after-handler",
+			url:    "/unmonitored-params/$globals",
+			status: http.StatusForbidden,
+		},
+		{
+			name:    "no-block",
+			url:     "/user",
+			headers: map[string]string{"x-forwarded-for": "1.2.3.5", "user-id": "legit-user-1"},
+			status:  http.StatusOK,
+			body:    "Hello User!\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			req := httptest.NewRequest("POST", tc.url, nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			res, err := app.Test(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.Equal(t, tc.status, res.StatusCode)
+			b, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			if tc.body != "" {
+				require.Equal(t, tc.body, string(b))
+			} else {
+				require.NotContains(t, string(b), "Hello")
+			}
+
+			finished := mt.FinishedSpans()
+			require.Len(t, finished, 1)
+			if tc.status == http.StatusForbidden {
+				require.Equal(t, true, finished[0].Tag("appsec.blocked"))
+			}
+		})
+	}
+	// The route handler still ran for the request blocked according to its path parameters
+	require.Equal(t, 1, unmonitoredCalls)
+}
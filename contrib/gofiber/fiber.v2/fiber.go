@@ -7,14 +7,17 @@
 package fiber // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/gofiber/fiber.v2"
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
 	"strconv"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/fasthttptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
 
@@ -29,6 +32,15 @@ func init() {
 }
 
 // Middleware returns middleware that will trace incoming requests.
+//
+// When AppSec is enabled, the request is monitored and blocked before the
+// following handlers run, except for its path parameters: fiber only resolves
+// them when routing the request to the handlers of its route, once the
+// middleware called c.Next(), and doesn't expose them to the middleware before.
+// They are therefore monitored once the handlers of the route returned, and a
+// request blocked according to them gets the blocking response in place of the
+// response of the handlers, which still ran. Register AppSecPathParams in the
+// routes to monitor their path parameters before their handlers run.
 func Middleware(opts ...Option) func(c *fiber.Ctx) error {
 	cfg := new(config)
 	defaults(cfg)
@@ -73,11 +85,31 @@ func Middleware(opts ...Option) func(c *fiber.Ctx) error {
 
 		defer span.Finish()
 
+		afterHandle, handled := func(map[string]string) {}, false
+		if appsec.Enabled() {
+			ctx, afterHandle, handled = fasthttptrace.BeforeHandle(ctx, c.Context(), span, nil)
+		}
+
 		// pass the span through the request UserContext
 		c.SetUserContext(ctx)
 
 		// pass the execution down the line
-		err := c.Next()
+		var err error
+		if !handled {
+			err = c.Next()
+		}
+		// The status of fiber errors, such as fiber.ErrNotFound when no route matches, is only set by the
+		// error handler of the app once the middleware returned: set it now so that it is monitored and tagged.
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			c.Status(fiberErr.Code)
+		}
+		// The route parameters are only known once the request was routed to its handler
+		if monitored, _ := c.Locals(pathParamsMonitoredKey).(bool); monitored {
+			afterHandle(nil)
+		} else {
+			afterHandle(c.AllParams())
+		}
 
 		span.SetTag(ext.ResourceName, cfg.resourceNamer(c))
 		span.SetTag(ext.HTTPRoute, c.Route().Path)
@@ -171,6 +171,27 @@ func TestCustomError(t *testing.T) {
 	assert.Equal("/err", span.Tag(ext.HTTPRoute))
 }
 
+func TestNotFound(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	router := fiber.New()
+	router.Use(Middleware())
+
+	response, err := router.Test(httptest.NewRequest("GET", "/missing", nil))
+	assert.Nil(err)
+	defer response.Body.Close()
+	assert.Equal(404, response.StatusCode)
+
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 1)
+	assert.Equal("404", spans[0].Tag(ext.HTTPCode))
+	var fiberErr *fiber.Error
+	assert.ErrorAs(spans[0].Tag(ext.Error).(error), &fiberErr)
+	assert.Equal(404, fiberErr.Code)
+}
+
 func TestUserContext(t *testing.T) {
 	assert := assert.New(t)
 	mt := mocktracer.Start()
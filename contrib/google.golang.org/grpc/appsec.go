@@ -20,7 +20,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf/actions"
 )
 
-func applyAction(blockAtomic *atomic.Pointer[actions.BlockGRPC], err *error) bool {
+func applyAction(ctx context.Context, method string, blockAtomic *atomic.Pointer[actions.BlockGRPC], err *error) bool {
 	if blockAtomic == nil {
 		return false
 	}
@@ -30,6 +30,12 @@ func applyAction(blockAtomic *atomic.Pointer[actions.BlockGRPC], err *error) boo
 		return false
 	}
 
+	// Use the error of the custom block handler registered with appsec.SetGRPCBlockHandler, if any
+	if e := block.CustomError(ctx, method); e != nil {
+		*err = e
+		return true
+	}
+
 	code, e := block.GRPCWrapper()
 	*err = status.Error(codes.Code(code), e.Error())
 	return true
@@ -52,26 +58,26 @@ func appsecUnaryHandlerMiddleware(method string, span ddtrace.Span, handler grpc
 
 		defer func() {
 			var statusCode int
-			if statusErr, ok := rpcErr.(interface{ GRPCStatus() *status.Status }); ok && !applyAction(blockAtomic, &rpcErr) {
+			if statusErr, ok := rpcErr.(interface{ GRPCStatus() *status.Status }); ok && !applyAction(ctx, method, blockAtomic, &rpcErr) {
 				statusCode = int(statusErr.GRPCStatus().Code())
 			}
 			op.Finish(span, grpcsec.HandlerOperationRes{StatusCode: statusCode})
-			applyAction(blockAtomic, &rpcErr)
+			applyAction(ctx, method, blockAtomic, &rpcErr)
 		}()
 
 		// Check if a blocking condition was detected so far with the start operation event (ip blocking, metadata blocking, etc.)
-		if applyAction(blockAtomic, &rpcErr) {
+		if applyAction(ctx, method, blockAtomic, &rpcErr) {
 			return
 		}
 
 		// As of our gRPC abstract operation definition, we must fake a receive operation for unary RPCs (the same model fits both unary and streaming RPCs)
-		if _ = grpcsec.MonitorRequestMessage(ctx, req); applyAction(blockAtomic, &rpcErr) {
+		if _ = grpcsec.MonitorRequestMessage(ctx, req); applyAction(ctx, method, blockAtomic, &rpcErr) {
 			return
 		}
 
 		defer func() {
 			_ = grpcsec.MonitorResponseMessage(ctx, res)
-			applyAction(blockAtomic, &rpcErr)
+			applyAction(ctx, method, blockAtomic, &rpcErr)
 		}()
 
 		// Call the original handler - let the deferred function above handle the blocking condition and return error
@@ -100,16 +106,16 @@ func appsecStreamHandlerMiddleware(method string, span ddtrace.Span, handler grp
 
 		defer func() {
 			var statusCode int
-			if res, ok := rpcErr.(interface{ Status() codes.Code }); ok && !applyAction(blockAtomic, &rpcErr) {
+			if res, ok := rpcErr.(interface{ Status() codes.Code }); ok && !applyAction(ctx, method, blockAtomic, &rpcErr) {
 				statusCode = int(res.Status())
 			}
 
 			op.Finish(span, grpcsec.HandlerOperationRes{StatusCode: statusCode})
-			applyAction(blockAtomic, &rpcErr)
+			applyAction(ctx, method, blockAtomic, &rpcErr)
 		}()
 
 		// Check if a blocking condition was detected so far with the start operation event (ip blocking, metadata blocking, etc.)
-		if applyAction(blockAtomic, &rpcErr) {
+		if applyAction(ctx, method, blockAtomic, &rpcErr) {
 			return
 		}
 
@@ -118,6 +124,7 @@ func appsecStreamHandlerMiddleware(method string, span ddtrace.Span, handler grp
 			ServerStream:     stream,
 			handlerOperation: op,
 			ctx:              ctx,
+			method:           method,
 			action:           blockAtomic,
 			rpcErr:           &rpcErr,
 		})
@@ -128,6 +135,7 @@ type appsecServerStream struct {
 	grpc.ServerStream
 	handlerOperation *grpcsec.HandlerOperation
 	ctx              context.Context
+	method           string
 	action           *atomic.Pointer[actions.BlockGRPC]
 	rpcErr           *error
 }
@@ -136,7 +144,7 @@ type appsecServerStream struct {
 // execution with AppSec.
 func (ss *appsecServerStream) RecvMsg(msg any) (err error) {
 	defer func() {
-		if _ = grpcsec.MonitorRequestMessage(ss.ctx, msg); applyAction(ss.action, ss.rpcErr) {
+		if _ = grpcsec.MonitorRequestMessage(ss.ctx, msg); applyAction(ss.ctx, ss.method, ss.action, ss.rpcErr) {
 			err = *ss.rpcErr
 		}
 	}()
@@ -144,7 +152,7 @@ func (ss *appsecServerStream) RecvMsg(msg any) (err error) {
 }
 
 func (ss *appsecServerStream) SendMsg(msg any) error {
-	if _ = grpcsec.MonitorResponseMessage(ss.ctx, msg); applyAction(ss.action, ss.rpcErr) {
+	if _ = grpcsec.MonitorResponseMessage(ss.ctx, msg); applyAction(ss.ctx, ss.method, ss.action, ss.rpcErr) {
 		return *ss.rpcErr
 	}
 	return ss.ServerStream.SendMsg(msg)
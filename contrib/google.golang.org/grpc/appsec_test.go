@@ -14,6 +14,7 @@ import (
 	"testing"
 
 	pappsec "gopkg.in/DataDog/dd-trace-go.v1/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
 
@@ -242,6 +243,150 @@ func TestBlocking(t *testing.T) {
 	})
 }
 
+// Test that the user monitoring functions of the appsec package block gRPC calls and tag the root span
+func TestUserMonitoring(t *testing.T) {
+	t.Setenv("DD_APPSEC_RULES", "../../../internal/appsec/testdata/blocking.json")
+	appsec.Start()
+	defer appsec.Stop()
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	for _, tc := range []struct {
+		name         string
+		md           metadata.MD
+		expectedRule string
+		expectedTags map[string]any
+	}{
+		{
+			name:         "user blocking",
+			md:           metadata.Pairs("user-id", "blocked-user-1"),
+			expectedRule: "blk-001-002",
+			expectedTags: map[string]any{"usr.id": "blocked-user-1"},
+		},
+		{
+			name:         "login failure blocking",
+			md:           metadata.Pairs("user-id", "ato-user-1", "login", "failure"),
+			expectedRule: "blk-001-003",
+			expectedTags: map[string]any{
+				"appsec.events.users.login.failure.track":      true,
+				"appsec.events.users.login.failure.usr.id":     "ato-user-1",
+				"appsec.events.users.login.failure.usr.exists": true,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			withClient := func(t *testing.T, do func(client FixtureClient)) {
+				rig, err := newAppsecRig(t, false)
+				require.NoError(t, err)
+				defer func() { assert.NoError(t, rig.Close()) }()
+				mt := mocktracer.Start()
+				defer mt.Stop()
+
+				do(rig.client)
+
+				finished := mt.FinishedSpans()
+				require.True(t, len(finished) >= 1)
+				root := finished[len(finished)-1]
+				events, _ := root.Tag("_dd.appsec.json").(string)
+				require.Contains(t, events, tc.expectedRule)
+				require.Equal(t, true, root.Tag("appsec.blocked"))
+				for k, v := range tc.expectedTags {
+					require.Equal(t, v, root.Tag(k), k)
+				}
+			}
+
+			t.Run("unary", func(t *testing.T) {
+				withClient(t, func(client FixtureClient) {
+					ctx := metadata.NewOutgoingContext(context.Background(), tc.md)
+					reply, err := client.Ping(ctx, &FixtureRequest{Name: "hello"})
+					require.Nil(t, reply)
+					require.Equal(t, codes.Aborted, status.Code(err))
+				})
+			})
+
+			t.Run("stream", func(t *testing.T) {
+				withClient(t, func(client FixtureClient) {
+					ctx := metadata.NewOutgoingContext(context.Background(), tc.md)
+					stream, err := client.StreamPing(ctx)
+					require.NoError(t, err)
+					defer func() { assert.NoError(t, stream.CloseSend()) }()
+
+					if err := stream.Send(&FixtureRequest{Name: "hello"}); err != io.EOF {
+						require.NoError(t, err)
+					}
+					reply, err := stream.Recv()
+					require.Equal(t, codes.Aborted, status.Code(err))
+					require.Nil(t, reply)
+				})
+			})
+		})
+	}
+}
+
+// Test that API Security extracts the schemas of the gRPC messages and metadata
+func TestAPISecurity(t *testing.T) {
+	t.Setenv("DD_API_SECURITY_ENABLED", "true")
+	t.Setenv("DD_API_SECURITY_REQUEST_SAMPLE_RATE", "1.0")
+	appsec.Start()
+	defer appsec.Stop()
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	rig, err := newAppsecRig(t, false)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, rig.Close()) }()
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("my-metadata", "is-beautiful"))
+	_, err = rig.client.Ping(ctx, &FixtureRequest{Name: "hello"})
+	require.NoError(t, err)
+
+	finished := mt.FinishedSpans()
+	require.Len(t, finished, 1)
+	for _, tag := range []string{"_dd.appsec.s.req.body", "_dd.appsec.s.req.headers", "_dd.appsec.s.res.body"} {
+		require.NotNil(t, finished[0].Tag(tag), tag)
+	}
+}
+
+func TestCustomBlockHandler(t *testing.T) {
+	t.Setenv("DD_APPSEC_RULES", "../../../internal/appsec/testdata/blocking.json")
+	appsec.Start()
+	defer appsec.Stop()
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	var blockInfo *events.BlockInfo
+	pappsec.SetGRPCBlockHandler("/grpc.Fixture/*", func(_ context.Context, _ string, info *events.BlockInfo) error {
+		blockInfo = info
+		return status.Errorf(codes.PermissionDenied, "blocked: %s", info.SecurityResponseID)
+	})
+	defer pappsec.SetGRPCBlockHandler("/grpc.Fixture/*", nil)
+
+	rig, err := newAppsecRig(t, false)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, rig.Close()) }()
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("x-client-ip", "1.2.3.4"))
+	reply, err := rig.client.Ping(ctx, &FixtureRequest{Name: "hello"})
+	require.Nil(t, reply)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.NotNil(t, blockInfo)
+	require.Equal(t, "blk-001-001", blockInfo.RuleID)
+	require.Contains(t, status.Convert(err).Message(), blockInfo.SecurityResponseID)
+
+	// The block can be looked up from the security response ID
+	finished := mt.FinishedSpans()
+	require.NotEmpty(t, finished)
+	root := finished[len(finished)-1]
+	require.Equal(t, blockInfo.SecurityResponseID, root.Tag("appsec.security_response_id"))
+}
+
 func TestPasslist(t *testing.T) {
 	// This custom rule file includes two rules detecting the same sec event, a grpc metadata value containing "zouzou",
 	// but only one of them is passlisted (custom-1 is passlisted, custom-2 is not and must trigger).
@@ -386,23 +531,29 @@ type appsecFixtureServer struct {
 }
 
 func (s *appsecFixtureServer) StreamPing(stream Fixture_StreamPingServer) (err error) {
-	ctx := stream.Context()
-	md, _ := metadata.FromIncomingContext(ctx)
-	ids := md.Get("user-id")
-	if len(ids) > 0 {
-		if err := pappsec.SetUser(ctx, ids[0]); err != nil {
-			return err
-		}
+	if err := monitorFixtureUser(stream.Context()); err != nil {
+		return err
 	}
 	return s.s.StreamPing(stream)
 }
 func (s *appsecFixtureServer) Ping(ctx context.Context, in *FixtureRequest) (*FixtureReply, error) {
+	if err := monitorFixtureUser(ctx); err != nil {
+		return nil, err
+	}
+	return s.s.Ping(ctx, in)
+}
+
+// monitorFixtureUser calls the user monitoring functions of the appsec package with the user ID given in the
+// "user-id" metadata: appsec.TrackUserLoginFailureEvent when the "login" metadata is "failure", appsec.SetUser otherwise.
+func monitorFixtureUser(ctx context.Context) error {
 	md, _ := metadata.FromIncomingContext(ctx)
 	ids := md.Get("user-id")
-	if len(ids) > 0 {
-		if err := pappsec.SetUser(ctx, ids[0]); err != nil {
-			return nil, err
-		}
+	if len(ids) == 0 {
+		return nil
 	}
-	return s.s.Ping(ctx, in)
+	if login := md.Get("login"); len(login) > 0 && login[0] == "failure" {
+		pappsec.TrackUserLoginFailureEvent(ctx, ids[0], true, nil)
+		return nil
+	}
+	return pappsec.SetUser(ctx, ids[0])
 }
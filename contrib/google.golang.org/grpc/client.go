@@ -26,6 +26,8 @@ type clientStream struct {
 	ctx    context.Context
 	cfg    *config
 	method string
+	// stats accumulates the messages of the stream. It is nil if the call is not traced.
+	stats *messageStats
 }
 
 func (cs *clientStream) Context() context.Context {
@@ -33,8 +35,9 @@ func (cs *clientStream) Context() context.Context {
 }
 
 func (cs *clientStream) RecvMsg(m interface{}) (err error) {
+	var span ddtrace.Span
 	if _, ok := cs.cfg.untracedMethods[cs.method]; cs.cfg.traceStreamMessages && !ok {
-		span, _ := startSpanFromContext(
+		span, _ = startSpanFromContext(
 			cs.Context(),
 			cs.method,
 			"grpc.message",
@@ -48,12 +51,17 @@ func (cs *clientStream) RecvMsg(m interface{}) (err error) {
 		defer func() { finishWithError(span, err, cs.cfg) }()
 	}
 	err = cs.ClientStream.RecvMsg(m)
+	if err == nil {
+		recordMessage(cs.stats, span, directionReceived, m)
+		setPayloadTag(cs.cfg, cs.method, span, tagResponsePayload, m)
+	}
 	return err
 }
 
 func (cs *clientStream) SendMsg(m interface{}) (err error) {
+	var span ddtrace.Span
 	if _, ok := cs.cfg.untracedMethods[cs.method]; cs.cfg.traceStreamMessages && !ok {
-		span, _ := startSpanFromContext(
+		span, _ = startSpanFromContext(
 			cs.Context(),
 			cs.method,
 			"grpc.message",
@@ -67,6 +75,10 @@ func (cs *clientStream) SendMsg(m interface{}) (err error) {
 		defer func() { finishWithError(span, err, cs.cfg) }()
 	}
 	err = cs.ClientStream.SendMsg(m)
+	if err == nil {
+		recordMessage(cs.stats, span, directionSent, m)
+		setPayloadTag(cs.cfg, cs.method, span, tagRequestPayload, m)
+	}
 	return err
 }
 
@@ -91,7 +103,10 @@ func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
 				methodKind = methodKindClientStream
 			}
 		}
-		var stream grpc.ClientStream
+		var (
+			stream grpc.ClientStream
+			stats  *messageStats
+		)
 		if _, ok := cfg.untracedMethods[method]; cfg.traceStreamCalls && !ok {
 			var (
 				span tracer.Span
@@ -114,8 +129,10 @@ func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
 				setSpanTargetFromPeer(span, *p)
 			}
 
+			stats = new(messageStats)
 			go func() {
 				<-stream.Context().Done()
+				stats.setTags(span)
 				finishWithError(span, stream.Context().Err(), cfg)
 			}()
 		} else {
@@ -137,6 +154,7 @@ func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
 			cfg:          cfg,
 			method:       method,
 			ctx:          ctx,
+			stats:        stats,
 		}, nil
 	}
 }
@@ -158,6 +176,10 @@ func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
 			func(ctx context.Context, opts []grpc.CallOption) error {
 				return invoker(ctx, method, req, reply, cc, opts...)
 			})
+		setPayloadTag(cfg, method, span, tagRequestPayload, req)
+		if err == nil {
+			setPayloadTag(cfg, method, span, tagResponsePayload, reply)
+		}
 		finishWithError(span, err, cfg)
 		return err
 	}
@@ -130,6 +130,143 @@ func TestUnary(t *testing.T) {
 	}
 }
 
+func TestStreamingMessageStats(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	rig, err := newRig(true, WithServiceName("grpc"))
+	require.NoError(t, err, "error setting up rig")
+	defer func() { assert.NoError(t, rig.Close()) }()
+
+	stream, err := rig.client.StreamPing(context.Background())
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, stream.Send(&FixtureRequest{Name: "pass"}))
+		_, err := stream.Recv()
+		require.NoError(t, err)
+	}
+	require.NoError(t, stream.CloseSend())
+	stream.Recv()
+
+	// 2 call spans, 4 message spans on each side
+	waitForSpans(mt, 10)
+
+	var clientMessages, serverMessages []mocktracer.Span
+	for _, span := range mt.FinishedSpans() {
+		switch span.OperationName() {
+		case "grpc.client":
+			assert.Equal(t, 2, span.Tag(tagMessagesSent))
+			assert.Equal(t, 2, span.Tag(tagMessagesReceived))
+			assert.Equal(t, int64(12), span.Tag(tagBytesSent))
+			assert.Equal(t, int64(16), span.Tag(tagBytesReceived))
+		case "grpc.server":
+			assert.Equal(t, 2, span.Tag(tagMessagesSent))
+			assert.Equal(t, 2, span.Tag(tagMessagesReceived))
+			assert.Equal(t, int64(16), span.Tag(tagBytesSent))
+			assert.Equal(t, int64(12), span.Tag(tagBytesReceived))
+		case "grpc.message":
+			if span.Tag(tagMessageDirection) == nil {
+				// the message spans of the final RecvMsg calls returning io.EOF
+				continue
+			}
+			if span.Tag(tagMessageSize) == 6 && span.Tag(tagMessageDirection) == directionSent ||
+				span.Tag(tagMessageSize) == 8 && span.Tag(tagMessageDirection) == directionReceived {
+				clientMessages = append(clientMessages, span)
+			} else {
+				serverMessages = append(serverMessages, span)
+			}
+		}
+	}
+	for _, msgs := range [][]mocktracer.Span{clientMessages, serverMessages} {
+		require.Len(t, msgs, 4)
+		seqs := map[interface{}][]interface{}{}
+		for _, span := range msgs {
+			seqs[span.Tag(tagMessageDirection)] = append(seqs[span.Tag(tagMessageDirection)], span.Tag(tagMessageSeq))
+			assert.NotNil(t, span.Tag(tagMessageLatency))
+		}
+		assert.ElementsMatch(t, []interface{}{1, 2}, seqs[directionSent])
+		assert.ElementsMatch(t, []interface{}{1, 2}, seqs[directionReceived])
+	}
+}
+
+func TestPayloadCapture(t *testing.T) {
+	t.Run("unary", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		rig, err := newRig(true, WithPayloadCapture("/grpc.Fixture/Ping"), WithPayloadMaxSize(10))
+		require.NoError(t, err, "error setting up rig")
+		defer func() { assert.NoError(t, rig.Close()) }()
+
+		_, err = rig.client.Ping(context.Background(), &FixtureRequest{Name: "pass"})
+		require.NoError(t, err)
+
+		spans := mt.FinishedSpans()
+		require.Len(t, spans, 2)
+		for _, span := range spans {
+			assert.Equal(t, `{"name":"p`, span.Tag(tagRequestPayload))
+			assert.Equal(t, true, span.Tag(tagRequestPayload+tagPayloadTruncatedSuffix))
+			assert.Equal(t, `{"message"`, span.Tag(tagResponsePayload))
+			assert.Equal(t, true, span.Tag(tagResponsePayload+tagPayloadTruncatedSuffix))
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		rig, err := newRig(true, WithPayloadCapture(), WithRedactedFields("message"))
+		require.NoError(t, err, "error setting up rig")
+		defer func() { assert.NoError(t, rig.Close()) }()
+
+		stream, err := rig.client.StreamPing(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&FixtureRequest{Name: "pass"}))
+		_, err = stream.Recv()
+		require.NoError(t, err)
+		require.NoError(t, stream.CloseSend())
+		stream.Recv()
+
+		// 2 call spans, 3 message spans on each side
+		waitForSpans(mt, 8)
+
+		var requests, responses int
+		for _, span := range mt.FinishedSpans() {
+			if span.OperationName() != "grpc.message" {
+				continue
+			}
+			if p := span.Tag(tagRequestPayload); p != nil {
+				assert.Equal(t, `{"name":"pass"}`, p)
+				requests++
+			}
+			if p := span.Tag(tagResponsePayload); p != nil {
+				assert.Equal(t, `{"message":"[REDACTED]"}`, p)
+				responses++
+			}
+			assert.Nil(t, span.Tag(tagRequestPayload+tagPayloadTruncatedSuffix))
+		}
+		assert.Equal(t, 2, requests)
+		assert.Equal(t, 2, responses)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		rig, err := newRig(true, WithPayloadCapture("/grpc.Fixture/StreamPing"))
+		require.NoError(t, err, "error setting up rig")
+		defer func() { assert.NoError(t, rig.Close()) }()
+
+		_, err = rig.client.Ping(context.Background(), &FixtureRequest{Name: "pass"})
+		require.NoError(t, err)
+
+		for _, span := range mt.FinishedSpans() {
+			assert.Nil(t, span.Tag(tagRequestPayload))
+			assert.Nil(t, span.Tag(tagResponsePayload))
+		}
+	})
+}
+
 func TestStreaming(t *testing.T) {
 	// creates a stream, then sends/recvs two pings, then closes the stream
 	runPings := func(t *testing.T, ctx context.Context, client FixtureClient) {
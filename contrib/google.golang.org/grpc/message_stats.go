@@ -0,0 +1,160 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/runtime/protoimpl"
+)
+
+// maxMessageEvents is the maximum number of message events recorded on a single RPC span.
+const maxMessageEvents = 128
+
+const (
+	directionSent     = "sent"
+	directionReceived = "received"
+)
+
+// messageEvent holds information about a message exchanged on a stream. It is
+// encoded the same way as the span events added through the OpenTelemetry API.
+type messageEvent struct {
+	Name         string                 `json:"name"`
+	TimeUnixNano int64                  `json:"time_unix_nano"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// messageStats accumulates the messages exchanged during an RPC.
+type messageStats struct {
+	mu            sync.Mutex
+	sent          int
+	received      int
+	bytesSent     int64
+	bytesReceived int64
+	last          time.Time
+	withEvents    bool
+	events        []messageEvent
+	dropped       int
+}
+
+type messageStatsKey struct{}
+
+func contextWithMessageStats(ctx context.Context, s *messageStats) context.Context {
+	return context.WithValue(ctx, messageStatsKey{}, s)
+}
+
+func messageStatsFromContext(ctx context.Context) (*messageStats, bool) {
+	s, ok := ctx.Value(messageStatsKey{}).(*messageStats)
+	return s, ok
+}
+
+// enableEvents enables recording a span event for each message.
+func (s *messageStats) enableEvents() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.withEvents = true
+}
+
+// record records a message of the given size sent or received at t. It returns the
+// sequence number of the message in its direction, starting at 1, and the time
+// elapsed since the previous message of the RPC, which is zero for the first one.
+func (s *messageStats) record(direction string, size int, t time.Time) (seq int, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if direction == directionSent {
+		s.sent++
+		s.bytesSent += int64(size)
+		seq = s.sent
+	} else {
+		s.received++
+		s.bytesReceived += int64(size)
+		seq = s.received
+	}
+	if !s.last.IsZero() {
+		latency = t.Sub(s.last)
+	}
+	s.last = t
+	if !s.withEvents {
+		return seq, latency
+	}
+	if len(s.events) >= maxMessageEvents {
+		s.dropped++
+		return seq, latency
+	}
+	s.events = append(s.events, messageEvent{
+		Name:         "grpc.message",
+		TimeUnixNano: t.UnixNano(),
+		Attributes: map[string]interface{}{
+			tagMessageDirection: direction,
+			tagMessageSeq:       seq,
+			tagMessageSize:      size,
+			tagMessageLatency:   latency.Nanoseconds(),
+		},
+	})
+	return seq, latency
+}
+
+// setTags sets the aggregate tags of the RPC, and its message events if any, on span.
+func (s *messageStats) setTags(span ddtrace.Span) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	span.SetTag(tagMessagesSent, s.sent)
+	span.SetTag(tagMessagesReceived, s.received)
+	span.SetTag(tagBytesSent, s.bytesSent)
+	span.SetTag(tagBytesReceived, s.bytesReceived)
+	if len(s.events) > 0 {
+		b, err := json.Marshal(s.events)
+		if err != nil {
+			log.Debug("contrib/google.golang.org/grpc: failed to encode message events: %v", err)
+		} else {
+			span.SetTag(tagEvents, string(b))
+		}
+	}
+	if s.dropped > 0 {
+		span.SetTag(tagMessageEventsDropped, s.dropped)
+	}
+}
+
+// recordMessage records message m of a stream traced by the interceptors into the
+// stats of the call, if it is traced, and tags the span of the message, if any.
+func recordMessage(stats *messageStats, span ddtrace.Span, direction string, m interface{}) {
+	if stats == nil && span == nil {
+		return
+	}
+	size := messageSize(m)
+	if stats == nil {
+		span.SetTag(tagMessageDirection, direction)
+		span.SetTag(tagMessageSize, size)
+		return
+	}
+	seq, latency := stats.record(direction, size, time.Now())
+	if span == nil {
+		return
+	}
+	span.SetTag(tagMessageDirection, direction)
+	span.SetTag(tagMessageSeq, seq)
+	span.SetTag(tagMessageSize, size)
+	span.SetTag(tagMessageLatency, latency.Nanoseconds())
+}
+
+// messageSize returns the serialized size of m, or 0 if m is not a protobuf message.
+func messageSize(m interface{}) int {
+	switch m := m.(type) {
+	case proto.Message:
+		return proto.Size(m)
+	case protoiface.MessageV1:
+		return proto.Size(protoimpl.X.ProtoMessageV2Of(m))
+	}
+	return 0
+}
@@ -31,6 +31,7 @@ type config struct {
 	nonErrorCodes       map[codes.Code]bool
 	traceStreamCalls    bool
 	traceStreamMessages bool
+	streamMessageEvents bool
 	noDebugStack        bool
 	ignoredMethods      map[string]struct{}
 	untracedMethods     map[string]struct{}
@@ -38,6 +39,10 @@ type config struct {
 	ignoredMetadata     map[string]struct{}
 	withRequestTags     bool
 	withErrorDetailTags bool
+	payloadCapture      bool
+	payloadMethods      map[string]struct{}
+	payloadMaxSize      int
+	redactedFields      map[string]struct{}
 	spanOpts            []ddtrace.StartSpanOption
 	tags                map[string]interface{}
 }
@@ -51,6 +56,7 @@ func defaults(cfg *config) {
 	cfg.traceStreamCalls = true
 	cfg.traceStreamMessages = true
 	cfg.nonErrorCodes = map[codes.Code]bool{codes.Canceled: true}
+	cfg.payloadMaxSize = defaultPayloadMaxSize
 	// cfg.spanOpts = append(cfg.spanOpts, tracer.AnalyticsRate(globalconfig.AnalyticsRate()))
 	if internal.BoolEnv("DD_TRACE_GRPC_ANALYTICS_ENABLED", false) {
 		cfg.spanOpts = append(cfg.spanOpts, tracer.AnalyticsRate(1.0))
@@ -107,6 +113,16 @@ func WithStreamMessages(enabled bool) Option {
 	}
 }
 
+// WithStreamMessageEvents enables or disables recording the messages of streaming RPCs as
+// events on the RPC span. Each event holds the direction, sequence number, size and
+// latency since the previous message of the message. This option only applies to the
+// stats handler; it is disabled by default.
+func WithStreamMessageEvents(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.streamMessageEvents = enabled
+	}
+}
+
 // NoDebugStack disables debug stacks for traces with errors. This is useful in situations
 // where errors are frequent and the overhead of calling debug.Stack may affect performance.
 func NoDebugStack() Option {
@@ -203,6 +219,50 @@ func WithErrorDetailTags() Option {
 	}
 }
 
+// WithPayloadCapture specifies whether the requests and responses of the given full methods
+// should be added to spans as JSON. The payloads of all methods are captured if no method
+// is given. Payloads are truncated to the size set with WithPayloadMaxSize, and the value
+// of the fields annotated with the debug_redact option or set with WithRedactedFields is
+// replaced. Payloads of streaming calls are added to the message spans, see
+// WithStreamMessages. This option does not apply to the stats handler.
+func WithPayloadCapture(methods ...string) Option {
+	var pms map[string]struct{}
+	if len(methods) > 0 {
+		pms = make(map[string]struct{}, len(methods))
+		for _, m := range methods {
+			pms[m] = struct{}{}
+		}
+	}
+	return func(cfg *config) {
+		cfg.payloadCapture = true
+		cfg.payloadMethods = pms
+	}
+}
+
+// WithPayloadMaxSize sets the maximum size, in bytes, of the payloads captured with
+// WithPayloadCapture. Larger payloads are truncated. The default is 4096.
+func WithPayloadMaxSize(n int) Option {
+	return func(cfg *config) {
+		if n > 0 {
+			cfg.payloadMaxSize = n
+		}
+	}
+}
+
+// WithRedactedFields specifies the fields whose value is redacted from the payloads
+// captured with WithPayloadCapture. Fields are given as dot-separated paths of proto
+// field names from the root message, e.g. "user.password".
+func WithRedactedFields(paths ...string) Option {
+	return func(cfg *config) {
+		if cfg.redactedFields == nil {
+			cfg.redactedFields = make(map[string]struct{}, len(paths))
+		}
+		for _, p := range paths {
+			cfg.redactedFields[p] = struct{}{}
+		}
+	}
+}
+
 // WithCustomTag will attach the value to the span tagged by the key.
 func WithCustomTag(key string, value interface{}) Option {
 	return func(cfg *config) {
@@ -0,0 +1,262 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package grpc
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/runtime/protoimpl"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// defaultPayloadMaxSize is the default maximum size, in bytes, of a captured payload.
+const defaultPayloadMaxSize = 4096
+
+// redactedValue replaces the value of redacted fields in captured payloads.
+const redactedValue = "[REDACTED]"
+
+// payloadMarshaler encodes captured payloads. Proto field names are used so that
+// they can be matched against the redacted field paths.
+var payloadMarshaler = protojson.MarshalOptions{UseProtoNames: true}
+
+// capturesPayload reports whether the payloads of the given method are captured.
+func (cfg *config) capturesPayload(method string) bool {
+	if !cfg.payloadCapture {
+		return false
+	}
+	if cfg.payloadMethods == nil {
+		return true
+	}
+	_, ok := cfg.payloadMethods[method]
+	return ok
+}
+
+// setPayloadTag sets m, encoded as JSON, as the given tag of span if the payloads
+// of method are captured. Redacted fields are replaced and the payload is truncated
+// to the configured maximum size, on a UTF-8 character boundary. A truncated payload
+// is not valid JSON, and is marked as such by the tag with the truncated suffix.
+func setPayloadTag(cfg *config, method string, span ddtrace.Span, tag string, m interface{}) {
+	if span == nil || !cfg.capturesPayload(method) {
+		return
+	}
+	msg, ok := protoMessage(m)
+	if !ok {
+		return
+	}
+	b, err := marshalPayload(msg, cfg.redactedFields)
+	if err != nil {
+		log.Debug("contrib/google.golang.org/grpc: failed to encode payload: %v", err)
+		return
+	}
+	if len(b) > cfg.payloadMaxSize {
+		b = truncateUTF8(b, cfg.payloadMaxSize)
+		span.SetTag(tag+tagPayloadTruncatedSuffix, true)
+	}
+	span.SetTag(tag, string(b))
+}
+
+// truncateUTF8 truncates b to at most size bytes without splitting a UTF-8 character.
+func truncateUTF8(b []byte, size int) []byte {
+	for size > 0 && !utf8.RuneStart(b[size]) {
+		size--
+	}
+	return b[:size]
+}
+
+func protoMessage(m interface{}) (proto.Message, bool) {
+	switch m := m.(type) {
+	case proto.Message:
+		return m, true
+	case protoiface.MessageV1:
+		return protoimpl.X.ProtoMessageV2Of(m), true
+	}
+	return nil, false
+}
+
+// marshalPayload encodes msg as JSON, replacing the value of the fields annotated with
+// the debug_redact option and of the fields whose path is in redacted.
+func marshalPayload(msg proto.Message, redacted map[string]struct{}) ([]byte, error) {
+	b, err := payloadMarshaler.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	desc := msg.ProtoReflect().Descriptor()
+	if len(redacted) == 0 && !mayHaveRedactedFields(desc) {
+		return b, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	redactMessage(v, desc, "", redacted)
+	return json.Marshal(v)
+}
+
+// Well-known types whose JSON encoding is not the one of their fields.
+const (
+	anyFullName       protoreflect.FullName = "google.protobuf.Any"
+	structFullName    protoreflect.FullName = "google.protobuf.Struct"
+	valueFullName     protoreflect.FullName = "google.protobuf.Value"
+	listValueFullName protoreflect.FullName = "google.protobuf.ListValue"
+)
+
+// redactedFieldsCache caches the result of hasRedactedFields per message type.
+var redactedFieldsCache sync.Map // protoreflect.FullName -> bool
+
+// mayHaveRedactedFields reports whether messages of type desc may have fields annotated
+// with the debug_redact option.
+func mayHaveRedactedFields(desc protoreflect.MessageDescriptor) bool {
+	if v, ok := redactedFieldsCache.Load(desc.FullName()); ok {
+		return v.(bool)
+	}
+	has := hasRedactedFields(desc, make(map[protoreflect.FullName]bool))
+	redactedFieldsCache.Store(desc.FullName(), has)
+	return has
+}
+
+// hasRedactedFields reports whether desc, or any message nested in it, has a field
+// annotated with the debug_redact option. Messages holding a google.protobuf.Any may
+// have such fields in the message it holds. seen guards against recursive messages.
+func hasRedactedFields(desc protoreflect.MessageDescriptor, seen map[protoreflect.FullName]bool) bool {
+	if desc.FullName() == anyFullName {
+		return true
+	}
+	if seen[desc.FullName()] {
+		return false
+	}
+	seen[desc.FullName()] = true
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if isDebugRedact(fd) {
+			return true
+		}
+		if md := fieldMessage(fd); md != nil && hasRedactedFields(md, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactFields walks the JSON object v encoding a message of type desc, found at
+// the given field path, and redacts its fields.
+func redactFields(v map[string]interface{}, desc protoreflect.MessageDescriptor, path string, redacted map[string]struct{}) {
+	for key, val := range v {
+		fd := desc.Fields().ByName(protoreflect.Name(key))
+		if fd == nil {
+			continue
+		}
+		fieldPath := key
+		if path != "" {
+			fieldPath = path + "." + key
+		}
+		if _, ok := redacted[fieldPath]; ok || isDebugRedact(fd) {
+			v[key] = redactedValue
+			continue
+		}
+		md := fieldMessage(fd)
+		if md == nil {
+			continue
+		}
+		switch {
+		case fd.IsMap():
+			if val, ok := val.(map[string]interface{}); ok {
+				for _, mv := range val {
+					redactMessage(mv, md, fieldPath, redacted)
+				}
+			}
+		case fd.IsList():
+			if val, ok := val.([]interface{}); ok {
+				for _, e := range val {
+					redactMessage(e, md, fieldPath, redacted)
+				}
+			}
+		default:
+			redactMessage(val, md, fieldPath, redacted)
+		}
+	}
+}
+
+// redactMessage redacts the fields of the JSON value v encoding a message of type desc,
+// found at the given field path. The values held by a google.protobuf.Any are redacted
+// according to the type they hold, and the keys of a google.protobuf.Struct are handled
+// as fields.
+func redactMessage(v interface{}, desc protoreflect.MessageDescriptor, path string, redacted map[string]struct{}) {
+	switch desc.FullName() {
+	case anyFullName:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		typeURL, _ := obj["@type"].(string)
+		mt, err := protoregistry.GlobalTypes.FindMessageByURL(typeURL)
+		if err != nil {
+			return
+		}
+		md := mt.Descriptor()
+		if value, ok := obj["value"]; ok && strings.HasPrefix(string(md.FullName()), "google.protobuf.") {
+			// well-known types with a custom JSON encoding are held in the value key
+			if md.Fields().ByName("value") == nil {
+				redactMessage(value, md, path, redacted)
+				return
+			}
+		}
+		redactFields(obj, md, path, redacted)
+	case structFullName, valueFullName, listValueFullName:
+		redactJSON(v, path, redacted)
+	default:
+		if obj, ok := v.(map[string]interface{}); ok {
+			redactFields(obj, desc, path, redacted)
+		}
+	}
+}
+
+// redactJSON redacts the keys of the JSON value v whose path is in redacted.
+func redactJSON(v interface{}, path string, redacted map[string]struct{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			if _, ok := redacted[keyPath]; ok {
+				v[key] = redactedValue
+				continue
+			}
+			redactJSON(val, keyPath, redacted)
+		}
+	case []interface{}:
+		for _, e := range v {
+			redactJSON(e, path, redacted)
+		}
+	}
+}
+
+// fieldMessage returns the descriptor of the message held by fd, or of the values
+// of fd if it is a map, or nil if fd does not hold messages.
+func fieldMessage(fd protoreflect.FieldDescriptor) protoreflect.MessageDescriptor {
+	if fd.IsMap() {
+		fd = fd.MapValue()
+	}
+	return fd.Message()
+}
+
+func isDebugRedact(fd protoreflect.FieldDescriptor) bool {
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	return ok && opts.GetDebugRedact()
+}
@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+func TestMarshalPayload(t *testing.T) {
+	t.Run("plain", func(t *testing.T) {
+		b, err := marshalPayload(&FixtureRequest{Name: "pass"}, nil)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name":"pass"}`, string(b))
+	})
+
+	t.Run("paths", func(t *testing.T) {
+		msg := &descriptorpb.DescriptorProto{
+			Name: proto.String("User"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("password"), JsonName: proto.String("password")},
+				{Name: proto.String("email"), JsonName: proto.String("email")},
+			},
+		}
+		b, err := marshalPayload(msg, map[string]struct{}{"field.json_name": {}})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"name": "User",
+			"field": [
+				{"name": "password", "json_name": "[REDACTED]"},
+				{"name": "email", "json_name": "[REDACTED]"}
+			]
+		}`, string(b))
+	})
+
+	t.Run("debug_redact", func(t *testing.T) {
+		fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+			Name:    proto.String("redact_test.proto"),
+			Package: proto.String("grpc.test"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("Credentials"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:   proto.String("user"),
+						Number: proto.Int32(1),
+						Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+						Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					},
+					{
+						Name:    proto.String("password"),
+						Number:  proto.Int32(2),
+						Type:    descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+						Label:   descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Options: &descriptorpb.FieldOptions{DebugRedact: proto.Bool(true)},
+					},
+				},
+			}},
+		}, nil)
+		require.NoError(t, err)
+		md := fd.Messages().ByName("Credentials")
+		msg := dynamicpb.NewMessage(md)
+		msg.Set(md.Fields().ByName("user"), protoreflect.ValueOfString("alice"))
+		msg.Set(md.Fields().ByName("password"), protoreflect.ValueOfString("s3cr3t"))
+
+		b, err := marshalPayload(msg, nil)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"user":"alice","password":"[REDACTED]"}`, string(b))
+	})
+
+	t.Run("any", func(t *testing.T) {
+		value, err := anypb.New(&descriptorpb.FieldDescriptorProto{
+			Name:     proto.String("password"),
+			JsonName: proto.String("password"),
+		})
+		require.NoError(t, err)
+		msg := &typepb.Option{Name: "field", Value: value}
+		b, err := marshalPayload(msg, map[string]struct{}{"value.json_name": {}})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"name": "field",
+			"value": {
+				"@type": "type.googleapis.com/google.protobuf.FieldDescriptorProto",
+				"name": "password",
+				"json_name": "[REDACTED]"
+			}
+		}`, string(b))
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		value, err := structpb.NewStruct(map[string]interface{}{
+			"user": map[string]interface{}{"name": "alice", "password": "s3cr3t"},
+		})
+		require.NoError(t, err)
+		b, err := marshalPayload(value, map[string]struct{}{"user.password": {}})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"user":{"name":"alice","password":"[REDACTED]"}}`, string(b))
+
+		// a Struct held by an Any
+		msg, err := anypb.New(value)
+		require.NoError(t, err)
+		b, err = marshalPayload(msg, map[string]struct{}{"user.password": {}})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"@type": "type.googleapis.com/google.protobuf.Struct",
+			"value": {"user": {"name": "alice", "password": "[REDACTED]"}}
+		}`, string(b))
+	})
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	b := []byte(`{"name":"héllo"}`)
+	assert.Equal(t, `{"name":"h`, string(truncateUTF8(b, 11)))
+	assert.Equal(t, `{"name":"hé`, string(truncateUTF8(b, 12)))
+	assert.Equal(t, `{"name":"hél`, string(truncateUTF8(b, 13)))
+}
+
+func TestCapturesPayload(t *testing.T) {
+	cfg := new(config)
+	defaults(cfg)
+	assert.False(t, cfg.capturesPayload("/grpc.Fixture/Ping"))
+
+	WithPayloadCapture()(cfg)
+	assert.True(t, cfg.capturesPayload("/grpc.Fixture/Ping"))
+
+	WithPayloadCapture("/grpc.Fixture/StreamPing")(cfg)
+	assert.False(t, cfg.capturesPayload("/grpc.Fixture/Ping"))
+	assert.True(t, cfg.capturesPayload("/grpc.Fixture/StreamPing"))
+}
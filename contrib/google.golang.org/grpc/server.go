@@ -25,6 +25,8 @@ type serverStream struct {
 	cfg    *config
 	method string
 	ctx    context.Context
+	// stats accumulates the messages of the stream. It is nil if the call is not traced.
+	stats *messageStats
 }
 
 // Context returns the ServerStream Context.
@@ -40,10 +42,11 @@ func (ss *serverStream) Context() context.Context {
 }
 
 func (ss *serverStream) RecvMsg(m interface{}) (err error) {
+	var span ddtrace.Span
 	_, im := ss.cfg.ignoredMethods[ss.method]
 	_, um := ss.cfg.untracedMethods[ss.method]
 	if ss.cfg.traceStreamMessages && !im && !um {
-		span, _ := startSpanFromContext(
+		span, _ = startSpanFromContext(
 			ss.ctx,
 			ss.method,
 			"grpc.message",
@@ -58,14 +61,19 @@ func (ss *serverStream) RecvMsg(m interface{}) (err error) {
 		}()
 	}
 	err = ss.ServerStream.RecvMsg(m)
+	if err == nil {
+		recordMessage(ss.stats, span, directionReceived, m)
+		setPayloadTag(ss.cfg, ss.method, span, tagRequestPayload, m)
+	}
 	return err
 }
 
 func (ss *serverStream) SendMsg(m interface{}) (err error) {
+	var span ddtrace.Span
 	_, im := ss.cfg.ignoredMethods[ss.method]
 	_, um := ss.cfg.untracedMethods[ss.method]
 	if ss.cfg.traceStreamMessages && !im && !um {
-		span, _ := startSpanFromContext(
+		span, _ = startSpanFromContext(
 			ss.ctx,
 			ss.method,
 			"grpc.message",
@@ -76,6 +84,10 @@ func (ss *serverStream) SendMsg(m interface{}) (err error) {
 		defer func() { finishWithError(span, err, ss.cfg) }()
 	}
 	err = ss.ServerStream.SendMsg(m)
+	if err == nil {
+		recordMessage(ss.stats, span, directionSent, m)
+		setPayloadTag(ss.cfg, ss.method, span, tagResponsePayload, m)
+	}
 	return err
 }
 
@@ -89,6 +101,7 @@ func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
 	log.Debug("contrib/google.golang.org/grpc: Configuring StreamServerInterceptor: %#v", cfg)
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
 		ctx := ss.Context()
+		var stats *messageStats
 		// if we've enabled call tracing, create a span
 		_, im := cfg.ignoredMethods[info.FullMethod]
 		_, um := cfg.untracedMethods[info.FullMethod]
@@ -111,7 +124,11 @@ func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
 			case info.IsClientStream:
 				span.SetTag(tagMethodKind, methodKindClientStream)
 			}
-			defer func() { finishWithError(span, err, cfg) }()
+			stats = new(messageStats)
+			defer func() {
+				stats.setTags(span)
+				finishWithError(span, err, cfg)
+			}()
 			if appsec.Enabled() {
 				handler = appsecStreamHandlerMiddleware(info.FullMethod, span, handler)
 			}
@@ -124,6 +141,7 @@ func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
 			cfg:          cfg,
 			method:       info.FullMethod,
 			ctx:          ctx,
+			stats:        stats,
 		})
 	}
 }
@@ -154,10 +172,14 @@ func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
 		span.SetTag(tagMethodKind, methodKindUnary)
 		withMetadataTags(ctx, cfg, span)
 		withRequestTags(cfg, req, span)
+		setPayloadTag(cfg, info.FullMethod, span, tagRequestPayload, req)
 		if appsec.Enabled() {
 			handler = appsecUnaryHandlerMiddleware(info.FullMethod, span, handler)
 		}
 		resp, err := handler(ctx, req)
+		if err == nil {
+			setPayloadTag(cfg, info.FullMethod, span, tagResponsePayload, resp)
+		}
 		finishWithError(span, err, cfg)
 		return resp, err
 	}
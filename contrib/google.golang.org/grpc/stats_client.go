@@ -39,7 +39,7 @@ func (h *clientStatsHandler) TagRPC(ctx context.Context, rti *stats.RPCTagInfo)
 		spanOpts...,
 	)
 	ctx = injectSpanIntoContext(ctx)
-	return ctx
+	return contextWithMessageStats(ctx, new(messageStats))
 }
 
 // HandleRPC processes the RPC ending event by finishing the span from the context.
@@ -57,8 +57,8 @@ func (h *clientStatsHandler) HandleRPC(ctx context.Context, rs stats.RPCStats) {
 			}
 			span.SetTag(ext.TargetPort, port)
 		}
-	case *stats.End:
-		finishWithError(span, rs.Error, h.cfg)
+	default:
+		handleMessageStats(ctx, span, rs, h.cfg)
 	}
 }
 
@@ -58,6 +58,10 @@ func TestClientStatsHandler(t *testing.T) {
 	assert.Equal("grpc", tags[ext.RPCSystem])
 	assert.Equal("/grpc.Fixture/Ping", tags[ext.GRPCFullMethod])
 	assert.Equal(ext.SpanKindClient, tags[ext.SpanKind])
+	assert.Equal(1, tags[tagMessagesSent])
+	assert.Equal(1, tags[tagMessagesReceived])
+	assert.Equal(int64(6), tags[tagBytesSent])
+	assert.Equal(int64(8), tags[tagBytesReceived])
 }
 
 func newClientStatsHandlerTestServer(statsHandler stats.Handler) (*rig, error) {
@@ -8,6 +8,7 @@ package grpc
 import (
 	"context"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 
@@ -44,7 +45,7 @@ func (h *serverStatsHandler) TagRPC(ctx context.Context, rti *stats.RPCTagInfo)
 		h.cfg.serviceName,
 		spanOpts...,
 	)
-	return ctx
+	return contextWithMessageStats(ctx, new(messageStats))
 }
 
 // HandleRPC processes the RPC ending event by finishing the span from the context.
@@ -53,8 +54,31 @@ func (h *serverStatsHandler) HandleRPC(ctx context.Context, rs stats.RPCStats) {
 	if !ok {
 		return
 	}
-	if v, ok := rs.(*stats.End); ok {
-		finishWithError(span, v.Error, h.cfg)
+	handleMessageStats(ctx, span, rs, h.cfg)
+}
+
+// handleMessageStats records the payloads of the RPC and finishes its span when it ends.
+// It is shared by the client and server stats handlers.
+func handleMessageStats(ctx context.Context, span ddtrace.Span, rs stats.RPCStats, cfg *config) {
+	ms, ok := messageStatsFromContext(ctx)
+	switch rs := rs.(type) {
+	case *stats.Begin:
+		if ok && cfg.streamMessageEvents && (rs.IsClientStream || rs.IsServerStream) {
+			ms.enableEvents()
+		}
+	case *stats.InPayload:
+		if ok {
+			ms.record(directionReceived, rs.Length, rs.RecvTime)
+		}
+	case *stats.OutPayload:
+		if ok {
+			ms.record(directionSent, rs.Length, rs.SentTime)
+		}
+	case *stats.End:
+		if ok {
+			ms.setTags(span)
+		}
+		finishWithError(span, rs.Error, cfg)
 	}
 }
 
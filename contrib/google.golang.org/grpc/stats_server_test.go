@@ -7,6 +7,7 @@ package grpc
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
@@ -14,6 +15,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/stats"
@@ -67,3 +69,68 @@ func newServerStatsHandlerTestServer(statsHandler stats.Handler) (*rig, error) {
 		},
 	)
 }
+
+func TestServerStatsHandlerMessageStats(t *testing.T) {
+	statsHandler := NewServerStatsHandler(WithServiceName("grpc-service"), WithStreamMessageEvents(true))
+	server, err := newServerStatsHandlerTestServer(statsHandler)
+	require.NoError(t, err)
+	defer server.Close()
+
+	t.Run("unary", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		_, err = server.client.Ping(context.Background(), &FixtureRequest{Name: "pass"})
+		require.NoError(t, err)
+
+		waitForSpans(mt, 1)
+		span := mt.FinishedSpans()[0]
+		assert.Equal(t, 1, span.Tag(tagMessagesReceived))
+		assert.Equal(t, 1, span.Tag(tagMessagesSent))
+		assert.Equal(t, int64(6), span.Tag(tagBytesReceived))
+		assert.Equal(t, int64(8), span.Tag(tagBytesSent))
+		assert.Nil(t, span.Tag(tagEvents), "unary calls have no message events")
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		stream, err := server.client.StreamPing(context.Background())
+		require.NoError(t, err)
+		for i := 0; i < 2; i++ {
+			require.NoError(t, stream.Send(&FixtureRequest{Name: "pass"}))
+			_, err := stream.Recv()
+			require.NoError(t, err)
+		}
+		require.NoError(t, stream.CloseSend())
+		stream.Recv()
+
+		waitForSpans(mt, 1)
+		span := mt.FinishedSpans()[0]
+		assert.Equal(t, 2, span.Tag(tagMessagesReceived))
+		assert.Equal(t, 2, span.Tag(tagMessagesSent))
+		assert.Equal(t, int64(12), span.Tag(tagBytesReceived))
+		assert.Equal(t, int64(16), span.Tag(tagBytesSent))
+
+		var events []messageEvent
+		require.NoError(t, json.Unmarshal([]byte(span.Tag(tagEvents).(string)), &events))
+		require.Len(t, events, 4)
+		for i, want := range []struct {
+			direction string
+			seq, size float64
+		}{
+			{directionReceived, 1, 6},
+			{directionSent, 1, 8},
+			{directionReceived, 2, 6},
+			{directionSent, 2, 8},
+		} {
+			assert.Equal(t, "grpc.message", events[i].Name)
+			assert.Equal(t, want.direction, events[i].Attributes[tagMessageDirection])
+			assert.Equal(t, want.seq, events[i].Attributes[tagMessageSeq])
+			assert.Equal(t, want.size, events[i].Attributes[tagMessageSize])
+		}
+		assert.Equal(t, float64(0), events[0].Attributes[tagMessageLatency])
+		assert.Greater(t, events[3].Attributes[tagMessageLatency], float64(0))
+	})
+}
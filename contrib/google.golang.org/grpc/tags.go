@@ -13,6 +13,21 @@ const (
 	tagMetadataPrefix      = "grpc.metadata."
 	tagRequest             = "grpc.request"
 	tagStatusDetailsPrefix = "grpc.status_details."
+
+	tagRequestPayload         = "grpc.request.payload"
+	tagResponsePayload        = "grpc.response.payload"
+	tagPayloadTruncatedSuffix = "_truncated"
+
+	tagMessagesSent         = "grpc.messages.sent"
+	tagMessagesReceived     = "grpc.messages.received"
+	tagBytesSent            = "grpc.bytes.sent"
+	tagBytesReceived        = "grpc.bytes.received"
+	tagMessageDirection     = "grpc.message.direction"
+	tagMessageSeq           = "grpc.message.seq"
+	tagMessageSize          = "grpc.message.size"
+	tagMessageLatency       = "grpc.message.latency_ns"
+	tagEvents               = "events"
+	tagMessageEventsDropped = "grpc.message_events_dropped"
 )
 
 const (
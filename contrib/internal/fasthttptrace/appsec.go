@@ -0,0 +1,139 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package fasthttptrace
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/httpsec"
+
+	"github.com/valyala/fasthttp"
+)
+
+// BeforeHandle contains the appsec functionality that should be executed before a fasthttp handler runs.
+// ctx is the Go context of the request, which is fctx itself for plain fasthttp handlers. It returns the
+// Go context the handler must use, an afterHandle function that should be executed after the handler
+// runs, and a handled bool that instructs if the request has been handled or not - in case it was handled,
+// the original handler should not run.
+// Since fasthttp buffers the response until the handler returns, the blocking response sent when a security
+// rule blocks the request after the handler ran replaces the response written by the handler.
+// The path parameters are optional and can also be provided once known by calling afterHandle.
+func BeforeHandle(ctx context.Context, fctx *fasthttp.RequestCtx, span ddtrace.Span, pathParams map[string]string) (context.Context, func(pathParams map[string]string), bool) {
+	w := &responseWriter{fctx: fctx}
+	cfg := httptrace.AppsecConfig()
+	cfg.ResponseHeaderCopier = func(http.ResponseWriter) http.Header {
+		return w.responseHeaders()
+	}
+
+	_, r, afterHandle, handled := httpsec.BeforeHandle(w, newRequest(ctx, fctx), span, pathParams, cfg)
+
+	// Register the handler operation in the fasthttp request context too, as it is the Go context
+	// used by plain fasthttp handlers
+	ctx = r.Context()
+	if op, ok := dyngo.FromContext(ctx); ok {
+		dyngo.RegisterOperationValue(fctx.SetUserValue, op)
+	}
+
+	return ctx, func(pathParams map[string]string) {
+		_ = httpsec.MonitorPathParams(ctx, pathParams)
+		afterHandle()
+	}, handled
+}
+
+// newRequest returns the http.Request representation of the fasthttp request, with ctx as its context.
+// Unlike fasthttpadaptor.ConvertRequest, the values are copied since they are kept in the service entry
+// span tags, which outlive the fasthttp request buffers.
+func newRequest(ctx context.Context, fctx *fasthttp.RequestCtx) *http.Request {
+	body := fctx.PostBody()
+	r := &http.Request{
+		Method:        string(fctx.Method()),
+		RequestURI:    string(fctx.RequestURI()),
+		Host:          string(fctx.Host()),
+		RemoteAddr:    fctx.RemoteAddr().String(),
+		Header:        make(http.Header),
+		ContentLength: int64(len(body)),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+	}
+	u, err := url.ParseRequestURI(r.RequestURI)
+	if err != nil {
+		u = &url.URL{Path: string(fctx.Path())}
+	}
+	r.URL = u
+	fctx.Request.Header.VisitAll(func(k, v []byte) {
+		r.Header.Add(string(k), string(v))
+	})
+	return r.WithContext(ctx)
+}
+
+// responseWriter is the http.ResponseWriter representation of the fasthttp response used by httpsec
+// to read the response and to write the blocking response.
+type responseWriter struct {
+	fctx        *fasthttp.RequestCtx
+	header      http.Header
+	wroteHeader bool
+}
+
+// Header returns the headers of the blocking response, copied to the fasthttp response by WriteHeader.
+func (w *responseWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+// WriteHeader starts writing the blocking response, discarding the response the handler may have
+// written so far.
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.fctx.Response.Reset()
+	for k, values := range w.header {
+		for _, v := range values {
+			w.fctx.Response.Header.Add(k, v)
+		}
+	}
+	w.fctx.SetStatusCode(statusCode)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.fctx.Write(b)
+}
+
+// Status returns the status code of the fasthttp response.
+func (w *responseWriter) Status() int {
+	return w.fctx.Response.StatusCode()
+}
+
+// ResponseBody returns the parsed response body, if it can be monitored. Streamed bodies are not.
+func (w *responseWriter) ResponseBody() any {
+	if w.fctx.Response.IsBodyStream() {
+		return nil
+	}
+	var rec httpsec.ResponseBodyRecorder
+	rec.Record(w.responseHeaders(), w.fctx.Response.Body())
+	return rec.Body()
+}
+
+// responseHeaders returns a copy of the headers of the fasthttp response.
+func (w *responseWriter) responseHeaders() http.Header {
+	h := make(http.Header)
+	w.fctx.Response.Header.VisitAll(func(k, v []byte) {
+		h.Add(string(k), string(v))
+	})
+	return h
+}
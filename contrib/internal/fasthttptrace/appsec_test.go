@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package fasthttptrace
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestNewRequest(t *testing.T) {
+	var req fasthttp.Request
+	req.Header.SetMethod("POST")
+	req.SetRequestURI("/path?a=1&a=2")
+	req.Header.SetHost("example.com")
+	req.Header.Add("X-Multi", "v1")
+	req.Header.Add("X-Multi", "v2")
+	req.Header.Set("Cookie", "c1=v1; c2=v2")
+	req.SetBodyString(`{"key":"value"}`)
+	var fctx fasthttp.RequestCtx
+	fctx.Init(&req, &net.TCPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}, nil)
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	r := newRequest(ctx, &fctx)
+	require.Equal(t, "POST", r.Method)
+	require.Equal(t, "/path?a=1&a=2", r.RequestURI)
+	require.Equal(t, "/path", r.URL.Path)
+	require.Equal(t, []string{"1", "2"}, r.URL.Query()["a"])
+	require.Equal(t, "example.com", r.Host)
+	require.Equal(t, "1.2.3.4:1234", r.RemoteAddr)
+	require.Equal(t, []string{"v1", "v2"}, r.Header.Values("X-Multi"))
+	require.Len(t, r.Cookies(), 2)
+	require.Equal(t, "value", r.Context().Value(ctxKey{}))
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	require.Equal(t, `{"key":"value"}`, string(body))
+
+	// The request values must not share the memory of the fasthttp request
+	req.Header.Set("X-Multi", "overwritten")
+	require.Equal(t, []string{"v1", "v2"}, r.Header.Values("X-Multi"))
+}
+
+func TestResponseWriter(t *testing.T) {
+	var fctx fasthttp.RequestCtx
+	fctx.Response.Header.SetContentType("application/json")
+	fctx.Response.Header.Set("X-Handler", "value")
+	fctx.SetStatusCode(http.StatusCreated)
+	fctx.SetBodyString(`{"key":"value"}`)
+
+	w := &responseWriter{fctx: &fctx}
+	require.Equal(t, http.StatusCreated, w.Status())
+	require.Equal(t, "value", w.responseHeaders().Get("X-Handler"))
+	require.Equal(t, map[string]any{"key": "value"}, w.ResponseBody())
+
+	t.Run("blocking-response", func(t *testing.T) {
+		// Writing the blocking response replaces the response of the handler
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusForbidden)
+		_, err := w.Write([]byte("blocked"))
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusForbidden, fctx.Response.StatusCode())
+		require.Equal(t, "blocked", string(fctx.Response.Body()))
+		require.Equal(t, "text/html", string(fctx.Response.Header.ContentType()))
+		require.Empty(t, fctx.Response.Header.Peek("X-Handler"))
+		require.Nil(t, w.ResponseBody())
+	})
+}
@@ -65,7 +65,11 @@ func BeforeHandle(cfg *ServeConfig, w http.ResponseWriter, r *http.Request) (htt
 	afterHandle := closeSpan
 	handled := false
 	if appsec.Enabled() {
-		secW, secReq, secAfterHandle, secHandled := httpsec.BeforeHandle(rw, rt, span, cfg.RouteParams, nil)
+		secW, secReq, secAfterHandle, secHandled := httpsec.BeforeHandle(rw, rt, span, cfg.RouteParams, AppsecConfig())
+		if httpsec.MonitorsResponseBody(secReq.Context()) {
+			// only record the response body when a security rule inspects it
+			ddrw.body = new(httpsec.ResponseBodyRecorder)
+		}
 		afterHandle = func() {
 			secAfterHandle()
 			closeSpan()
@@ -76,3 +80,9 @@ func BeforeHandle(cfg *ServeConfig, w http.ResponseWriter, r *http.Request) (htt
 	}
 	return rw, rt, afterHandle, handled
 }
+
+// AppsecConfig returns the configuration of the AppSec monitoring of a request. It is also used by
+// the integrations of HTTP servers not based on net/http, such as fasthttp.
+func AppsecConfig() *httpsec.Config {
+	return &httpsec.Config{ParseRequestBody: cfg.appsecBodyParsing}
+}
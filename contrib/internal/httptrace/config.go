@@ -26,6 +26,9 @@ const (
 	envTraceClientIPEnabled = "DD_TRACE_CLIENT_IP_ENABLED"
 	// envServerErrorStatuses is the name of the env var used to specify error status codes on http server spans
 	envServerErrorStatuses = "DD_TRACE_HTTP_SERVER_ERROR_STATUSES"
+	// envAppsecBodyParsingEnabled is the name of the env var used to enable the automatic parsing of
+	// request bodies for their monitoring by AppSec
+	envAppsecBodyParsingEnabled = "DD_APPSEC_HTTP_BODY_PARSING_ENABLED"
 )
 
 // defaultQueryStringRegexp is the regexp used for query string obfuscation if `envQueryStringRegexp` is empty.
@@ -36,6 +39,7 @@ type config struct {
 	queryString       bool           // reports whether the query string should be included in the URL span tag.
 	traceClientIP     bool
 	isStatusError     func(statusCode int) bool
+	appsecBodyParsing bool // reports whether request bodies are parsed before the handler runs for AppSec.
 }
 
 func newConfig() config {
@@ -44,6 +48,7 @@ func newConfig() config {
 		queryStringRegexp: defaultQueryStringRegexp,
 		traceClientIP:     internal.BoolEnv(envTraceClientIPEnabled, false),
 		isStatusError:     isServerError,
+		appsecBodyParsing: internal.BoolEnv(envAppsecBodyParsingEnabled, false),
 	}
 	v := os.Getenv(envServerErrorStatuses)
 	if fn := GetErrorCodesFromInput(v); fn != nil {
@@ -47,6 +47,15 @@ func TestConfig(t *testing.T) {
 				queryString: true,
 			},
 		},
+		{
+			name: "enable-appsec-body-parsing",
+			env:  map[string]string{envAppsecBodyParsingEnabled: "true"},
+			cfg: config{
+				queryString:       true,
+				queryStringRegexp: defaultQueryStringRegexp,
+				appsecBodyParsing: true,
+			},
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			for k, v := range tc.env {
@@ -55,6 +64,7 @@ func TestConfig(t *testing.T) {
 			c := newConfig()
 			require.Equal(t, tc.cfg.queryStringRegexp, c.queryStringRegexp)
 			require.Equal(t, tc.cfg.queryString, c.queryString)
+			require.Equal(t, tc.cfg.appsecBodyParsing, c.appsecBodyParsing)
 		})
 	}
 }
@@ -7,17 +7,23 @@ package httptrace
 
 //go:generate sh -c "go run make_responsewriter.go | gofmt > trace_gen.go"
 
-import "net/http"
+import (
+	"net/http"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/httpsec"
+)
 
 // responseWriter is a small wrapper around an http response writer that will
 // intercept and store the status of a request.
 type responseWriter struct {
 	http.ResponseWriter
 	status int
+	// body records the response body when it is monitored by AppSec, or is nil.
+	body *httpsec.ResponseBodyRecorder
 }
 
 func newResponseWriter(w http.ResponseWriter) *responseWriter {
-	return &responseWriter{w, 0}
+	return &responseWriter{ResponseWriter: w}
 }
 
 // Status returns the status code that was monitored.
@@ -32,7 +38,11 @@ func (w *responseWriter) Write(b []byte) (int, error) {
 	if w.status == 0 {
 		w.WriteHeader(http.StatusOK)
 	}
-	return w.ResponseWriter.Write(b)
+	n, err := w.ResponseWriter.Write(b)
+	if w.body != nil && n > 0 {
+		w.body.Record(w.Header(), b[:n])
+	}
+	return n, err
 }
 
 // WriteHeader sends an HTTP response header with status code.
@@ -45,6 +55,14 @@ func (w *responseWriter) WriteHeader(status int) {
 	w.status = status
 }
 
+// ResponseBody returns the parsed response body if it was recorded, or nil.
+func (w *responseWriter) ResponseBody() any {
+	if w.body == nil {
+		return nil
+	}
+	return w.body.Body()
+}
+
 // Unwrap returns the underlying wrapped http.ResponseWriter.
 func (w *responseWriter) Unwrap() http.ResponseWriter {
 	return w.ResponseWriter
@@ -7,9 +7,15 @@ package httptrace
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/httpsec"
+
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_wrapResponseWriter(t *testing.T) {
@@ -34,3 +40,63 @@ func Test_wrapResponseWriter(t *testing.T) {
 	})
 
 }
+
+func TestResponseWriterBody(t *testing.T) {
+	t.Run("recorded", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		w, ddrw := wrapResponseWriter(rec)
+		ddrw.body = new(httpsec.ResponseBodyRecorder)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"user":`))
+		w.Write([]byte(`"admin"}`))
+
+		assert.Equal(t, `{"user":"admin"}`, rec.Body.String())
+		assert.Equal(t, map[string]any{"user": "admin"}, w.(interface{ ResponseBody() any }).ResponseBody())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		w, _ := wrapResponseWriter(httptest.NewRecorder())
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"user":"admin"}`))
+		assert.Nil(t, w.(interface{ ResponseBody() any }).ResponseBody())
+	})
+}
+
+// Test that the response bodies are only recorded for the requests sampled by API Security, as
+// the default rules don't inspect them.
+func TestBeforeHandleResponseBody(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		sampleRate string
+		recorded   bool
+	}{
+		{name: "sampled", sampleRate: "1.0", recorded: true},
+		{name: "unsampled", sampleRate: "0.0", recorded: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("DD_API_SECURITY_ENABLED", "true")
+			t.Setenv("DD_API_SECURITY_REQUEST_SAMPLE_RATE", tc.sampleRate)
+			appsec.Start()
+			defer appsec.Stop()
+			if !appsec.Enabled() {
+				t.Skip("appsec disabled")
+			}
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			r := httptest.NewRequest("GET", "/", nil)
+			w, _, afterHandle, handled := BeforeHandle(nil, httptest.NewRecorder(), r)
+			require.False(t, handled)
+			w.Header().Set("Content-Type", "application/json")
+			_, err := w.Write([]byte(`{"user":"admin"}`))
+			require.NoError(t, err)
+			body := w.(interface{ ResponseBody() any }).ResponseBody()
+			afterHandle()
+			if tc.recorded {
+				assert.Equal(t, map[string]any{"user": "admin"}, body)
+			} else {
+				assert.Nil(t, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+// Package logtrace provides the log/trace correlation fields shared by the logging integrations.
+package logtrace
+
+import (
+	"context"
+	"os"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+)
+
+const (
+	// KeyService is the log key holding the service name.
+	KeyService = "dd.service"
+	// KeyEnv is the log key holding the environment.
+	KeyEnv = "dd.env"
+	// KeyVersion is the log key holding the application version.
+	KeyVersion = "dd.version"
+)
+
+// Fields holds the correlation fields of a span.
+type Fields struct {
+	// TraceID is the lower 64 bits of the trace ID.
+	TraceID uint64
+	// TraceID128 is the hex-encoded 128-bit trace ID. It is only set when
+	// DD_TRACE_128_BIT_TRACEID_LOGGING_ENABLED is true and the trace ID has
+	// its upper 64 bits set, in which case it should be logged instead of TraceID.
+	TraceID128 string
+	SpanID     uint64
+	Service    string
+	Env        string
+	Version    string
+}
+
+// FromContext returns the correlation fields of the span found in ctx.
+// It reports false if there is no span in ctx.
+func FromContext(ctx context.Context) (Fields, bool) {
+	if ctx == nil {
+		return Fields{}, false
+	}
+	span, ok := tracer.SpanFromContext(ctx)
+	if !ok {
+		return Fields{}, false
+	}
+	return FromSpan(span), true
+}
+
+// FromSpan returns the correlation fields of the given span. Like the span's %v
+// formatting, the service, environment and version are taken from the running
+// tracer, the environment and version falling back to DD_ENV and DD_VERSION
+// without one.
+func FromSpan(span ddtrace.Span) Fields {
+	f := Fields{
+		TraceID: span.Context().TraceID(),
+		SpanID:  span.Context().SpanID(),
+		Service: globalconfig.ServiceName(),
+	}
+	var ok bool
+	if f.Env, f.Version, ok = globalconfig.TracerEnvVersion(); !ok {
+		f.Env, f.Version = os.Getenv("DD_ENV"), os.Getenv("DD_VERSION")
+	}
+	if !internal.BoolEnv("DD_TRACE_128_BIT_TRACEID_LOGGING_ENABLED", false) {
+		return f
+	}
+	if w3c, ok := span.Context().(ddtrace.SpanContextW3C); ok && hasUpper(w3c.TraceID128Bytes()) {
+		f.TraceID128 = w3c.TraceID128()
+	}
+	return f
+}
+
+func hasUpper(id [16]byte) bool {
+	for _, b := range id[:8] {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package httprouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+)
+
+// Test a security scanner attack via path parameters
+func TestAppSecPathParams(t *testing.T) {
+	appsec.Start()
+	defer appsec.Stop()
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	router := New()
+	router.GET("/path0.0/:myPathParam0/path0.1/:myPathParam1/path0.2/:myPathParam2", func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		w.Write([]byte("Hello Params!\n"))
+	})
+
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	// Send a security scanner attack (according to appsec rule id crs-913-120)
+	r := httptest.NewRequest("GET", "/path0.0/param0/path0.1/param1/path0.2/appscan_fingerprint", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, "Hello Params!\n", w.Body.String())
+
+	finished := mt.FinishedSpans()
+	require.Len(t, finished, 1)
+	event, _ := finished[0].Tag("_dd.appsec.json").(string)
+	require.Contains(t, event, "crs-913-120")
+	require.Contains(t, event, "myPathParam2")
+	require.Contains(t, event, "server.request.path_params")
+}
@@ -43,8 +43,13 @@ func BeforeHandle[T any, WT Router](
 	// get the resource associated to this request
 	route := req.URL.Path
 	_, ps, _ := wRouter.Lookup(req.Method, route)
+	var routeParams map[string]string
+	if len(ps) > 0 {
+		routeParams = make(map[string]string, len(ps))
+	}
 	for _, param := range ps {
 		route = strings.Replace(route, param.GetValue(), ":"+param.GetKey(), 1)
+		routeParams[param.GetKey()] = param.GetValue()
 	}
 
 	resource := req.Method + " " + route
@@ -52,10 +57,11 @@ func BeforeHandle[T any, WT Router](
 	spanOpts = append(spanOpts, httptrace.HeaderTagsFromRequest(req, cfg.headerTags))
 
 	serveCfg := &httptrace.ServeConfig{
-		Service:  cfg.serviceName,
-		Resource: resource,
-		SpanOpts: spanOpts,
-		Route:    route,
+		Service:     cfg.serviceName,
+		Resource:    resource,
+		SpanOpts:    spanOpts,
+		Route:       route,
+		RouteParams: routeParams,
 	}
 	return httptrace.BeforeHandle(serveCfg, w, req)
 }
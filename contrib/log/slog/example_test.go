@@ -46,3 +46,24 @@ func ExampleWrapHandler() {
 	// log a message using the context containing span information
 	logger.Log(ctx, slog.LevelInfo, "this is a log with tracing information")
 }
+
+func ExampleWithSpanEvents() {
+	// start the DataDog tracer
+	tracer.Start()
+	defer tracer.Stop()
+
+	// create the application logger, attaching warnings and errors to the active span
+	// and marking the span as errored when an error is logged
+	myHandler := slog.NewJSONHandler(os.Stdout, nil)
+	logger := slog.New(slogtrace.WrapHandler(myHandler,
+		slogtrace.WithSpanEvents(slog.LevelWarn),
+		slogtrace.WithSpanErrors(slog.LevelError),
+	))
+
+	// start a new span
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "ExampleWithSpanEvents")
+	defer span.Finish()
+
+	// this record is logged and also recorded on the span
+	logger.Log(ctx, slog.LevelError, "this is an error log attached to the span")
+}
@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package slog
+
+import (
+	"log/slog"
+)
+
+// defaultSpanEventsLimit is the default maximum number of log records attached
+// to a single span.
+const defaultSpanEventsLimit = 16
+
+type config struct {
+	// eventLevel is the minimum level of the records attached to the active
+	// span as span events. Nil when disabled.
+	eventLevel slog.Leveler
+	// errorLevel is the minimum level of the records marking the active span
+	// as errored. Nil when disabled.
+	errorLevel slog.Leveler
+	// eventsLimit is the maximum number of records attached to a single span.
+	eventsLimit int
+}
+
+func defaults(cfg *config) {
+	cfg.eventsLimit = defaultSpanEventsLimit
+}
+
+// Option is used to customize the handler returned by WrapHandler.
+type Option func(cfg *config)
+
+// WithSpanEvents attaches the records at or above the given level to the span found
+// in the record's context, as span events. The number of events attached to a
+// single span is limited, see WithSpanEventsLimit.
+func WithSpanEvents(level slog.Leveler) Option {
+	return func(cfg *config) {
+		cfg.eventLevel = level
+	}
+}
+
+// WithSpanErrors marks the span found in the record's context as errored when a
+// record at or above the given level is logged. The error message, and the error
+// type and stack if the record holds an error attribute, are taken from the first
+// such record logged for the span.
+func WithSpanErrors(level slog.Leveler) Option {
+	return func(cfg *config) {
+		cfg.errorLevel = level
+	}
+}
+
+// WithSpanEventsLimit sets the maximum number of records attached to a single span
+// by WithSpanEvents. Records above this limit are counted but dropped.
+// The default is 16.
+func WithSpanEventsLimit(n int) Option {
+	return func(cfg *config) {
+		if n >= 0 {
+			cfg.eventsLimit = n
+		}
+	}
+}
@@ -30,18 +30,33 @@ func NewJSONHandler(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
 }
 
 // WrapHandler enhances the given logger handler attaching tracing information to logs.
-func WrapHandler(h slog.Handler) slog.Handler {
-	return &handler{h}
+// Options can be given to also attach log records to the span found in their context.
+func WrapHandler(h slog.Handler, opts ...Option) slog.Handler {
+	cfg := new(config)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	var spans *spanTracker
+	if cfg.eventLevel != nil || cfg.errorLevel != nil {
+		spans = newSpanTracker()
+	}
+	return &handler{Handler: h, cfg: cfg, spans: spans}
 }
 
 type handler struct {
 	slog.Handler
+	cfg   *config
+	spans *spanTracker
 }
 
 // Handle handles the given Record, attaching tracing information if found.
 func (h *handler) Handle(ctx context.Context, rec slog.Record) error {
 	span, ok := tracer.SpanFromContext(ctx)
 	if ok {
+		if h.spans != nil {
+			h.spans.record(span, h.cfg, rec)
+		}
 		rec = rec.Clone()
 		rec.Add(
 			slog.Uint64(ext.LogKeyTraceID, span.Context().TraceID()),
@@ -50,3 +65,13 @@ func (h *handler) Handle(ctx context.Context, rec slog.Record) error {
 	}
 	return h.Handler.Handle(ctx, rec)
 }
+
+// WithAttrs returns a new Handler whose attributes consist of both the receiver's attributes and the arguments.
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{Handler: h.Handler.WithAttrs(attrs), cfg: h.cfg, spans: h.spans}
+}
+
+// WithGroup returns a new Handler with the given group appended to the receiver's existing groups.
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{Handler: h.Handler.WithGroup(name), cfg: h.cfg, spans: h.spans}
+}
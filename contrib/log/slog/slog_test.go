@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
 	"strings"
@@ -18,6 +19,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	internallog "gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
@@ -119,3 +121,75 @@ func (h handlerGate) Handle(ctx context.Context, r slog.Record) {
 	h.gate()
 	h.Handler.Handle(ctx, r)
 }
+
+func TestSpanEvents(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	logger := slog.New(WrapHandler(slog.NewJSONHandler(io.Discard, nil), WithSpanEvents(slog.LevelWarn), WithSpanEventsLimit(2)))
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "test")
+	logger.InfoContext(ctx, "below the level")
+	logger.WarnContext(ctx, "first", "count", 1, slog.Group("req", "id", "abc"))
+	logger.With("attr", "value").ErrorContext(ctx, "second", "err", errors.New("boom"))
+	logger.ErrorContext(ctx, "third")
+	logger.ErrorContext(ctx, "fourth")
+	span.Finish()
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	var events []spanEvent
+	require.NoError(t, json.Unmarshal([]byte(spans[0].Tag("events").(string)), &events))
+	require.Len(t, events, 2)
+	assert.Equal(t, "first", events[0].Name)
+	assert.Equal(t, map[string]interface{}{"level": "WARN", "count": float64(1), "req.id": "abc"}, events[0].Attributes)
+	assert.Equal(t, "second", events[1].Name)
+	assert.Equal(t, map[string]interface{}{"level": "ERROR", "err": "boom"}, events[1].Attributes)
+	assert.Equal(t, 2, spans[0].Tag("log.events_dropped"))
+	assert.Nil(t, spans[0].Tag(ext.Error))
+}
+
+func TestSpanErrors(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	logger := slog.New(WrapHandler(slog.NewJSONHandler(io.Discard, nil), WithSpanErrors(slog.LevelError)))
+
+	t.Run("error attribute", func(t *testing.T) {
+		mt.Reset()
+		span, ctx := tracer.StartSpanFromContext(context.Background(), "test")
+		logger.WarnContext(ctx, "not an error")
+		logger.ErrorContext(ctx, "query failed", "err", errors.New("boom"))
+		logger.ErrorContext(ctx, "another failure")
+		span.Finish()
+
+		spans := mt.FinishedSpans()
+		require.Len(t, spans, 1)
+		assert.NotNil(t, spans[0].Tag(ext.Error))
+		assert.Equal(t, "query failed: boom", spans[0].Tag(ext.ErrorMsg))
+		assert.Nil(t, spans[0].Tag("events"))
+	})
+
+	t.Run("message only", func(t *testing.T) {
+		mt.Reset()
+		span, ctx := tracer.StartSpanFromContext(context.Background(), "test")
+		logger.ErrorContext(ctx, "something went wrong")
+		span.Finish()
+
+		spans := mt.FinishedSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, true, spans[0].Tag(ext.Error))
+		assert.Equal(t, "something went wrong", spans[0].Tag(ext.ErrorMsg))
+	})
+}
+
+func TestSpanTrackerEviction(t *testing.T) {
+	tr := newSpanTracker()
+	for i := 0; i < maxTrackedSpans+10; i++ {
+		tr.state(uint64(i))
+	}
+	assert.Len(t, tr.spans, maxTrackedSpans)
+	assert.NotContains(t, tr.spans, uint64(9))
+	assert.Contains(t, tr.spans, uint64(10))
+	assert.Contains(t, tr.spans, uint64(maxTrackedSpans+9))
+}
@@ -0,0 +1,170 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package slog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+const (
+	// keySpanEvents holds the span events, encoded the same way as the
+	// events added through the OpenTelemetry API.
+	keySpanEvents = "events"
+	// keySpanEventsDropped holds the number of records dropped because the
+	// span reached its events limit.
+	keySpanEventsDropped = "log.events_dropped"
+)
+
+// maxTrackedSpans bounds the number of spans for which the handler keeps state.
+// Handlers have no way to know when a span finishes, so the state of the oldest
+// spans is evicted first.
+const maxTrackedSpans = 4096
+
+// spanEvent holds information about a log record attached to a span.
+type spanEvent struct {
+	Name         string                 `json:"name"`
+	TimeUnixNano int64                  `json:"time_unix_nano"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// spanState holds what has been attached to a span so far.
+type spanState struct {
+	events  []spanEvent
+	dropped int
+	errored bool
+}
+
+// spanTracker attaches log records to spans, keeping per-span state for up to
+// maxTrackedSpans spans.
+type spanTracker struct {
+	mu    sync.Mutex
+	spans map[uint64]*spanState
+	order []uint64 // span IDs in insertion order, used as a ring buffer
+	next  int      // index in order of the next span ID to evict
+}
+
+func newSpanTracker() *spanTracker {
+	return &spanTracker{spans: make(map[uint64]*spanState)}
+}
+
+// state returns the state of the span with the given ID, creating it if needed.
+// t.mu must be held.
+func (t *spanTracker) state(spanID uint64) *spanState {
+	if s, ok := t.spans[spanID]; ok {
+		return s
+	}
+	if len(t.order) < maxTrackedSpans {
+		t.order = append(t.order, spanID)
+	} else {
+		delete(t.spans, t.order[t.next])
+		t.order[t.next] = spanID
+		t.next = (t.next + 1) % maxTrackedSpans
+	}
+	s := &spanState{}
+	t.spans[spanID] = s
+	return s
+}
+
+// record attaches rec to span according to cfg.
+func (t *spanTracker) record(span ddtrace.Span, cfg *config, rec slog.Record) {
+	addEvent := cfg.eventLevel != nil && rec.Level >= cfg.eventLevel.Level()
+	markError := cfg.errorLevel != nil && rec.Level >= cfg.errorLevel.Level()
+	if !addEvent && !markError {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := t.state(span.Context().SpanID())
+	if markError && !st.errored {
+		st.errored = true
+		if err := recordError(rec); err != nil {
+			span.SetTag(ext.Error, err)
+			span.SetTag(ext.ErrorMsg, rec.Message+": "+err.Error())
+		} else {
+			span.SetTag(ext.Error, true)
+			span.SetTag(ext.ErrorMsg, rec.Message)
+		}
+	}
+	if !addEvent {
+		return
+	}
+	if len(st.events) >= cfg.eventsLimit {
+		st.dropped++
+		span.SetTag(keySpanEventsDropped, st.dropped)
+		return
+	}
+	st.events = append(st.events, newSpanEvent(rec))
+	b, err := json.Marshal(st.events)
+	if err != nil {
+		log.Debug("contrib/log/slog: failed to encode span events: %v", err)
+		return
+	}
+	span.SetTag(keySpanEvents, string(b))
+}
+
+func newSpanEvent(rec slog.Record) spanEvent {
+	attrs := map[string]interface{}{"level": rec.Level.String()}
+	rec.Attrs(func(a slog.Attr) bool {
+		addAttr(attrs, "", a)
+		return true
+	})
+	return spanEvent{
+		Name:         rec.Message,
+		TimeUnixNano: rec.Time.UnixNano(),
+		Attributes:   attrs,
+	}
+}
+
+// addAttr adds a to attrs, flattening groups into dotted keys.
+func addAttr(attrs map[string]interface{}, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	key := prefix + a.Key
+	switch v.Kind() {
+	case slog.KindGroup:
+		if a.Key != "" {
+			prefix = key + "."
+		}
+		for _, ga := range v.Group() {
+			addAttr(attrs, prefix, ga)
+		}
+	case slog.KindString:
+		attrs[key] = v.String()
+	case slog.KindInt64:
+		attrs[key] = v.Int64()
+	case slog.KindUint64:
+		attrs[key] = v.Uint64()
+	case slog.KindFloat64:
+		attrs[key] = v.Float64()
+	case slog.KindBool:
+		attrs[key] = v.Bool()
+	case slog.KindDuration:
+		attrs[key] = v.Duration().String()
+	case slog.KindTime:
+		attrs[key] = v.Time().Format(time.RFC3339Nano)
+	default:
+		attrs[key] = fmt.Sprint(v.Any())
+	}
+}
+
+// recordError returns the first error found in the attributes of rec, if any.
+func recordError(rec slog.Record) (err error) {
+	rec.Attrs(func(a slog.Attr) bool {
+		if e, ok := a.Value.Resolve().Any().(error); ok {
+			err = e
+			return false
+		}
+		return true
+	})
+	return err
+}
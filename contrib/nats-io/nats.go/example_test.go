@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package nats_test
+
+import (
+	"context"
+	"log"
+	"time"
+
+	natstrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/nats-io/nats.go"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/nats-io/nats.go"
+)
+
+func Example() {
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conn := natstrace.WrapConn(nc, natstrace.WithServiceName("my-service"))
+	defer conn.Close()
+
+	_, err = conn.Subscribe("orders", func(ctx context.Context, msg *nats.Msg) {
+		// ctx carries the span started for the received message.
+		span, _ := tracer.StartSpanFromContext(ctx, "process.order")
+		defer span.Finish()
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := conn.PublishWithContext(context.Background(), "orders", []byte("hello")); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleConn_RequestWithContext() {
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conn := natstrace.WrapConn(nc)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := conn.RequestWithContext(ctx, "rpc", []byte("ping"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("received %s", resp.Data)
+}
+
+func ExampleSubscription_Fetch() {
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conn := natstrace.WrapConn(nc, natstrace.WithDataStreams())
+	defer conn.Close()
+
+	js, err := conn.JetStream()
+	if err != nil {
+		log.Fatal(err)
+	}
+	sub, err := js.PullSubscribe("orders.new", "processor")
+	if err != nil {
+		log.Fatal(err)
+	}
+	msgs, err := sub.Fetch(10, nats.MaxWait(time.Second))
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, msg := range msgs {
+		// continue the trace from the consume span recorded by Fetch.
+		ctx := natstrace.ContextWithMsgSpan(context.Background(), msg)
+		span, ctx := tracer.StartSpanFromContext(ctx, "process.order")
+		js.Ack(msg, nats.Context(ctx))
+		span.Finish()
+	}
+}
@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package nats
+
+import (
+	"context"
+	"sync"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/nats-io/nats.go"
+)
+
+// JetStreamContext wraps a nats.JetStreamContext, tracing published messages and
+// the messages fetched by pull subscriptions created through it. The context of an
+// operation is taken from the nats.Context option, if given.
+type JetStreamContext struct {
+	nats.JetStreamContext
+	cfg *config
+}
+
+// WrapJetStreamContext wraps the given JetStream context so that its operations are traced.
+func WrapJetStreamContext(js nats.JetStreamContext, opts ...Option) *JetStreamContext {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return &JetStreamContext{JetStreamContext: js, cfg: cfg}
+}
+
+// Publish publishes data on the given subject to a stream and traces the operation.
+func (js *JetStreamContext) Publish(subj string, data []byte, opts ...nats.PubOpt) (*nats.PubAck, error) {
+	return js.PublishMsg(&nats.Msg{Subject: subj, Data: data}, opts...)
+}
+
+// PublishMsg publishes the given message to a stream and traces the operation.
+func (js *JetStreamContext) PublishMsg(msg *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error) {
+	// JetStream servers always support headers
+	span := startProduceSpan(contextFromOptions(opts), js.cfg, js.cfg.publishSpanName, ext.SpanKindProducer, msg, true)
+	ack, err := js.JetStreamContext.PublishMsg(msg, opts...)
+	if ack != nil {
+		span.SetTag("stream", ack.Stream)
+		span.SetTag("stream_sequence", ack.Sequence)
+		if ack.Duplicate {
+			span.SetTag("duplicate", true)
+		}
+	}
+	span.Finish(tracer.WithError(err))
+	return ack, err
+}
+
+// PullSubscribe creates a pull subscription whose fetched messages are traced.
+func (js *JetStreamContext) PullSubscribe(subj, durable string, opts ...nats.SubOpt) (*Subscription, error) {
+	sub, err := js.JetStreamContext.PullSubscribe(subj, durable, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Subscription{Subscription: sub, cfg: js.cfg, durable: durable}, nil
+}
+
+// Subscription wraps a JetStream pull subscription.
+type Subscription struct {
+	*nats.Subscription
+	cfg     *config
+	durable string
+
+	mu   sync.Mutex
+	msgs []*nats.Msg // last fetched messages, whose spans are finished by the next Fetch
+}
+
+// fetchedSpans maps the messages returned by Fetch to their consume span, until
+// it is finished.
+var fetchedSpans sync.Map // map[*nats.Msg]ddtrace.Span
+
+// ContextWithMsgSpan returns a copy of ctx carrying the consume span started for
+// msg by Fetch, so that its processing can be traced as a child of this span. ctx
+// is returned unchanged if msg has no unfinished consume span.
+func ContextWithMsgSpan(ctx context.Context, msg *nats.Msg) context.Context {
+	if span, ok := fetchedSpans.Load(msg); ok {
+		return tracer.ContextWithSpan(ctx, span.(ddtrace.Span))
+	}
+	return ctx
+}
+
+// Fetch pulls a batch of messages from the stream. A consume span is started for
+// each message, as a child of the span propagated in the message headers, which
+// can be retrieved with ContextWithMsgSpan. The spans cover the processing of the
+// batch: they are finished by the next call to Fetch, Unsubscribe or Drain.
+func (s *Subscription) Fetch(batch int, opts ...nats.PullOpt) ([]*nats.Msg, error) {
+	s.finishSpans()
+	msgs, err := s.Subscription.Fetch(batch, opts...)
+	if len(msgs) == 0 {
+		return msgs, err
+	}
+	ctx := contextFromOptions(opts)
+	for _, msg := range msgs {
+		span, _ := startConsumeSpan(ctx, s.cfg, msg, "", s.durable)
+		fetchedSpans.Store(msg, span)
+	}
+	s.mu.Lock()
+	s.msgs = msgs
+	s.mu.Unlock()
+	return msgs, err
+}
+
+// Unsubscribe finishes the spans of the last fetched messages and removes the
+// subscription.
+func (s *Subscription) Unsubscribe() error {
+	s.finishSpans()
+	return s.Subscription.Unsubscribe()
+}
+
+// Drain finishes the spans of the last fetched messages and drains the
+// subscription.
+func (s *Subscription) Drain() error {
+	s.finishSpans()
+	return s.Subscription.Drain()
+}
+
+func (s *Subscription) finishSpans() {
+	s.mu.Lock()
+	msgs := s.msgs
+	s.msgs = nil
+	s.mu.Unlock()
+	for _, msg := range msgs {
+		if span, ok := fetchedSpans.LoadAndDelete(msg); ok {
+			span.(ddtrace.Span).Finish()
+		}
+	}
+}
+
+// Ack acknowledges a JetStream message and traces the operation. The span is a
+// child of the context given with nats.Context, or of the consume span started
+// for the message by Fetch, or of the span propagated in the message headers
+// otherwise.
+func (js *JetStreamContext) Ack(msg *nats.Msg, opts ...nats.AckOpt) error {
+	return js.traceAck(msg, "nats.ack", opts, func() error { return msg.Ack(opts...) })
+}
+
+// Nak negatively acknowledges a JetStream message and traces the operation.
+func (js *JetStreamContext) Nak(msg *nats.Msg, opts ...nats.AckOpt) error {
+	return js.traceAck(msg, "nats.nak", opts, func() error { return msg.Nak(opts...) })
+}
+
+// Term terminates the redelivery of a JetStream message and traces the operation.
+func (js *JetStreamContext) Term(msg *nats.Msg, opts ...nats.AckOpt) error {
+	return js.traceAck(msg, "nats.term", opts, func() error { return msg.Term(opts...) })
+}
+
+func (js *JetStreamContext) traceAck(msg *nats.Msg, operation string, opts []nats.AckOpt, do func() error) error {
+	spanOpts := []ddtrace.StartSpanOption{
+		tracer.ResourceName(msg.Subject),
+		tracer.Tag(ext.Component, componentName),
+		tracer.Tag(ext.SpanKind, ext.SpanKindClient),
+		tracer.Tag(ext.MessagingSystem, ext.MessagingSystemNATS),
+	}
+	if js.cfg.serviceName != "" {
+		spanOpts = append(spanOpts, tracer.ServiceName(js.cfg.serviceName))
+	}
+	if js.cfg.measured {
+		spanOpts = append(spanOpts, tracer.Measured())
+	}
+	ctx := contextFromOptions(opts)
+	if _, ok := tracer.SpanFromContext(ctx); !ok {
+		ctx = ContextWithMsgSpan(ctx, msg)
+	}
+	if _, ok := tracer.SpanFromContext(ctx); !ok {
+		if spanctx, err := tracer.Extract(NewMsgCarrier(msg)); err == nil {
+			spanOpts = append(spanOpts, tracer.ChildOf(spanctx))
+		}
+	}
+	span, _ := tracer.StartSpanFromContext(ctx, operation, spanOpts...)
+	setJetStreamTags(span, msg)
+	err := do()
+	span.Finish(tracer.WithError(err))
+	return err
+}
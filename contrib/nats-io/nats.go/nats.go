@@ -0,0 +1,286 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+// Package nats provides functions to trace the nats-io/nats.go package (https://github.com/nats-io/nats.go).
+package nats // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/nats-io/nats.go"
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/datastreams"
+	"gopkg.in/DataDog/dd-trace-go.v1/datastreams/options"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
+
+	"github.com/nats-io/nats.go"
+)
+
+const componentName = "nats-io/nats.go"
+
+func init() {
+	telemetry.LoadIntegration(componentName)
+	tracer.MarkIntegrationImported("github.com/nats-io/nats.go")
+}
+
+// jsAckPrefix is the reply subject prefix used by JetStream for message acknowledgements.
+const jsAckPrefix = "$JS.ACK."
+
+// MsgHandler is a callback for traced subscriptions. The context carries the span
+// started for the received message.
+type MsgHandler func(ctx context.Context, msg *nats.Msg)
+
+// Conn wraps a *nats.Conn, tracing published messages, requests and the messages
+// received by subscriptions created through it.
+type Conn struct {
+	*nats.Conn
+	cfg *config
+}
+
+// WrapConn wraps the given connection so that its operations are traced.
+func WrapConn(nc *nats.Conn, opts ...Option) *Conn {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	log.Debug("contrib/nats-io/nats.go: Wrapping Conn: %#v", cfg)
+	return &Conn{Conn: nc, cfg: cfg}
+}
+
+// Publish publishes data on the given subject and traces the operation.
+func (c *Conn) Publish(subj string, data []byte) error {
+	return c.PublishMsgWithContext(context.Background(), &nats.Msg{Subject: subj, Data: data})
+}
+
+// PublishWithContext publishes data on the given subject. The publish span is a
+// child of the span found in ctx, if any.
+func (c *Conn) PublishWithContext(ctx context.Context, subj string, data []byte) error {
+	return c.PublishMsgWithContext(ctx, &nats.Msg{Subject: subj, Data: data})
+}
+
+// PublishMsg publishes the given message and traces the operation.
+func (c *Conn) PublishMsg(msg *nats.Msg) error {
+	return c.PublishMsgWithContext(context.Background(), msg)
+}
+
+// PublishMsgWithContext publishes the given message. The publish span is a child
+// of the span found in ctx, if any, and its context is propagated in the message headers
+// when the server supports them.
+func (c *Conn) PublishMsgWithContext(ctx context.Context, msg *nats.Msg) error {
+	span := startProduceSpan(ctx, c.cfg, c.cfg.publishSpanName, ext.SpanKindProducer, msg, c.Conn.HeadersSupported())
+	err := c.Conn.PublishMsg(msg)
+	span.Finish(tracer.WithError(err))
+	return err
+}
+
+// Request sends a request on the given subject and waits for a response.
+func (c *Conn) Request(subj string, data []byte, timeout time.Duration) (*nats.Msg, error) {
+	return c.RequestMsg(&nats.Msg{Subject: subj, Data: data}, timeout)
+}
+
+// RequestWithContext sends a request on the given subject and waits for a response
+// until ctx is done.
+func (c *Conn) RequestWithContext(ctx context.Context, subj string, data []byte) (*nats.Msg, error) {
+	return c.RequestMsgWithContext(ctx, &nats.Msg{Subject: subj, Data: data})
+}
+
+// RequestMsg sends the given request message and waits for a response.
+func (c *Conn) RequestMsg(msg *nats.Msg, timeout time.Duration) (*nats.Msg, error) {
+	return c.request(context.Background(), msg, func(msg *nats.Msg) (*nats.Msg, error) {
+		return c.Conn.RequestMsg(msg, timeout)
+	})
+}
+
+// RequestMsgWithContext sends the given request message and waits for a response
+// until ctx is done.
+func (c *Conn) RequestMsgWithContext(ctx context.Context, msg *nats.Msg) (*nats.Msg, error) {
+	return c.request(ctx, msg, func(msg *nats.Msg) (*nats.Msg, error) {
+		return c.Conn.RequestMsgWithContext(ctx, msg)
+	})
+}
+
+// request traces a request-reply exchange as a client span, which becomes the
+// parent of the server span started by the responder's traced subscription.
+func (c *Conn) request(ctx context.Context, msg *nats.Msg, do func(*nats.Msg) (*nats.Msg, error)) (*nats.Msg, error) {
+	span := startProduceSpan(ctx, c.cfg, c.cfg.requestSpanName, ext.SpanKindClient, msg, c.Conn.HeadersSupported())
+	resp, err := do(msg)
+	if resp != nil {
+		span.SetTag("response_size", len(resp.Data))
+	}
+	span.Finish(tracer.WithError(err))
+	return resp, err
+}
+
+// Subscribe subscribes to the given subject. A span is started for each received
+// message and passed to cb through its context.
+func (c *Conn) Subscribe(subj string, cb MsgHandler) (*nats.Subscription, error) {
+	return c.Conn.Subscribe(subj, c.wrapHandler("", cb))
+}
+
+// QueueSubscribe subscribes to the given subject as part of the given queue group.
+// A span is started for each received message and passed to cb through its context.
+func (c *Conn) QueueSubscribe(subj, queue string, cb MsgHandler) (*nats.Subscription, error) {
+	return c.Conn.QueueSubscribe(subj, queue, c.wrapHandler(queue, cb))
+}
+
+// JetStream returns a traced JetStreamContext for the connection.
+func (c *Conn) JetStream(opts ...nats.JSOpt) (*JetStreamContext, error) {
+	js, err := c.Conn.JetStream(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &JetStreamContext{JetStreamContext: js, cfg: c.cfg}, nil
+}
+
+func (c *Conn) wrapHandler(queue string, cb MsgHandler) nats.MsgHandler {
+	return func(msg *nats.Msg) {
+		span, ctx := startConsumeSpan(context.Background(), c.cfg, msg, queue, "")
+		defer span.Finish()
+		cb(ctx, msg)
+	}
+}
+
+// contextFromOptions returns the context given to an operation through nats.Context,
+// or context.Background if there is none.
+func contextFromOptions[T any](opts []T) context.Context {
+	for _, opt := range opts {
+		if c, ok := any(opt).(nats.ContextOpt); ok && c.Context != nil {
+			return c.Context
+		}
+	}
+	return context.Background()
+}
+
+// startProduceSpan starts a span for a message about to be sent. Its context is
+// injected into the message headers if headers are supported by the server, as
+// publishing a message with headers to a server without their support fails.
+func startProduceSpan(ctx context.Context, cfg *config, operation, kind string, msg *nats.Msg, headers bool) ddtrace.Span {
+	opts := []ddtrace.StartSpanOption{
+		tracer.ResourceName(msg.Subject),
+		tracer.SpanType(ext.SpanTypeMessageProducer),
+		tracer.Tag("message_size", len(msg.Data)),
+		tracer.Tag(ext.Component, componentName),
+		tracer.Tag(ext.SpanKind, kind),
+		tracer.Tag(ext.MessagingSystem, ext.MessagingSystemNATS),
+	}
+	if cfg.serviceName != "" {
+		opts = append(opts, tracer.ServiceName(cfg.serviceName))
+	}
+	if cfg.measured {
+		opts = append(opts, tracer.Measured())
+	}
+	span, ctx := tracer.StartSpanFromContext(ctx, operation, opts...)
+	if !headers {
+		return span
+	}
+	if err := tracer.Inject(span.Context(), NewMsgCarrier(msg)); err != nil {
+		log.Debug("contrib/nats-io/nats.go: failed injecting tracing headers: %v", err)
+	}
+	setProduceCheckpoint(ctx, cfg.dataStreamsEnabled, msg)
+	return span
+}
+
+// startConsumeSpan starts a span for a received message, received by the given
+// queue group or JetStream consumer, if any. The span is a child of the span
+// propagated in the message headers, or of the span of ctx otherwise. Messages
+// expecting a reply, other than JetStream acknowledgements, are traced as server
+// spans. The message headers are left unchanged.
+func startConsumeSpan(ctx context.Context, cfg *config, msg *nats.Msg, queue, consumer string) (ddtrace.Span, context.Context) {
+	kind := ext.SpanKindConsumer
+	if msg.Reply != "" && !strings.HasPrefix(msg.Reply, jsAckPrefix) {
+		kind = ext.SpanKindServer
+	}
+	opts := []ddtrace.StartSpanOption{
+		tracer.ResourceName(msg.Subject),
+		tracer.SpanType(ext.SpanTypeMessageConsumer),
+		tracer.Tag("message_size", len(msg.Data)),
+		tracer.Tag(ext.Component, componentName),
+		tracer.Tag(ext.SpanKind, kind),
+		tracer.Tag(ext.MessagingSystem, ext.MessagingSystemNATS),
+	}
+	group := queue
+	if queue != "" {
+		opts = append(opts, tracer.Tag("queue", queue))
+	}
+	if consumer != "" {
+		opts = append(opts, tracer.Tag("consumer", consumer))
+		group = consumer
+	}
+	if cfg.serviceName != "" {
+		opts = append(opts, tracer.ServiceName(cfg.serviceName))
+	}
+	if cfg.measured {
+		opts = append(opts, tracer.Measured())
+	}
+	carrier := NewMsgCarrier(msg)
+	if spanctx, err := tracer.Extract(carrier); err == nil {
+		opts = append(opts, tracer.ChildOf(spanctx))
+	} else if parent, ok := tracer.SpanFromContext(ctx); ok {
+		opts = append(opts, tracer.ChildOf(parent.Context()))
+	}
+	span := tracer.StartSpan(cfg.consumeSpanName, opts...)
+	ctx = tracer.ContextWithSpan(ctx, span)
+	setJetStreamTags(span, msg)
+	ctx = setConsumeCheckpoint(ctx, cfg.dataStreamsEnabled, group, msg)
+	return span, ctx
+}
+
+// setJetStreamTags tags the span with the JetStream metadata of msg, if it has any.
+func setJetStreamTags(span ddtrace.Span, msg *nats.Msg) {
+	if !strings.HasPrefix(msg.Reply, jsAckPrefix) {
+		return
+	}
+	md, err := msg.Metadata()
+	if err != nil {
+		return
+	}
+	span.SetTag("stream", md.Stream)
+	span.SetTag("consumer", md.Consumer)
+	span.SetTag("stream_sequence", md.Sequence.Stream)
+	span.SetTag("num_delivered", md.NumDelivered)
+}
+
+func setProduceCheckpoint(ctx context.Context, enabled bool, msg *nats.Msg) {
+	if !enabled || msg == nil {
+		return
+	}
+	edges := []string{"direction:out", "topic:" + msg.Subject, "type:nats"}
+	carrier := NewMsgCarrier(msg)
+	ctx, ok := tracer.SetDataStreamsCheckpointWithParams(datastreams.ExtractFromBase64Carrier(ctx, carrier), options.CheckpointParams{PayloadSize: getMsgSize(msg)}, edges...)
+	if !ok {
+		return
+	}
+	datastreams.InjectToBase64Carrier(ctx, carrier)
+}
+
+func setConsumeCheckpoint(ctx context.Context, enabled bool, group string, msg *nats.Msg) context.Context {
+	if !enabled || msg == nil {
+		return ctx
+	}
+	edges := []string{"direction:in", "topic:" + msg.Subject, "type:nats"}
+	if group != "" {
+		edges = append(edges, "group:"+group)
+	}
+	carrier := NewMsgCarrier(msg)
+	out, ok := tracer.SetDataStreamsCheckpointWithParams(datastreams.ExtractFromBase64Carrier(ctx, carrier), options.CheckpointParams{PayloadSize: getMsgSize(msg)}, edges...)
+	if !ok {
+		return ctx
+	}
+	datastreams.InjectToBase64Carrier(out, carrier)
+	return out
+}
+
+func getMsgSize(msg *nats.Msg) (size int64) {
+	for k, vals := range msg.Header {
+		for _, v := range vals {
+			size += int64(len(k) + len(v))
+		}
+	}
+	return size + int64(len(msg.Data))
+}
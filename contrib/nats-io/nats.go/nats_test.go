@@ -0,0 +1,377 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package nats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/namingschematest"
+	"gopkg.in/DataDog/dd-trace-go.v1/datastreams"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishSubscribe(t *testing.T) {
+	mt, nc := setup(t)
+
+	done := make(chan struct{})
+	sub, err := nc.QueueSubscribe("orders", "workers", func(ctx context.Context, msg *nats.Msg) {
+		defer close(done)
+		_, ok := tracer.SpanFromContext(ctx)
+		assert.True(t, ok, "no span")
+		assert.Equal(t, []byte("hello"), msg.Data)
+	})
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	root, ctx := tracer.StartSpanFromContext(context.Background(), "propagation-test", tracer.WithSpanID(42))
+	require.NoError(t, nc.PublishWithContext(ctx, "orders", []byte("hello")))
+	root.Finish()
+	waitFor(t, done)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 3)
+	pub, consume := spans[0], spans[2]
+	assert.Equal(t, "nats.publish", pub.OperationName())
+	assert.Equal(t, uint64(42), pub.ParentID())
+	assert.Equal(t, map[string]interface{}{
+		"message_size":      5,
+		ext.ResourceName:    "orders",
+		ext.SpanType:        ext.SpanTypeMessageProducer,
+		ext.ServiceName:     nil,
+		ext.Component:       "nats-io/nats.go",
+		ext.SpanKind:        ext.SpanKindProducer,
+		ext.MessagingSystem: "nats",
+	}, pub.Tags())
+
+	assert.Equal(t, "nats.consume", consume.OperationName())
+	assert.Equal(t, pub.SpanID(), consume.ParentID())
+	assert.Equal(t, uint64(42), consume.TraceID())
+	assert.Equal(t, map[string]interface{}{
+		"message_size":      5,
+		"queue":             "workers",
+		ext.ResourceName:    "orders",
+		ext.SpanType:        ext.SpanTypeMessageConsumer,
+		ext.Component:       "nats-io/nats.go",
+		ext.SpanKind:        ext.SpanKindConsumer,
+		ext.MessagingSystem: "nats",
+	}, consume.Tags())
+}
+
+func TestPublishWithoutHeaderSupport(t *testing.T) {
+	mt, nc := setupServer(t, &server.Options{NoHeaderSupport: true})
+	require.False(t, nc.HeadersSupported())
+
+	done := make(chan struct{})
+	sub, err := nc.Subscribe("orders", func(_ context.Context, msg *nats.Msg) {
+		defer close(done)
+		assert.Empty(t, msg.Header)
+	})
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	msg := &nats.Msg{Subject: "orders", Data: []byte("hello")}
+	require.NoError(t, nc.PublishMsg(msg))
+	assert.Nil(t, msg.Header)
+	waitFor(t, done)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "nats.publish", spans[0].OperationName())
+	assert.Nil(t, spans[0].Tag(ext.Error))
+	// Without headers, the trace can't be propagated
+	assert.NotEqual(t, spans[0].TraceID(), spans[1].TraceID())
+}
+
+func TestRequestReply(t *testing.T) {
+	mt, nc := setup(t)
+
+	sub, err := nc.Subscribe("rpc", func(_ context.Context, msg *nats.Msg) {
+		assert.NoError(t, msg.Respond([]byte("pong!")))
+	})
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	resp, err := nc.Request("rpc", []byte("ping"), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("pong!"), resp.Data)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 2)
+	server, client := spans[0], spans[1]
+	assert.Equal(t, "nats.request", client.OperationName())
+	assert.Equal(t, ext.SpanKindClient, client.Tag(ext.SpanKind))
+	assert.Equal(t, 5, client.Tag("response_size"))
+	assert.Equal(t, "nats.consume", server.OperationName())
+	assert.Equal(t, ext.SpanKindServer, server.Tag(ext.SpanKind))
+	assert.Equal(t, client.SpanID(), server.ParentID())
+}
+
+func TestRequestNoResponders(t *testing.T) {
+	mt, nc := setup(t)
+
+	_, err := nc.Request("nobody", []byte("ping"), time.Second)
+	require.ErrorIs(t, err, nats.ErrNoResponders)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, nats.ErrNoResponders, spans[0].Tag(ext.Error))
+}
+
+func TestJetStream(t *testing.T) {
+	mt, nc := setup(t)
+
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}})
+	require.NoError(t, err)
+
+	ack, err := js.Publish("orders.new", []byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), ack.Sequence)
+
+	sub, err := js.PullSubscribe("orders.new", "processor")
+	require.NoError(t, err)
+	msgs, err := sub.Fetch(1, nats.MaxWait(time.Second))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	// The received message is left unchanged
+	spanctx, err := tracer.Extract(NewMsgCarrier(msgs[0]))
+	require.NoError(t, err)
+	pubID := spanctx.SpanID()
+	span, ok := tracer.SpanFromContext(ContextWithMsgSpan(context.Background(), msgs[0]))
+	require.True(t, ok)
+	consumeID := span.Context().SpanID()
+	require.NoError(t, js.Ack(msgs[0]))
+	// The consume span covers the processing of the message
+	assert.Len(t, mt.OpenSpans(), 1)
+	require.NoError(t, sub.Unsubscribe())
+	assert.Empty(t, mt.OpenSpans())
+	// The finished consume span is no longer retrieved
+	_, ok = tracer.SpanFromContext(ContextWithMsgSpan(context.Background(), msgs[0]))
+	assert.False(t, ok)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 3)
+	pub, ackSpan, consume := spans[0], spans[1], spans[2]
+	assert.Equal(t, "nats.publish", pub.OperationName())
+	assert.Equal(t, pubID, pub.SpanID())
+	assert.Equal(t, consumeID, consume.SpanID())
+	assert.Equal(t, "ORDERS", pub.Tag("stream"))
+	assert.Equal(t, uint64(1), pub.Tag("stream_sequence"))
+
+	assert.Equal(t, "nats.consume", consume.OperationName())
+	assert.Equal(t, ext.SpanKindConsumer, consume.Tag(ext.SpanKind))
+	assert.Equal(t, pub.SpanID(), consume.ParentID())
+	assert.Nil(t, consume.Tag("queue"))
+	assert.Equal(t, "ORDERS", consume.Tag("stream"))
+	assert.Equal(t, "processor", consume.Tag("consumer"))
+	assert.Equal(t, uint64(1), consume.Tag("num_delivered"))
+
+	assert.Equal(t, "nats.ack", ackSpan.OperationName())
+	assert.Equal(t, consume.SpanID(), ackSpan.ParentID())
+}
+
+func TestJetStreamServiceName(t *testing.T) {
+	mt, nc := setup(t, WithServiceName("orders-service"))
+
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}})
+	require.NoError(t, err)
+	_, err = js.Publish("orders.new", []byte("hello"))
+	require.NoError(t, err)
+
+	sub, err := js.PullSubscribe("orders.new", "processor")
+	require.NoError(t, err)
+	msgs, err := sub.Fetch(1, nats.MaxWait(time.Second))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.NoError(t, js.Nak(msgs[0]))
+	require.NoError(t, sub.Drain())
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 3)
+	for _, s := range spans {
+		assert.Equal(t, "orders-service", s.Tag(ext.ServiceName), s.OperationName())
+	}
+}
+
+func TestFetchFinishesPreviousSpans(t *testing.T) {
+	mt, nc := setup(t)
+
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}})
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = js.Publish("orders.new", []byte("hello"))
+		require.NoError(t, err)
+	}
+
+	sub, err := js.PullSubscribe("orders.new", "processor")
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+	_, err = sub.Fetch(1, nats.MaxWait(time.Second))
+	require.NoError(t, err)
+	assert.Len(t, mt.FinishedSpans(), 2)
+	_, err = sub.Fetch(1, nats.MaxWait(time.Second))
+	require.NoError(t, err)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "nats.consume", spans[2].OperationName())
+	assert.Equal(t, uint64(1), spans[2].Tag("stream_sequence"))
+	assert.Len(t, mt.OpenSpans(), 1)
+}
+
+func TestAckWithContext(t *testing.T) {
+	mt, nc := setup(t)
+
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}})
+	require.NoError(t, err)
+	_, err = js.Publish("orders.new", []byte("hello"))
+	require.NoError(t, err)
+
+	sub, err := js.PullSubscribe("orders.new", "processor")
+	require.NoError(t, err)
+	msgs, err := sub.Fetch(1, nats.MaxWait(time.Second))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	root, ctx := tracer.StartSpanFromContext(context.Background(), "process")
+	require.NoError(t, js.Term(msgs[0], nats.Context(ctx)))
+	root.Finish()
+	require.NoError(t, sub.Unsubscribe())
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 4)
+	assert.Equal(t, "nats.term", spans[1].OperationName())
+	assert.Equal(t, root.Context().SpanID(), spans[1].ParentID())
+}
+
+func TestNamingSchema(t *testing.T) {
+	genSpans := namingschematest.GenSpansFn(func(t *testing.T, serviceOverride string) []mocktracer.Span {
+		var opts []Option
+		if serviceOverride != "" {
+			opts = append(opts, WithServiceName(serviceOverride))
+		}
+		mt, nc := setup(t, opts...)
+
+		done := make(chan struct{})
+		sub, err := nc.Subscribe("orders", func(context.Context, *nats.Msg) { close(done) })
+		require.NoError(t, err)
+		defer sub.Unsubscribe()
+
+		require.NoError(t, nc.Publish("orders", []byte("hello")))
+		waitFor(t, done)
+
+		return mt.FinishedSpans()
+	})
+	assertOpV0 := func(t *testing.T, spans []mocktracer.Span) {
+		require.Len(t, spans, 2)
+		assert.Equal(t, "nats.publish", spans[0].OperationName())
+		assert.Equal(t, "nats.consume", spans[1].OperationName())
+	}
+	assertOpV1 := func(t *testing.T, spans []mocktracer.Span) {
+		require.Len(t, spans, 2)
+		assert.Equal(t, "nats.send", spans[0].OperationName())
+		assert.Equal(t, "nats.process", spans[1].OperationName())
+	}
+	serviceOverride := namingschematest.TestServiceOverride
+	wantServiceNameV0 := namingschematest.ServiceNameAssertions{
+		WithDefaults:             []string{"", ""},
+		WithDDService:            []string{"", ""},
+		WithDDServiceAndOverride: []string{serviceOverride, serviceOverride},
+	}
+	t.Run("ServiceName", namingschematest.NewServiceNameTest(genSpans, wantServiceNameV0))
+	t.Run("SpanName", namingschematest.NewSpanNameTest(genSpans, assertOpV0, assertOpV1))
+}
+
+func TestDataStreams(t *testing.T) {
+	t.Run("env", func(t *testing.T) {
+		cfg := defaultConfig()
+		assert.False(t, cfg.dataStreamsEnabled)
+
+		t.Setenv("DD_DATA_STREAMS_ENABLED", "true")
+		cfg = defaultConfig()
+		assert.True(t, cfg.dataStreamsEnabled)
+	})
+
+	t.Run("checkpoints", func(t *testing.T) {
+		_, nc := setup(t, WithDataStreams())
+
+		js, err := nc.JetStream()
+		require.NoError(t, err)
+		_, err = js.AddStream(&nats.StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}})
+		require.NoError(t, err)
+
+		published := &nats.Msg{Subject: "orders.new", Data: []byte("hello")}
+		_, err = js.PublishMsg(published)
+		require.NoError(t, err)
+		p, ok := datastreams.PathwayFromContext(datastreams.ExtractFromBase64Carrier(context.Background(), NewMsgCarrier(published)))
+		require.True(t, ok)
+		expectedCtx, _ := tracer.SetDataStreamsCheckpoint(context.Background(), "direction:out", "topic:orders.new", "type:nats")
+		expected, _ := datastreams.PathwayFromContext(expectedCtx)
+		assert.NotEqual(t, uint64(0), expected.GetHash())
+		assert.Equal(t, expected.GetHash(), p.GetHash())
+
+		sub, err := js.PullSubscribe("orders.new", "processor")
+		require.NoError(t, err)
+		defer sub.Unsubscribe()
+		msgs, err := sub.Fetch(1, nats.MaxWait(time.Second))
+		require.NoError(t, err)
+		require.Len(t, msgs, 1)
+		p, ok = datastreams.PathwayFromContext(datastreams.ExtractFromBase64Carrier(context.Background(), NewMsgCarrier(msgs[0])))
+		require.True(t, ok)
+		expectedCtx, _ = tracer.SetDataStreamsCheckpoint(expectedCtx, "direction:in", "topic:orders.new", "type:nats", "group:processor")
+		expected, _ = datastreams.PathwayFromContext(expectedCtx)
+		assert.NotEqual(t, uint64(0), expected.GetHash())
+		assert.Equal(t, expected.GetHash(), p.GetHash())
+	})
+}
+
+func waitFor(t *testing.T, done <-chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
+
+func setup(t *testing.T, opts ...Option) (mocktracer.Tracer, *Conn) {
+	return setupServer(t, &server.Options{JetStream: true, StoreDir: t.TempDir()}, opts...)
+}
+
+func setupServer(t *testing.T, sopts *server.Options, opts ...Option) (mocktracer.Tracer, *Conn) {
+	sopts.Host = "127.0.0.1"
+	sopts.Port = server.RANDOM_PORT
+	sopts.NoLog = true
+	sopts.NoSigs = true
+	srv, err := server.NewServer(sopts)
+	require.NoError(t, err)
+	go srv.Start()
+	require.True(t, srv.ReadyForConnections(5*time.Second), "nats server not ready")
+	t.Cleanup(srv.Shutdown)
+
+	nc, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(nc.Close)
+
+	mt := mocktracer.Start()
+	t.Cleanup(mt.Stop)
+	return mt, WrapConn(nc, opts...)
+}
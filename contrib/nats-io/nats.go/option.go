@@ -0,0 +1,55 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package nats
+
+import (
+	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
+)
+
+type config struct {
+	serviceName        string
+	publishSpanName    string
+	consumeSpanName    string
+	requestSpanName    string
+	measured           bool
+	dataStreamsEnabled bool
+}
+
+func defaultConfig() *config {
+	return &config{
+		serviceName:        namingschema.ServiceNameOverrideV0("", ""),
+		publishSpanName:    namingschema.OpName(namingschema.NATSOutbound),
+		consumeSpanName:    namingschema.OpName(namingschema.NATSInbound),
+		requestSpanName:    namingschema.OpName(namingschema.NATSRequest),
+		measured:           false,
+		dataStreamsEnabled: internal.BoolEnv("DD_DATA_STREAMS_ENABLED", false),
+	}
+}
+
+// Option is used to customize spans started by a traced Conn or JetStreamContext.
+type Option func(cfg *config)
+
+// WithServiceName sets the service name tag for traces started by a traced Conn or JetStreamContext.
+func WithServiceName(serviceName string) Option {
+	return func(cfg *config) {
+		cfg.serviceName = serviceName
+	}
+}
+
+// WithMeasured sets the measured tag for traces started by a traced Conn or JetStreamContext.
+func WithMeasured() Option {
+	return func(cfg *config) {
+		cfg.measured = true
+	}
+}
+
+// WithDataStreams enables the Data Streams monitoring product features: https://www.datadoghq.com/product/data-streams-monitoring/
+func WithDataStreams() Option {
+	return func(cfg *config) {
+		cfg.dataStreamsEnabled = true
+	}
+}
@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package os_test
+
+import (
+	"net/http"
+	"os"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
+	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
+	ostrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/os"
+)
+
+func ExampleReadFile() {
+	mux := httptrace.NewServeMux()
+	mux.HandleFunc("/report", func(w http.ResponseWriter, r *http.Request) {
+		// The file opening is blocked if name is a local file inclusion attempt.
+		b, err := ostrace.ReadFile(r.Context(), "/var/reports/"+r.URL.Query().Get("name"))
+		if events.IsSecurityError(err) {
+			// The response is written by the AppSec middleware.
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Write(b)
+	})
+	http.ListenAndServe(":8080", mux)
+}
+
+func ExampleFileServer() {
+	mux := httptrace.NewServeMux()
+	mux.Handle("/static/", http.StripPrefix("/static/", ostrace.FileServer(os.DirFS("/var/www"))))
+	http.ListenAndServe(":8080", mux)
+}
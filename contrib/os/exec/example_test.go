@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package exec_test
+
+import (
+	"net/http"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
+	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
+	exectrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/os/exec"
+)
+
+func ExampleCommandContext() {
+	mux := httptrace.NewServeMux()
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		host := r.URL.Query().Get("host")
+		// The command is blocked if host is a shell injection attempt.
+		out, err := exectrace.CommandContext(r.Context(), "sh", "-c", "ping -c 1 "+host).Output()
+		if events.IsSecurityError(err) {
+			// The response is written by the AppSec middleware.
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(out)
+	})
+	http.ListenAndServe(":8080", mux)
+}
+
+func ExampleCmd_WithContext() {
+	mux := httptrace.NewServeMux()
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		host := r.URL.Query().Get("host")
+		// The context of the request must be given to protect the command.
+		cmd := exectrace.Command("sh", "-c", "ping -c 1 "+host).WithContext(r.Context())
+		if err := cmd.Run(); err != nil && !events.IsSecurityError(err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	http.ListenAndServe(":8080", mux)
+}
@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+// Package exec provides a wrapper of the os/exec package (https://pkg.go.dev/os/exec)
+// protecting the commands it runs against command injections with AppSec RASP.
+//
+// A command is only protected when it is given the context of the request it is run
+// for, with CommandContext or WithContext:
+//
+//	exec.Command("ping", "-c", "1", host).WithContext(r.Context()).Run()
+//
+// The command line of every process is sent to the WAF as server.sys.exec.cmd, and
+// the command run by a shell invoked with -c, e.g. sh -c "ping -c 1 $host", as
+// server.sys.shell.cmd. The embedded security rules only check the latter, for shell
+// injections: the command lines of other processes are only checked by security rules
+// targeting server.sys.exec.cmd, which must be provided by a ruleset update, e.g.
+// through remote configuration or DD_APPSEC_RULES.
+package exec // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/os/exec"
+
+import (
+	"context"
+	"os/exec"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/ossec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
+)
+
+const componentName = "os/exec"
+
+func init() {
+	telemetry.LoadIntegration(componentName)
+	tracer.MarkIntegrationImported("os/exec")
+}
+
+// Cmd wraps an *exec.Cmd. When AppSec RASP is enabled, its command line is checked
+// against the request found in the context of the command before the process is
+// started. The process is not started and a *events.BlockingSecurityEvent error is
+// returned when the command is blocked; it can be checked with events.IsSecurityError.
+type Cmd struct {
+	*exec.Cmd
+	ctx context.Context
+}
+
+// Command returns a Cmd executing the named program with the given arguments, like
+// exec.Command. The command has no request context: it is not protected until the
+// context of the request is given with WithContext.
+func Command(name string, arg ...string) *Cmd {
+	return &Cmd{Cmd: exec.Command(name, arg...), ctx: context.Background()}
+}
+
+// CommandContext returns a Cmd executing the named program with the given arguments,
+// like exec.CommandContext. The command is protected using the request found in ctx.
+func CommandContext(ctx context.Context, name string, arg ...string) *Cmd {
+	return &Cmd{Cmd: exec.CommandContext(ctx, name, arg...), ctx: ctx}
+}
+
+// WithContext sets the context in which the request protecting the command is found.
+// Unlike CommandContext, ctx does not control the lifetime of the process.
+func (c *Cmd) WithContext(ctx context.Context) *Cmd {
+	c.ctx = ctx
+	return c
+}
+
+// Start starts the command like (*exec.Cmd).Start, unless it is blocked.
+func (c *Cmd) Start() error {
+	if err := c.protect(); err != nil {
+		return err
+	}
+	return c.Cmd.Start()
+}
+
+// Run starts the command and waits for it to complete like (*exec.Cmd).Run, unless
+// it is blocked.
+func (c *Cmd) Run() error {
+	if err := c.protect(); err != nil {
+		return err
+	}
+	return c.Cmd.Run()
+}
+
+// Output runs the command and returns its standard output like (*exec.Cmd).Output,
+// unless it is blocked.
+func (c *Cmd) Output() ([]byte, error) {
+	if err := c.protect(); err != nil {
+		return nil, err
+	}
+	return c.Cmd.Output()
+}
+
+// CombinedOutput runs the command and returns its combined standard output and
+// standard error like (*exec.Cmd).CombinedOutput, unless it is blocked.
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	if err := c.protect(); err != nil {
+		return nil, err
+	}
+	return c.Cmd.CombinedOutput()
+}
+
+func (c *Cmd) protect() error {
+	if !appsec.RASPEnabled() {
+		return nil
+	}
+	return ossec.ProtectExecOperation(c.ctx, c.Args)
+}
@@ -0,0 +1,155 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package exec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
+	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/ossec"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand(t *testing.T) {
+	out, err := Command("echo", "hello").Output()
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(out))
+}
+
+func TestShellCommand(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		cmd  string
+		ok   bool
+	}{
+		{args: []string{"sh", "-c", "ls /tmp"}, cmd: "ls /tmp", ok: true},
+		{args: []string{"/bin/bash", "-e", "-c", "echo $HOME", "bash"}, cmd: "echo $HOME", ok: true},
+		{args: []string{"bash", "-lc", "ls /tmp"}, cmd: "ls /tmp", ok: true},
+		{args: []string{"bash", "-o", "pipefail", "-ec", "ls /tmp"}, cmd: "ls /tmp", ok: true},
+		{args: []string{"sh", "script.sh"}},
+		{args: []string{"sh", "script.sh", "-c", "ls /tmp"}},
+		{args: []string{"bash", "--login", "script.sh"}},
+		{args: []string{"sh", "-c"}},
+		{args: []string{"ls", "-c", "/tmp"}},
+	} {
+		cmd, ok := ossec.ShellCommand(tc.args)
+		assert.Equal(t, tc.ok, ok, tc.args)
+		assert.Equal(t, tc.cmd, cmd, tc.args)
+	}
+}
+
+func TestAppsec(t *testing.T) {
+	t.Setenv("DD_APPSEC_RULES", "../../../internal/appsec/testdata/rasp.json")
+
+	for _, enabled := range []bool{true, false} {
+		t.Run(strconv.FormatBool(enabled), func(t *testing.T) {
+			t.Setenv("DD_APPSEC_RASP_ENABLED", strconv.FormatBool(enabled))
+
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			appsec.Start()
+			if !appsec.Enabled() {
+				t.Skip("appsec not enabled")
+			}
+			defer appsec.Stop()
+
+			mux := httptrace.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				dir := r.URL.Query().Get("dir")
+				err := CommandContext(r.Context(), "sh", "-c", "ls "+dir).Run()
+				if enabled {
+					require.True(t, events.IsSecurityError(err))
+					return
+				}
+				require.False(t, events.IsSecurityError(err))
+				w.WriteHeader(http.StatusNoContent)
+			})
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			res, err := srv.Client().Get(srv.URL + "?dir=" + url.QueryEscape("/tmp; cat /etc/passwd"))
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			spans := mt.FinishedSpans()
+			require.Len(t, spans, 1)
+			if enabled {
+				require.Equal(t, http.StatusForbidden, res.StatusCode)
+				require.Contains(t, spans[0].Tag("_dd.appsec.json"), "rasp-932-100")
+				require.Contains(t, spans[0].Tags(), "_dd.stack")
+			} else {
+				require.Equal(t, http.StatusNoContent, res.StatusCode)
+				require.NotContains(t, spans[0].Tags(), "_dd.stack")
+			}
+		})
+	}
+}
+
+func TestAppsecDefaultRules(t *testing.T) {
+	t.Setenv("DD_APPSEC_RASP_ENABLED", "true")
+
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	appsec.Start()
+	if !appsec.Enabled() {
+		t.Skip("appsec not enabled")
+	}
+	defer appsec.Stop()
+
+	mux := httptrace.NewServeMux()
+	mux.HandleFunc("/shell", func(w http.ResponseWriter, r *http.Request) {
+		err := Command("sh", "-c", "ls "+r.URL.Query().Get("dir")).WithContext(r.Context()).Run()
+		// The embedded shell injection rule only reports the attack
+		require.False(t, events.IsSecurityError(err))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/exec", func(w http.ResponseWriter, r *http.Request) {
+		err := Command("ls", r.URL.Query().Get("dir")).WithContext(r.Context()).Run()
+		require.False(t, events.IsSecurityError(err))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		path     string
+		detected bool
+	}{
+		{path: "/shell", detected: true},
+		// The embedded rules have no rule on server.sys.exec.cmd
+		{path: "/exec", detected: false},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			mt.Reset()
+			res, err := srv.Client().Get(srv.URL + tc.path + "?dir=" + url.QueryEscape("/tmp; cat /etc/passwd"))
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+			spans := mt.FinishedSpans()
+			require.Len(t, spans, 1)
+			if tc.detected {
+				require.Contains(t, spans[0].Tag("_dd.appsec.json"), "rasp-932-100")
+				require.Contains(t, spans[0].Tags(), "_dd.stack")
+			} else {
+				// The query is still reported by the non-RASP rules
+				require.NotContains(t, spans[0].Tag("_dd.appsec.json"), "rasp-")
+				require.NotContains(t, spans[0].Tags(), "_dd.stack")
+			}
+		})
+	}
+}
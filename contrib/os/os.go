@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+// Package os provides wrappers of the file functions of the os package (https://pkg.go.dev/os)
+// protecting the files they open against local file inclusions with AppSec RASP.
+//
+// The file paths are checked against the request found in the given context before the
+// files are opened. When a file opening is blocked, the file is not opened and an
+// *fs.PathError wrapping an *events.BlockingSecurityEvent error is returned; it can be
+// checked with events.IsSecurityError.
+package os // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/os"
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/ossec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
+)
+
+const componentName = "os"
+
+func init() {
+	telemetry.LoadIntegration(componentName)
+	tracer.MarkIntegrationImported("os")
+}
+
+// Open opens the named file for reading like os.Open, unless it is blocked.
+func Open(ctx context.Context, name string) (*os.File, error) {
+	return OpenFile(ctx, name, os.O_RDONLY, 0)
+}
+
+// OpenFile opens the named file like os.OpenFile, unless it is blocked.
+func OpenFile(ctx context.Context, name string, flag int, perm fs.FileMode) (*os.File, error) {
+	if err := protect(ctx, name, flag, perm); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(name, flag, perm)
+}
+
+// ReadFile reads the named file like os.ReadFile, unless it is blocked.
+func ReadFile(ctx context.Context, name string) ([]byte, error) {
+	if err := protect(ctx, name, os.O_RDONLY, 0); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(name)
+}
+
+// WriteFile writes data to the named file like os.WriteFile, unless it is blocked.
+func WriteFile(ctx context.Context, name string, data []byte, perm fs.FileMode) error {
+	if err := protect(ctx, name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm); err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, perm)
+}
+
+// WrapFS returns a file system opening the files of fsys unless they are blocked. The
+// names given to Open, which are relative to the root of fsys, are checked against the
+// request found in ctx.
+func WrapFS(ctx context.Context, fsys fs.FS) fs.FS {
+	return &protectedFS{fsys: fsys, ctx: ctx}
+}
+
+type protectedFS struct {
+	fsys fs.FS
+	ctx  context.Context
+	// blocked is set once a file opening is blocked.
+	blocked atomic.Bool
+}
+
+// Open implements fs.FS.
+func (p *protectedFS) Open(name string) (fs.File, error) {
+	if err := protect(p.ctx, name, os.O_RDONLY, 0); err != nil {
+		p.blocked.Store(true)
+		return nil, err
+	}
+	return p.fsys.Open(name)
+}
+
+// FileServer returns a handler serving the files of fsys like http.FileServer, where
+// the files are opened with WrapFS using the context of the request. It must be used
+// behind a handler monitored by AppSec, such as the ones of contrib/net/http, which
+// responds to the requests whose file opening is blocked.
+func FileServer(fsys fs.FS) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pfs := &protectedFS{fsys: fsys, ctx: r.Context()}
+		http.FileServer(http.FS(pfs)).ServeHTTP(&blockableResponseWriter{ResponseWriter: w, fs: pfs}, r)
+	})
+}
+
+// blockableResponseWriter discards the error response written by http.FileServer when
+// a file opening is blocked, so that the blocking response can be written instead.
+type blockableResponseWriter struct {
+	http.ResponseWriter
+	fs     *protectedFS
+	header http.Header
+}
+
+func (w *blockableResponseWriter) Header() http.Header {
+	if !w.fs.blocked.Load() {
+		return w.ResponseWriter.Header()
+	}
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+func (w *blockableResponseWriter) WriteHeader(status int) {
+	if w.fs.blocked.Load() {
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *blockableResponseWriter) Write(b []byte) (int, error) {
+	if w.fs.blocked.Load() {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func protect(ctx context.Context, name string, flag int, perm fs.FileMode) error {
+	if !appsec.RASPEnabled() {
+		return nil
+	}
+	if err := ossec.ProtectOpenOperation(ctx, name, flag, perm); err != nil {
+		return &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package os
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"testing/fstest"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
+	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFiles(t *testing.T) {
+	ctx := context.Background()
+	name := filepath.Join(t.TempDir(), "file")
+
+	require.NoError(t, WriteFile(ctx, name, []byte("hello"), 0o600))
+
+	b, err := ReadFile(ctx, name)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+
+	f, err := Open(ctx, name)
+	require.NoError(t, err)
+	b, err = io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+	require.NoError(t, f.Close())
+
+	f, err = OpenFile(ctx, name, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(" world")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	b, err = fs.ReadFile(WrapFS(ctx, os.DirFS(filepath.Dir(name))), "file")
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(b))
+}
+
+func TestFileServer(t *testing.T) {
+	fsys := fstest.MapFS{"hello.txt": {Data: []byte("hello")}}
+	srv := httptest.NewServer(FileServer(fsys))
+	defer srv.Close()
+
+	res, err := srv.Client().Get(srv.URL + "/hello.txt")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "hello", string(b))
+}
+
+func TestAppsec(t *testing.T) {
+	t.Setenv("DD_APPSEC_RULES", "../../internal/appsec/testdata/rasp.json")
+
+	for _, enabled := range []bool{true, false} {
+		t.Run(strconv.FormatBool(enabled), func(t *testing.T) {
+			t.Setenv("DD_APPSEC_RASP_ENABLED", strconv.FormatBool(enabled))
+
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			appsec.Start()
+			if !appsec.Enabled() {
+				t.Skip("appsec not enabled")
+			}
+			defer appsec.Stop()
+
+			mux := httptrace.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				_, err := ReadFile(r.Context(), r.URL.Query().Get("path"))
+				if enabled {
+					require.True(t, events.IsSecurityError(err))
+					return
+				}
+				require.False(t, events.IsSecurityError(err))
+				w.WriteHeader(http.StatusNoContent)
+			})
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			res, err := srv.Client().Get(srv.URL + "?path=../../../etc/passwd")
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			spans := mt.FinishedSpans()
+			require.Len(t, spans, 1)
+			if enabled {
+				require.Equal(t, http.StatusForbidden, res.StatusCode)
+				require.Contains(t, spans[0].Tag("_dd.appsec.json"), "rasp-930-100")
+				require.Contains(t, spans[0].Tags(), "_dd.stack")
+			} else {
+				require.Equal(t, http.StatusNoContent, res.StatusCode)
+			}
+		})
+	}
+}
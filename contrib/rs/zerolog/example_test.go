@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package zerolog_test
+
+import (
+	"context"
+	"os"
+
+	"github.com/rs/zerolog"
+
+	zerologtrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/rs/zerolog"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+func ExampleHook() {
+	// start the DataDog tracer
+	tracer.Start()
+	defer tracer.Stop()
+
+	// create the application logger
+	logger := zerolog.New(os.Stdout).Hook(zerologtrace.Hook{})
+
+	// start a new span
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "ExampleHook")
+	defer span.Finish()
+
+	// log a message using the context containing span information
+	logger.Info().Ctx(ctx).Msg("this is a log with tracing information")
+}
+
+func ExampleContextLogger() {
+	// start the DataDog tracer
+	tracer.Start()
+	defer tracer.Stop()
+
+	// start a new span
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "ExampleContextLogger")
+	defer span.Finish()
+
+	// create a logger carrying the span information
+	logger := zerologtrace.ContextLogger(ctx, zerolog.New(os.Stdout))
+	logger.Info().Msg("this is a log with tracing information")
+}
@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+// Package zerolog provides functions to correlate logs and traces using the rs/zerolog package (https://github.com/rs/zerolog).
+package zerolog // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/rs/zerolog"
+
+import (
+	"context"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/logtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
+
+	"github.com/rs/zerolog"
+)
+
+const componentName = "rs/zerolog"
+
+func init() {
+	telemetry.LoadIntegration(componentName)
+	tracer.MarkIntegrationImported("github.com/rs/zerolog")
+}
+
+// Hook attaches tracing information to events logged with a context, set with
+// (*zerolog.Event).Ctx or (zerolog.Context).Ctx, containing a span.
+type Hook struct{}
+
+var _ zerolog.Hook = Hook{}
+
+// Run implements zerolog.Hook.
+func (Hook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	f, ok := logtrace.FromContext(e.GetCtx())
+	if !ok {
+		return
+	}
+	if f.TraceID128 != "" {
+		e.Str(ext.LogKeyTraceID, f.TraceID128)
+	} else {
+		e.Uint64(ext.LogKeyTraceID, f.TraceID)
+	}
+	e.Uint64(ext.LogKeySpanID, f.SpanID)
+	if f.Service != "" {
+		e.Str(logtrace.KeyService, f.Service)
+	}
+	if f.Env != "" {
+		e.Str(logtrace.KeyEnv, f.Env)
+	}
+	if f.Version != "" {
+		e.Str(logtrace.KeyVersion, f.Version)
+	}
+}
+
+// ContextLogger returns a child of the given logger whose events carry the tracing
+// information of the span found in ctx. The logger is returned as is if there is
+// no span in ctx.
+func ContextLogger(ctx context.Context, l zerolog.Logger) zerolog.Logger {
+	f, ok := logtrace.FromContext(ctx)
+	if !ok {
+		return l
+	}
+	c := l.With()
+	if f.TraceID128 != "" {
+		c = c.Str(ext.LogKeyTraceID, f.TraceID128)
+	} else {
+		c = c.Uint64(ext.LogKeyTraceID, f.TraceID)
+	}
+	c = c.Uint64(ext.LogKeySpanID, f.SpanID)
+	if f.Service != "" {
+		c = c.Str(logtrace.KeyService, f.Service)
+	}
+	if f.Env != "" {
+		c = c.Str(logtrace.KeyEnv, f.Env)
+	}
+	if f.Version != "" {
+		c = c.Str(logtrace.KeyVersion, f.Version)
+	}
+	return c.Logger()
+}
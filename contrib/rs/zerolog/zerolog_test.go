@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package zerolog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	internallog "gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+func decodeLogs(t *testing.T, b *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var logs []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimRight(b.String(), "\n"), "\n") {
+		var data map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &data))
+		logs = append(logs, data)
+	}
+	return logs
+}
+
+func TestHook(t *testing.T) {
+	t.Setenv("DD_ENV", "test-env")
+	t.Setenv("DD_VERSION", "1.2.3")
+	tracer.Start(tracer.WithLogger(internallog.DiscardLogger{}), tracer.WithService("test-service"))
+	defer tracer.Stop()
+
+	var b bytes.Buffer
+	logger := zerolog.New(&b).Hook(Hook{})
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "test")
+	defer span.Finish()
+
+	logger.Info().Ctx(ctx).Msg("with context")
+	child := logger.With().Ctx(ctx).Logger()
+	child.Error().Msg("with child logger")
+	logger.Info().Msg("without context")
+
+	logs := decodeLogs(t, &b)
+	require.Len(t, logs, 3)
+	for _, data := range logs[:2] {
+		assert.Equal(t, float64(span.Context().TraceID()), data[ext.LogKeyTraceID])
+		assert.Equal(t, float64(span.Context().SpanID()), data[ext.LogKeySpanID])
+		assert.Equal(t, "test-service", data["dd.service"])
+		assert.Equal(t, "test-env", data["dd.env"])
+		assert.Equal(t, "1.2.3", data["dd.version"])
+	}
+	assert.NotContains(t, logs[2], ext.LogKeyTraceID)
+}
+
+func TestContextLogger(t *testing.T) {
+	tracer.Start(tracer.WithLogger(internallog.DiscardLogger{}))
+	defer tracer.Stop()
+
+	var b bytes.Buffer
+	logger := zerolog.New(&b)
+	assert.Equal(t, logger, ContextLogger(context.Background(), logger))
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "test")
+	defer span.Finish()
+
+	t.Run("64-bit", func(t *testing.T) {
+		b.Reset()
+		l := ContextLogger(ctx, logger)
+		l.Info().Msg("message")
+
+		logs := decodeLogs(t, &b)
+		require.Len(t, logs, 1)
+		assert.Equal(t, float64(span.Context().TraceID()), logs[0][ext.LogKeyTraceID])
+		assert.Equal(t, float64(span.Context().SpanID()), logs[0][ext.LogKeySpanID])
+	})
+
+	t.Run("128-bit", func(t *testing.T) {
+		t.Setenv("DD_TRACE_128_BIT_TRACEID_LOGGING_ENABLED", "true")
+		b.Reset()
+		l := ContextLogger(ctx, logger)
+		l.Info().Msg("message")
+
+		logs := decodeLogs(t, &b)
+		require.Len(t, logs, 1)
+		traceID, ok := logs[0][ext.LogKeyTraceID].(string)
+		require.True(t, ok)
+		assert.Len(t, traceID, 32)
+	})
+}
@@ -16,6 +16,7 @@ import (
 	"context"
 	"math"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/datastreams"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -56,23 +57,26 @@ func (tr *Tracer) StartConsumeSpan(ctx context.Context, msg Message) ddtrace.Spa
 	if err := tracer.Inject(span.Context(), carrier); err != nil {
 		log.Debug("contrib/segmentio/kafka.go.v0: Failed to inject span context into carrier in reader, %v", err)
 	}
+	if tr.dataStreamsEnabled {
+		datastreams.SetSchemaTags(span, ext.SchemaOperationDeserialization, msg.GetTopic(), msg.GetValue())
+	}
 	return span
 }
 
 func (tr *Tracer) StartProduceSpan(ctx context.Context, writer Writer, msg Message, spanOpts ...tracer.StartSpanOption) ddtrace.Span {
+	topic := writer.GetTopic()
+	if topic == "" {
+		topic = msg.GetTopic()
+	}
 	opts := []tracer.StartSpanOption{
 		tracer.ServiceName(tr.producerServiceName),
+		tracer.ResourceName("Produce Topic " + topic),
 		tracer.SpanType(ext.SpanTypeMessageProducer),
 		tracer.Tag(ext.Component, componentName),
 		tracer.Tag(ext.SpanKind, ext.SpanKindProducer),
 		tracer.Tag(ext.MessagingSystem, ext.MessagingSystemKafka),
 		tracer.Tag(ext.KafkaBootstrapServers, tr.kafkaCfg.BootstrapServers),
 	}
-	if writer.GetTopic() != "" {
-		opts = append(opts, tracer.ResourceName("Produce Topic "+writer.GetTopic()))
-	} else {
-		opts = append(opts, tracer.ResourceName("Produce Topic "+msg.GetTopic()))
-	}
 	if !math.IsNaN(tr.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, tr.analyticsRate))
 	}
@@ -82,6 +86,9 @@ func (tr *Tracer) StartProduceSpan(ctx context.Context, writer Writer, msg Messa
 	if err := tracer.Inject(span.Context(), carrier); err != nil {
 		log.Debug("contrib/segmentio/kafka.go.v0: Failed to inject span context into carrier in writer, %v", err)
 	}
+	if tr.dataStreamsEnabled {
+		datastreams.SetSchemaTags(span, ext.SchemaOperationSerialization, topic, msg.GetValue())
+	}
 	return span
 }
 
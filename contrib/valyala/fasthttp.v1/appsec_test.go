@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package fasthttp
+
+import (
+	"net"
+	"testing"
+
+	pappsec "gopkg.in/DataDog/dd-trace-go.v1/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// serveAppSecRequest serves a request built by setup with the given handler and returns its context.
+func serveAppSecRequest(h fasthttp.RequestHandler, setup func(req *fasthttp.Request)) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.Header.SetMethod("POST")
+	req.SetRequestURI("/")
+	setup(&req)
+	var fctx fasthttp.RequestCtx
+	fctx.Init(&req, &net.TCPAddr{IP: net.IPv4(1, 2, 3, 5), Port: 1234}, nil)
+	h(&fctx)
+	return &fctx
+}
+
+func TestAppSec(t *testing.T) {
+	appsec.Start()
+	defer appsec.Stop()
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	h := WrapHandler(func(fctx *fasthttp.RequestCtx) {
+		fctx.SetBodyString("Hello World!\n")
+	})
+
+	for _, tc := range []struct {
+		name  string
+		setup func(req *fasthttp.Request)
+		rule  string
+	}{
+		{
+			// Send an LFI attack (according to appsec rule id crs-930-110)
+			name:  "request-uri",
+			setup: func(req *fasthttp.Request) { req.SetRequestURI("/../../../secret.txt") },
+			rule:  "crs-930-110",
+		},
+		{
+			// Send the canary user agent detected as an attack attempt
+			name:  "headers",
+			setup: func(req *fasthttp.Request) { req.Header.SetUserAgent("dd-test-scanner-log") },
+			rule:  "ua0-600-55x",
+		},
+		{
+			// Send a PHP injection attack via the query string (according to appsec rule id crs-933-130)
+			name:  "query",
+			setup: func(req *fasthttp.Request) { req.SetRequestURI("/?x=$globals") },
+			rule:  "crs-933-130",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			fctx := serveAppSecRequest(h, tc.setup)
+			require.Equal(t, "Hello World!\n", string(fctx.Response.Body()))
+
+			finished := mt.FinishedSpans()
+			require.Len(t, finished, 1)
+			event, _ := finished[0].Tag("_dd.appsec.json").(string)
+			require.Contains(t, event, tc.rule)
+		})
+	}
+}
+
+// Test that IP and user blocking work by using custom rules/rules data
+func TestBlocking(t *testing.T) {
+	t.Setenv("DD_APPSEC_RULES", "../../../internal/appsec/testdata/blocking.json")
+	appsec.Start()
+	defer appsec.Stop()
+	if !appsec.Enabled() {
+		t.Skip("AppSec needs to be enabled for this test")
+	}
+
+	h := WrapHandler(func(fctx *fasthttp.RequestCtx) {
+		if err := pappsec.SetUser(fctx, string(fctx.Request.Header.Peek("user-id"))); err != nil {
+			return
+		}
+		fctx.SetBodyString("Hello World!\n")
+	})
+
+	for _, tc := range []struct {
+		name    string
+		headers map[string]string
+		blocked bool
+	}{
+		{
+			name:    "ip-block",
+			headers: map[string]string{"x-forwarded-for": "1.2.3.4", "user-id": "legit-user-1"},
+			blocked: true,
+		},
+		{
+			name:    "user-block",
+			headers: map[string]string{"user-id": "blocked-user-1"},
+			blocked: true,
+		},
+		{
+			name:    "no-block",
+			headers: map[string]string{"user-id": "legit-user-1"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			fctx := serveAppSecRequest(h, func(req *fasthttp.Request) {
+				for k, v := range tc.headers {
+					req.Header.Set(k, v)
+				}
+			})
+
+			finished := mt.FinishedSpans()
+			require.Len(t, finished, 1)
+			if !tc.blocked {
+				require.Equal(t, fasthttp.StatusOK, fctx.Response.StatusCode())
+				require.Equal(t, "Hello World!\n", string(fctx.Response.Body()))
+				require.Nil(t, finished[0].Tag("appsec.blocked"))
+				return
+			}
+			require.Equal(t, fasthttp.StatusForbidden, fctx.Response.StatusCode())
+			require.NotContains(t, string(fctx.Response.Body()), "Hello World!\n")
+			require.Equal(t, true, finished[0].Tag("appsec.blocked"))
+			require.Equal(t, "403", finished[0].Tag("http.status_code"))
+		})
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
 )
@@ -50,7 +51,14 @@ func WrapHandler(h fasthttp.RequestHandler, opts ...Option) fasthttp.RequestHand
 		}
 		span := fasthttptrace.StartSpanFromContext(fctx, "http.request", spanOpts...)
 		defer span.Finish()
-		h(fctx)
+		afterHandle, handled := func(map[string]string) {}, false
+		if appsec.Enabled() {
+			_, afterHandle, handled = fasthttptrace.BeforeHandle(fctx, fctx, span, nil)
+		}
+		if !handled {
+			h(fctx)
+		}
+		afterHandle(nil)
 		span.SetTag(ext.ResourceName, cfg.resourceNamer(fctx))
 		status := fctx.Response.StatusCode()
 		if cfg.isStatusError(status) {
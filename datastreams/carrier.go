@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package datastreams
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
+)
+
+// HTTPHeadersCarrier wraps an http.Header as a TextMapWriter and
+// TextMapReader, to propagate the pathway in the headers of HTTP requests,
+// e.g. webhooks:
+//
+//	ctx = datastreams.SetProduceCheckpoint(ctx, "webhook", url, datastreams.HTTPHeadersCarrier(req.Header))
+//
+// and on the receiving side:
+//
+//	ctx = datastreams.SetConsumeCheckpoint(r.Context(), "webhook", url, datastreams.HTTPHeadersCarrier(r.Header))
+type HTTPHeadersCarrier http.Header
+
+var _ TextMapWriter = (*HTTPHeadersCarrier)(nil)
+var _ TextMapReader = (*HTTPHeadersCarrier)(nil)
+
+// Set implements TextMapWriter.
+func (c HTTPHeadersCarrier) Set(key, val string) {
+	http.Header(c).Set(key, val)
+}
+
+// ForeachKey implements TextMapReader.
+func (c HTTPHeadersCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, vals := range c {
+		for _, v := range vals {
+			if err := handler(k, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// BinaryCarrier carries the pathway in binary form, for transports
+// propagating a byte slice rather than key/value headers, e.g. a message
+// attribute, a Redis stream field or the header of a dropped file. It is a
+// TextMapWriter and a TextMapReader, so it can be used with
+// SetProduceCheckpoint and SetConsumeCheckpoint:
+//
+//	var c datastreams.BinaryCarrier
+//	ctx = datastreams.SetProduceCheckpoint(ctx, "file", dir, &c)
+//	// store c.Data along with the file
+//
+// and on the receiving side:
+//
+//	ctx = datastreams.SetConsumeCheckpoint(ctx, "file", dir, &datastreams.BinaryCarrier{Data: data})
+type BinaryCarrier struct {
+	// Data is the encoded pathway, empty if there is none.
+	Data []byte
+}
+
+var _ TextMapWriter = (*BinaryCarrier)(nil)
+var _ TextMapReader = (*BinaryCarrier)(nil)
+
+// Set implements TextMapWriter. Only the pathway is stored.
+func (c *BinaryCarrier) Set(key, val string) {
+	if key != datastreams.PropagationKeyBase64 {
+		return
+	}
+	if data, err := base64.StdEncoding.DecodeString(val); err == nil {
+		c.Data = data
+	}
+}
+
+// ForeachKey implements TextMapReader.
+func (c *BinaryCarrier) ForeachKey(handler func(key, val string) error) error {
+	if len(c.Data) == 0 {
+		return nil
+	}
+	return handler(datastreams.PropagationKeyBase64, base64.StdEncoding.EncodeToString(c.Data))
+}
@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package datastreams
+
+import (
+	"context"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/datastreams/options"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// CheckpointOption configures a checkpoint set with SetProduceCheckpoint or
+// SetConsumeCheckpoint.
+type CheckpointOption func(*checkpointConfig)
+
+type checkpointConfig struct {
+	payloadSize   int64
+	group         string
+	transactionID string
+}
+
+// WithPayloadSize sets the size in bytes of the produced or consumed
+// message, including its headers, to track the throughput of the pathway.
+func WithPayloadSize(size int64) CheckpointOption {
+	return func(cfg *checkpointConfig) {
+		cfg.payloadSize = size
+	}
+}
+
+// WithGroup sets the consumer group of a consume checkpoint, when several
+// consumers share the messages of the source.
+func WithGroup(group string) CheckpointOption {
+	return func(cfg *checkpointConfig) {
+		cfg.group = group
+	}
+}
+
+// WithTransactionID tracks the transaction with the given ID, e.g. the ID of
+// an order, at the checkpoint, as with TrackTransaction. The checkpoint is
+// named after its direction and its target or source, e.g. "produce:orders".
+func WithTransactionID(id string) CheckpointOption {
+	return func(cfg *checkpointConfig) {
+		cfg.transactionID = id
+	}
+}
+
+// SetProduceCheckpoint sets a produce checkpoint for a message sent to target,
+// e.g. a queue, a stream or a URL, through a transport of the given type,
+// e.g. "redis" or "webhook". The pathway of ctx, if any, is the upstream path
+// of the message. The resulting pathway is injected into carrier, which must
+// be sent along with the message, and is returned in a new context.
+//
+// The checkpoint is only set if the tracer is started with Data Streams
+// Monitoring enabled; ctx is returned as is otherwise.
+func SetProduceCheckpoint(ctx context.Context, typ, target string, carrier TextMapWriter, opts ...CheckpointOption) context.Context {
+	var cfg checkpointConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.transactionID != "" {
+		TrackTransaction(cfg.transactionID, "produce:"+target)
+	}
+	edges := []string{"direction:out", "manual_checkpoint:true", "topic:" + target, "type:" + typ}
+	ctx, ok := tracer.SetDataStreamsCheckpointWithParams(ctx, options.CheckpointParams{PayloadSize: cfg.payloadSize}, edges...)
+	if !ok {
+		return ctx
+	}
+	InjectToBase64Carrier(ctx, carrier)
+	return ctx
+}
+
+// SetConsumeCheckpoint sets a consume checkpoint for a message received from
+// source through a transport of the given type, matching the produce
+// checkpoint set with SetProduceCheckpoint. The pathway is extracted from
+// carrier, which was sent along with the message, and the resulting pathway
+// is returned in a new context, to be used for the checkpoints of the
+// processing of the message.
+//
+// The checkpoint is only set if the tracer is started with Data Streams
+// Monitoring enabled; the context returned then only holds the extracted
+// pathway.
+func SetConsumeCheckpoint(ctx context.Context, typ, source string, carrier TextMapReader, opts ...CheckpointOption) context.Context {
+	var cfg checkpointConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.transactionID != "" {
+		TrackTransaction(cfg.transactionID, "consume:"+source)
+	}
+	edges := []string{"direction:in", "manual_checkpoint:true", "topic:" + source, "type:" + typ}
+	if cfg.group != "" {
+		edges = append(edges, "group:"+cfg.group)
+	}
+	ctx, _ = tracer.SetDataStreamsCheckpointWithParams(ExtractFromBase64Carrier(ctx, carrier), options.CheckpointParams{PayloadSize: cfg.payloadSize}, edges...)
+	return ctx
+}
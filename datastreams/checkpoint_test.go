@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package datastreams
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type textMapCarrier interface {
+	TextMapWriter
+	TextMapReader
+}
+
+func TestCheckpoints(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	for name, newCarrier := range map[string]func() textMapCarrier{
+		"text-map": func() textMapCarrier {
+			return make(carrier)
+		},
+		"http-headers": func() textMapCarrier {
+			return HTTPHeadersCarrier(make(http.Header))
+		},
+		"binary": func() textMapCarrier {
+			return &BinaryCarrier{}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := newCarrier()
+			produceCtx := SetProduceCheckpoint(context.Background(), "redis", "orders", c, WithPayloadSize(42))
+			produced, ok := datastreams.PathwayFromContext(produceCtx)
+			require.True(t, ok)
+			// Same as a checkpoint with hand-assembled edge tags
+			expected, _ := tracer.SetDataStreamsCheckpoint(context.Background(), "direction:out", "manual_checkpoint:true", "topic:orders", "type:redis")
+			expectedPathway, _ := datastreams.PathwayFromContext(expected)
+			assert.Equal(t, expectedPathway.GetHash(), produced.GetHash())
+
+			extracted, ok := datastreams.PathwayFromContext(ExtractFromBase64Carrier(context.Background(), c))
+			require.True(t, ok)
+			assert.Equal(t, produced.GetHash(), extracted.GetHash())
+
+			consumeCtx := SetConsumeCheckpoint(context.Background(), "redis", "orders", c, WithGroup("billing"))
+			consumed, ok := datastreams.PathwayFromContext(consumeCtx)
+			require.True(t, ok)
+			assert.NotEqual(t, produced.GetHash(), consumed.GetHash())
+			// The consumed pathway is downstream of the produced one
+			expected, _ = tracer.SetDataStreamsCheckpoint(ExtractFromBase64Carrier(context.Background(), c), "direction:in", "group:billing", "manual_checkpoint:true", "topic:orders", "type:redis")
+			expectedPathway, _ = datastreams.PathwayFromContext(expected)
+			assert.Equal(t, expectedPathway.GetHash(), consumed.GetHash())
+		})
+	}
+}
+
+func TestCheckpointsDisabled(t *testing.T) {
+	c := make(carrier)
+	ctx := SetProduceCheckpoint(context.Background(), "redis", "orders", c)
+	_, ok := datastreams.PathwayFromContext(ctx)
+	assert.False(t, ok)
+	assert.Empty(t, c)
+}
+
+func TestBinaryCarrier(t *testing.T) {
+	var c BinaryCarrier
+	c.Set("other", "value")
+	assert.Empty(t, c.Data)
+	c.Set(datastreams.PropagationKeyBase64, "invalid base64")
+	assert.Empty(t, c.Data)
+
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	ctx, _ := tracer.SetDataStreamsCheckpoint(context.Background(), "direction:out", "topic:orders", "type:file")
+	InjectToBase64Carrier(ctx, &c)
+	p, _ := datastreams.PathwayFromContext(ctx)
+	assert.Equal(t, p.Encode(), c.Data)
+}
@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package datastreams_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/datastreams"
+)
+
+// The pathway is propagated in the headers of webhook requests.
+func ExampleSetProduceCheckpoint_http() {
+	body := []byte(`{"order":42}`)
+	req, err := http.NewRequest("POST", "http://billing.internal/hooks/orders", bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	datastreams.SetProduceCheckpoint(context.Background(), "webhook", "orders", datastreams.HTTPHeadersCarrier(req.Header),
+		datastreams.WithPayloadSize(int64(len(body))))
+	http.DefaultClient.Do(req)
+}
+
+// The receiving side of the webhook sets the matching consume checkpoint.
+func ExampleSetConsumeCheckpoint_http() {
+	http.HandleFunc("/hooks/orders", func(w http.ResponseWriter, r *http.Request) {
+		ctx := datastreams.SetConsumeCheckpoint(r.Context(), "webhook", "orders", datastreams.HTTPHeadersCarrier(r.Header),
+			datastreams.WithPayloadSize(r.ContentLength))
+		// Use ctx for the checkpoints of the processing of the order.
+		_ = ctx
+	})
+}
+
+// The pathway is stored in binary form along with dropped files.
+func ExampleBinaryCarrier() {
+	var c datastreams.BinaryCarrier
+	datastreams.SetProduceCheckpoint(context.Background(), "file", "/var/spool/orders", &c)
+	os.WriteFile("/var/spool/orders/42.pathway", c.Data, 0644)
+
+	// On the consuming side
+	data, _ := os.ReadFile("/var/spool/orders/42.pathway")
+	ctx := datastreams.SetConsumeCheckpoint(context.Background(), "file", "/var/spool/orders", &datastreams.BinaryCarrier{Data: data})
+	_ = ctx
+}
+
+// A specific order can be followed across queues by tracking its ID at the
+// checkpoints it goes through.
+func ExampleTrackTransaction() {
+	orderID := "order-42"
+	headers := make(http.Header)
+	datastreams.SetProduceCheckpoint(context.Background(), "webhook", "orders", datastreams.HTTPHeadersCarrier(headers),
+		datastreams.WithTransactionID(orderID))
+	// Later on, once the order is processed.
+	datastreams.TrackTransaction(orderID, "order-shipped")
+}
@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package datastreams
+
+import (
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// RegisterProtobufSchema registers the schema of the protobuf messages of the
+// same type as msg as the schema of the payloads of topic. The instrumented
+// Kafka clients then report it for the messages produced to and consumed
+// from topic.
+func RegisterProtobufSchema(topic string, msg proto.Message) {
+	datastreams.RegisterTopicSchema(topic, datastreams.ProtobufSchema(msg.ProtoReflect().Descriptor()))
+}
+
+// RegisterAvroSchema registers the Avro schema with the given JSON definition
+// as the schema of the payloads of topic. The instrumented Kafka clients then
+// report it for the messages produced to and consumed from topic.
+func RegisterAvroSchema(topic, definition string) error {
+	s, err := datastreams.AvroSchema(definition)
+	if err != nil {
+		return err
+	}
+	datastreams.RegisterTopicSchema(topic, s)
+	return nil
+}
+
+// SetSchemaTags sets the schema tags on the span of a message of topic with
+// the given payload, if the schema of the messages of topic is sampled. The
+// operation is either ext.SchemaOperationSerialization, for produced messages,
+// or ext.SchemaOperationDeserialization, for consumed messages.
+//
+// The schema is the one registered with RegisterProtobufSchema or
+// RegisterAvroSchema, if any, or the schema inferred from the payload if it
+// is a JSON document. Schemas are sampled at most once every 30 seconds per
+// topic and operation, and only if the tracer is started with Data Streams
+// Monitoring enabled.
+func SetSchemaTags(span ddtrace.Span, operation, topic string, payload []byte) {
+	if weight := SampleSchema(operation, topic); weight > 0 {
+		SetSampledSchemaTags(span, operation, topic, payload, weight)
+	}
+}
+
+// SampleSchema returns the number of messages of topic a sample of their
+// schema for the given operation stands for, or 0 if the schema should not be
+// sampled now. Integrations which need to compute the payload of a message,
+// e.g. by encoding it, call it first and compute the payload only when the
+// returned weight is not zero, before calling SetSampledSchemaTags.
+func SampleSchema(operation, topic string) (weight int64) {
+	return tracer.SampleDataStreamsSchema(operation + ":" + topic)
+}
+
+// SetSampledSchemaTags sets the schema tags on the span of a message of topic
+// with the given payload, for a sample of the given weight returned by
+// SampleSchema.
+func SetSampledSchemaTags(span ddtrace.Span, operation, topic string, payload []byte, weight int64) {
+	s, ok := datastreams.PayloadSchema(topic, payload)
+	if !ok {
+		return
+	}
+	span.SetTag(ext.SchemaDefinition, s.Definition)
+	span.SetTag(ext.SchemaID, s.ID)
+	if s.Name != "" {
+		span.SetTag(ext.SchemaName, s.Name)
+	}
+	span.SetTag(ext.SchemaOperation, operation)
+	span.SetTag(ext.SchemaType, s.Type)
+	span.SetTag(ext.SchemaWeight, weight)
+}
@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package datastreams
+
+import (
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestSetSchemaTags(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	for i := 0; i < 3; i++ {
+		span := tracer.StartSpan("kafka.produce")
+		SetSchemaTags(span, ext.SchemaOperationSerialization, "schema-tags-json", []byte(`{"id": 42}`))
+		span.Finish()
+	}
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "json", spans[0].Tag(ext.SchemaType))
+	assert.Equal(t, ext.SchemaOperationSerialization, spans[0].Tag(ext.SchemaOperation))
+	assert.JSONEq(t, `{"type": "object", "properties": {"id": {"type": "integer"}}}`, spans[0].Tag(ext.SchemaDefinition).(string))
+	assert.NotEmpty(t, spans[0].Tag(ext.SchemaID))
+	assert.Equal(t, int64(1), spans[0].Tag(ext.SchemaWeight))
+	// Only one schema is sampled per interval.
+	assert.Nil(t, spans[1].Tag(ext.SchemaID))
+	assert.Nil(t, spans[2].Tag(ext.SchemaID))
+}
+
+func TestSampleSchema(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	var sampled int
+	for i := 0; i < 3; i++ {
+		span := tracer.StartSpan("kafka.produce")
+		if weight := SampleSchema(ext.SchemaOperationSerialization, "sample-schema"); weight > 0 {
+			sampled++
+			SetSampledSchemaTags(span, ext.SchemaOperationSerialization, "sample-schema", []byte(`{"id": 42}`), weight)
+		}
+		span.Finish()
+	}
+	assert.Equal(t, 1, sampled)
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "json", spans[0].Tag(ext.SchemaType))
+	assert.Equal(t, int64(1), spans[0].Tag(ext.SchemaWeight))
+}
+
+func TestRegisterSchema(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	RegisterProtobufSchema("schema-tags-protobuf", &structpb.Struct{})
+	require.NoError(t, RegisterAvroSchema("schema-tags-avro", `{"type": "record", "name": "Order", "fields": []}`))
+	assert.Error(t, RegisterAvroSchema("schema-tags-avro", `{"type":`))
+
+	span := tracer.StartSpan("kafka.consume")
+	SetSchemaTags(span, ext.SchemaOperationDeserialization, "schema-tags-protobuf", []byte{0x0a})
+	span.Finish()
+	span = tracer.StartSpan("kafka.consume")
+	SetSchemaTags(span, ext.SchemaOperationDeserialization, "schema-tags-avro", []byte{0x0a})
+	span.Finish()
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "protobuf", spans[0].Tag(ext.SchemaType))
+	assert.Equal(t, "google.protobuf.Struct", spans[0].Tag(ext.SchemaName))
+	assert.Equal(t, "avro", spans[1].Tag(ext.SchemaType))
+	assert.Equal(t, "Order", spans[1].Tag(ext.SchemaName))
+}
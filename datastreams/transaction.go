@@ -0,0 +1,22 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package datastreams
+
+import (
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// TrackTransaction records that the transaction with the given ID, e.g. the
+// ID of an order, went through the checkpoint with the given name, e.g.
+// "order-validated". This allows following a specific message across the
+// queues and services of a pathway.
+//
+// IDs and checkpoint names longer than 255 bytes are truncated, and only the
+// first 255 distinct checkpoint names are tracked. The transaction is only
+// tracked if the tracer is started with Data Streams Monitoring enabled.
+func TrackTransaction(transactionID, checkpointName string) {
+	tracer.TrackDataStreamsTransaction(transactionID, checkpointName)
+}
@@ -14,6 +14,7 @@ const (
 const (
 	MessagingSystemGCPPubsub = "googlepubsub"
 	MessagingSystemKafka     = "kafka"
+	MessagingSystemNATS      = "nats"
 )
 
 // Kafka tags.
@@ -23,3 +24,25 @@ const (
 	// KafkaBootstrapServers holds a comma separated list of bootstrap servers as defined in producer or consumer config.
 	KafkaBootstrapServers = "messaging.kafka.bootstrap.servers"
 )
+
+// Data Streams Monitoring schema tags, set on the spans of sampled messages.
+const (
+	// SchemaDefinition holds the definition of the schema of the message.
+	SchemaDefinition = "schema.definition"
+	// SchemaID holds the fingerprint of the definition of the schema.
+	SchemaID = "schema.id"
+	// SchemaName holds the fully qualified name of the schema.
+	SchemaName = "schema.name"
+	// SchemaOperation is either SchemaOperationSerialization or SchemaOperationDeserialization.
+	SchemaOperation = "schema.operation"
+	// SchemaType holds the type of the schema: protobuf, avro or json.
+	SchemaType = "schema.type"
+	// SchemaWeight holds the number of messages the sampled schema stands for.
+	SchemaWeight = "schema.weight"
+)
+
+// Available values for schema.operation.
+const (
+	SchemaOperationSerialization   = "serialization"
+	SchemaOperationDeserialization = "deserialization"
+)
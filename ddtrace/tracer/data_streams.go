@@ -72,3 +72,26 @@ func TrackKafkaHighWatermarkOffset(cluster string, topic string, partition int32
 		}
 	}
 }
+
+// TrackDataStreamsTransaction records that the transaction with the given ID,
+// e.g. the ID of an order, went through the checkpoint with the given name.
+// This allows following a specific message across queues and services.
+func TrackDataStreamsTransaction(transactionID, checkpointName string) {
+	if t, ok := internal.GetGlobalTracer().(dataStreamsContainer); ok {
+		if p := t.GetDataStreamsProcessor(); p != nil {
+			p.TrackTransaction(transactionID, checkpointName)
+		}
+	}
+}
+
+// SampleDataStreamsSchema returns the number of messages a sample of the schema
+// of the messages identified by key stands for, if the schema should be computed
+// and reported now, or 0 otherwise.
+func SampleDataStreamsSchema(key string) (weight int64) {
+	if t, ok := internal.GetGlobalTracer().(dataStreamsContainer); ok {
+		if p := t.GetDataStreamsProcessor(); p != nil {
+			return p.TrySampleSchema(key)
+		}
+	}
+	return 0
+}
@@ -82,10 +82,12 @@ var contribIntegrations = map[string]struct {
 	"github.com/labstack/echo":                      {"echo", false},
 	"github.com/labstack/echo/v4":                   {"echo v4", false},
 	"github.com/miekg/dns":                          {"miekg/dns", false},
+	"github.com/nats-io/nats.go":                    {"NATS", false},
 	"net/http":                                      {"HTTP", false},
 	"gopkg.in/olivere/elastic.v5":                   {"Elasticsearch v5", false},
 	"gopkg.in/olivere/elastic.v3":                   {"Elasticsearch v3", false},
 	"github.com/redis/go-redis/v9":                  {"Redis v9", false},
+	"github.com/rs/zerolog":                         {"zerolog", false},
 	"github.com/segmentio/kafka-go":                 {"Kafka v0", false},
 	"github.com/IBM/sarama":                         {"IBM sarama", false},
 	"github.com/Shopify/sarama":                     {"Shopify sarama", false},
@@ -97,6 +99,9 @@ var contribIntegrations = map[string]struct {
 	"github.com/valyala/fasthttp":                   {"FastHTTP", false},
 	"github.com/zenazn/goji":                        {"Goji", false},
 	"log/slog":                                      {"log/slog", false},
+	"os":                                            {"os", false},
+	"os/exec":                                       {"os/exec", false},
+	"go.uber.org/zap":                               {"zap", false},
 	"github.com/uptrace/bun":                        {"Bun", false},
 }
 
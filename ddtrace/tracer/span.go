@@ -87,6 +87,9 @@ type span struct {
 
 	pprofCtxActive  context.Context `msg:"-"` // contains pprof.WithLabel labels to tell the profiler more about this span
 	pprofCtxRestore context.Context `msg:"-"` // contains pprof.WithLabel labels of the parent span (if any) that need to be restored when this span finishes
+	pprofParent     *span           `msg:"-"` // local parent span, whose pprof labels are replaced by the ones of this span once applied
+	pprofChildren   int             `msg:"-"` // number of unfinished child spans whose pprof labels replaced the ones of this span
+	pprofPending    bool            `msg:"-"` // pprof labels were updated while pprofChildren > 0, and are applied by the last of them
 
 	taskEnd func() // ends execution tracer (runtime/trace) task, if started
 }
@@ -103,6 +106,53 @@ func (s *span) SetBaggageItem(key, val string) {
 	s.context.setBaggageItem(key, val)
 }
 
+// setPPROFLabel sets the pprof label key to value for s. The labels of the
+// goroutine are only updated when s is its innermost span: while child spans
+// applied their own labels, they are updated once the last of them finishes.
+// It must be called with s locked.
+func (s *span) setPPROFLabel(key, value string) {
+	prev := s.pprofCtxActive
+	s.pprofCtxActive = pprof.WithLabels(prev, pprof.Labels(key, value))
+	if s.pprofChildren > 0 {
+		s.pprofPending = true
+		return
+	}
+	if s.pprofCtxRestore == nil {
+		// These are the first labels applied for s
+		s.pprofCtxRestore = prev
+		s.pprofLabelsApplied()
+	}
+	pprof.SetGoroutineLabels(s.pprofCtxActive)
+}
+
+// pprofLabelsApplied records that the pprof labels of s replaced the ones of
+// its parent span. It must be called with s locked.
+func (s *span) pprofLabelsApplied() {
+	if p := s.pprofParent; p != nil {
+		p.Lock()
+		p.pprofChildren++
+		p.Unlock()
+	}
+}
+
+// pprofLabelsRestored returns the pprof labels to restore once s finished,
+// which are the latest labels of its parent span if they were updated while s
+// and its siblings were running. It must be called with s locked.
+func (s *span) pprofLabelsRestored() context.Context {
+	p := s.pprofParent
+	if p == nil {
+		return s.pprofCtxRestore
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.pprofChildren--
+	if p.pprofChildren == 0 && p.pprofPending {
+		p.pprofPending = false
+		return p.pprofCtxActive
+	}
+	return s.pprofCtxRestore
+}
+
 // BaggageItem gets the value for a baggage item given its key. Returns the
 // empty string if the value isn't found in this Span.
 func (s *span) BaggageItem(key string) string {
@@ -141,8 +191,10 @@ func (s *span) SetTag(key string, value interface{}) {
 			// We don't change s.pprofCtxRestore since that should
 			// stay as the original parent span context regardless
 			// of what we change at a lower level.
-			s.pprofCtxActive = pprof.WithLabels(s.pprofCtxActive, pprof.Labels(traceprof.TraceEndpoint, v))
-			pprof.SetGoroutineLabels(s.pprofCtxActive)
+			s.setPPROFLabel(traceprof.TraceEndpoint, v)
+		} else if stl := traceprof.GetSpanTagLabels(); s.pprofCtxActive != nil && stl != nil && stl.Has(key) {
+			// Update the label of the span tag for the runtime profilers.
+			s.setPPROFLabel(key, stl.Value(key, v))
 		}
 		s.setMeta(key, v)
 		return
@@ -397,6 +449,9 @@ func (s *span) setMeta(key, v string) {
 		s.Service = v
 	case ext.ResourceName:
 		s.Resource = v
+		if o := traceprof.GetSpanObserver(); o != nil {
+			o.SpanResourceChanged(s.profilerInfo())
+		}
 	case ext.SpanType:
 		s.Type = v
 	default:
@@ -586,10 +641,27 @@ func (s *span) finish(finishTime int64) {
 	}
 	s.context.finish()
 
+	if o := traceprof.GetSpanObserver(); o != nil {
+		o.SpanFinished(s.profilerInfo())
+	}
 	if s.pprofCtxRestore != nil {
 		// Restore the labels of the parent span so any CPU samples after this
 		// point are attributed correctly.
-		pprof.SetGoroutineLabels(s.pprofCtxRestore)
+		pprof.SetGoroutineLabels(s.pprofLabelsRestored())
+	}
+}
+
+// profilerInfo returns the description of the span for a
+// traceprof.SpanObserver.
+func (s *span) profilerInfo() traceprof.SpanInfo {
+	return traceprof.SpanInfo{
+		SpanID:   s.SpanID,
+		TraceID:  s.TraceID,
+		Service:  s.Service,
+		Resource: s.Resource,
+		Start:    time.Unix(0, s.Start),
+		Duration: time.Duration(s.Duration),
+		Error:    s.Error != 0,
 	}
 }
 
@@ -6,9 +6,11 @@
 package tracer
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"runtime"
+	"runtime/pprof"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -598,6 +600,146 @@ func TestSpanProfilingTags(t *testing.T) {
 	}
 }
 
+func TestSpanTagProfilerLabels(t *testing.T) {
+	tracer := newTracer(withTransport(newDefaultTransport()), WithProfilerCodeHotspots(false), WithProfilerEndpoints(false))
+	defer tracer.Stop()
+	traceprof.SetSpanTagLabels([]string{"tenant", "tier"}, 1)
+	defer traceprof.SetSpanTagLabels(nil, 0)
+
+	label := func(s *span, key string) string {
+		v, _ := pprof.Label(s.pprofCtxActive, key)
+		return v
+	}
+	root := tracer.StartSpan("web.request", Tag("tenant", "a")).(*span)
+	defer root.Finish()
+	require.NotNil(t, root.pprofCtxActive)
+	assert.Equal(t, "a", label(root, "tenant"))
+	assert.Empty(t, label(root, "tier"))
+
+	root.SetTag("tier", "gold")
+	root.SetTag("component", "tracer")
+	assert.Equal(t, "gold", label(root, "tier"))
+	assert.Empty(t, label(root, "component"))
+
+	// Values beyond the cardinality limit are replaced
+	other := tracer.StartSpan("web.request", Tag("tenant", "b")).(*span)
+	defer other.Finish()
+	assert.Equal(t, traceprof.SpanTagLabelOverflow, label(other, "tenant"))
+}
+
+func TestSpanTagProfilerLabelsNested(t *testing.T) {
+	tracer := newTracer(withTransport(newDefaultTransport()), WithProfilerCodeHotspots(true), WithProfilerEndpoints(false))
+	defer tracer.Stop()
+	traceprof.SetSpanTagLabels([]string{"tier"}, 10)
+	defer traceprof.SetSpanTagLabels(nil, 0)
+
+	root := tracer.StartSpan("web.request").(*span)
+	defer root.Finish()
+	child := tracer.StartSpan("db.query", ChildOf(root.Context())).(*span)
+	childID := fmt.Sprintf(`"span id":"%d"`, child.SpanID)
+	require.Contains(t, currentGoroutineLabels(t), childID)
+
+	// The labels of the active child span are kept
+	root.SetTag("tier", "gold")
+	labels := currentGoroutineLabels(t)
+	assert.Contains(t, labels, childID)
+	assert.NotContains(t, labels, "tier")
+
+	// The labels of the parent span are restored with its new tag
+	child.Finish()
+	labels = currentGoroutineLabels(t)
+	assert.Contains(t, labels, fmt.Sprintf(`"span id":"%d"`, root.SpanID))
+	assert.Contains(t, labels, `"tier":"gold"`)
+
+	root.SetTag("tier", "silver")
+	assert.Contains(t, currentGoroutineLabels(t), `"tier":"silver"`)
+}
+
+func TestSpanTagProfilerLabelsNoTags(t *testing.T) {
+	tracer := newTracer(withTransport(newDefaultTransport()), WithProfilerCodeHotspots(false), WithProfilerEndpoints(false))
+	defer tracer.Stop()
+	traceprof.SetSpanTagLabels([]string{"tier"}, 10)
+	defer traceprof.SetSpanTagLabels(nil, 0)
+
+	// No labels are applied for spans without the configured tags
+	s := tracer.StartSpan("web.request").(*span)
+	defer s.Finish()
+	assert.Nil(t, s.pprofCtxRestore)
+	pprof.ForLabels(s.pprofCtxActive, func(key, value string) bool {
+		t.Errorf("unexpected label %s:%s", key, value)
+		return true
+	})
+
+	s.SetTag("tier", "gold")
+	assert.NotNil(t, s.pprofCtxRestore)
+	assert.Contains(t, currentGoroutineLabels(t), `"tier":"gold"`)
+}
+
+// currentGoroutineLabels returns the pprof labels of the calling goroutine, as
+// reported by the goroutine profile.
+func currentGoroutineLabels(t *testing.T) string {
+	var b bytes.Buffer
+	require.NoError(t, pprof.Lookup("goroutine").WriteTo(&b, 1))
+	for _, record := range strings.Split(b.String(), "\n\n") {
+		if !strings.Contains(record, "tracer.currentGoroutineLabels") {
+			continue
+		}
+		for _, line := range strings.Split(record, "\n") {
+			if labels, ok := strings.CutPrefix(line, "# labels: "); ok {
+				return labels
+			}
+		}
+		return ""
+	}
+	t.Fatal("goroutine not found in the goroutine profile")
+	return ""
+}
+
+type recordingSpanObserver struct {
+	started, resourceChanged, finished []traceprof.SpanInfo
+}
+
+func (o *recordingSpanObserver) SpanStarted(s traceprof.SpanInfo) {
+	o.started = append(o.started, s)
+}
+
+func (o *recordingSpanObserver) SpanResourceChanged(s traceprof.SpanInfo) {
+	o.resourceChanged = append(o.resourceChanged, s)
+}
+
+func (o *recordingSpanObserver) SpanFinished(s traceprof.SpanInfo) {
+	o.finished = append(o.finished, s)
+}
+
+func TestSpanObserver(t *testing.T) {
+	tracer := newTracer(withTransport(newDefaultTransport()))
+	internal.SetGlobalTracer(tracer)
+	defer tracer.Stop()
+	o := &recordingSpanObserver{}
+	traceprof.SetSpanObserver(o)
+	defer traceprof.SetSpanObserver(nil)
+
+	span := tracer.StartSpan("web.request", ServiceName("web"), ResourceName("GET /")).(*span)
+	require.Len(t, o.started, 1)
+	assert.Equal(t, span.SpanID, o.started[0].SpanID)
+	assert.Equal(t, span.TraceID, o.started[0].TraceID)
+	assert.Equal(t, "web", o.started[0].Service)
+	assert.Equal(t, "GET /", o.started[0].Resource)
+	assert.Zero(t, o.started[0].Duration)
+
+	span.SetTag(ext.ResourceName, "GET /users")
+	require.NotEmpty(t, o.resourceChanged)
+	last := o.resourceChanged[len(o.resourceChanged)-1]
+	assert.Equal(t, span.SpanID, last.SpanID)
+	assert.Equal(t, "GET /users", last.Resource)
+
+	span.Finish(WithError(errors.New("failed")))
+	require.Len(t, o.finished, 1)
+	assert.Equal(t, span.SpanID, o.finished[0].SpanID)
+	assert.Equal(t, time.Duration(span.Duration), o.finished[0].Duration)
+	assert.True(t, o.finished[0].Error)
+}
+
 func TestSpanError(t *testing.T) {
 	assert := assert.New(t)
 	tracer := newTracer(withTransport(newDefaultTransport()))
@@ -24,6 +24,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
 	appsecConfig "gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/config"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/hostname"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/remoteconfig"
@@ -157,6 +158,7 @@ func Start(opts ...StartOption) {
 		return
 	}
 	internal.SetGlobalTracer(t)
+	globalconfig.SetTracerEnvVersion(t.config.env, t.config.version)
 	if t.config.logStartup {
 		logStartup(t)
 	}
@@ -188,6 +190,7 @@ func Start(opts ...StartOption) {
 // Stop stops the started tracer. Subsequent calls are valid but become no-op.
 func Stop() {
 	internal.SetGlobalTracer(&internal.NoopTracer{})
+	globalconfig.ClearTracerEnvVersion()
 	log.Flush()
 }
 
@@ -613,7 +616,10 @@ func (t *tracer) StartSpan(operationName string, options ...ddtrace.StartSpanOpt
 		t.sample(span)
 	}
 	pprofContext, span.taskEnd = startExecutionTracerTask(pprofContext, span)
-	if t.config.profilerHotspots || t.config.profilerEndpoints {
+	if t.config.profilerHotspots || t.config.profilerEndpoints || traceprof.GetSpanTagLabels() != nil {
+		if context != nil {
+			span.pprofParent = context.span
+		}
 		t.applyPPROFLabels(pprofContext, span)
 	}
 	if t.config.serviceMappings != nil {
@@ -621,6 +627,9 @@ func (t *tracer) StartSpan(operationName string, options ...ddtrace.StartSpanOpt
 			span.Service = newSvc
 		}
 	}
+	if o := traceprof.GetSpanObserver(); o != nil {
+		o.SpanStarted(span.profilerInfo())
+	}
 	if log.DebugEnabled() {
 		// avoid allocating the ...interface{} argument if debug logging is disabled
 		log.Debug("Started Span: %v, Operation: %s, Resource: %s, Tags: %v, %v",
@@ -637,10 +646,10 @@ func (t *tracer) StartSpan(operationName string, options ...ddtrace.StartSpanOpt
 	return span
 }
 
-// applyPPROFLabels applies pprof labels for the profiler's code hotspots and
-// endpoint filtering feature to span. When span finishes, any pprof labels
-// found in ctx are restored. Additionally, this func informs the profiler how
-// many times each endpoint is called.
+// applyPPROFLabels applies pprof labels for the profiler's code hotspots,
+// endpoint filtering and span tag labels features to span. When span
+// finishes, any pprof labels found in ctx are restored. Additionally, this
+// func informs the profiler how many times each endpoint is called.
 func (t *tracer) applyPPROFLabels(ctx gocontext.Context, span *span) {
 	var labels []string
 	if t.config.profilerHotspots {
@@ -663,10 +672,24 @@ func (t *tracer) applyPPROFLabels(ctx gocontext.Context, span *span) {
 			}
 		}
 	}
+	stl := traceprof.GetSpanTagLabels()
+	if stl != nil {
+		// Span tags set later with SetTag are applied then, see span.SetTag
+		for _, k := range stl.Keys() {
+			if v, ok := span.Meta[k]; ok {
+				labels = append(labels, k, stl.Value(k, v))
+			}
+		}
+	}
 	if len(labels) > 0 {
 		span.pprofCtxRestore = ctx
 		span.pprofCtxActive = pprof.WithLabels(ctx, pprof.Labels(labels...))
+		span.pprofLabelsApplied()
 		pprof.SetGoroutineLabels(span.pprofCtxActive)
+	} else if stl != nil {
+		// No labels to apply yet, but span tags set later with SetTag may add
+		// some on top of ctx
+		span.pprofCtxActive = ctx
 	}
 }
 
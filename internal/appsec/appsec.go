@@ -138,6 +138,10 @@ type appsec struct {
 	features   []listener.Feature
 	featuresMu sync.Mutex
 	started    bool
+	// rulesMu serializes the security rules updates, received through remote config or from the
+	// local rules files.
+	rulesMu       sync.Mutex
+	rulesReloader *rulesReloader
 }
 
 func newAppSec(cfg *config.Config) *appsec {
@@ -160,6 +164,17 @@ func (a *appsec) start(telemetry *appsecTelemetry) error {
 		log.Error("appsec: non-critical error while loading libddwaf: %v", err)
 	}
 
+	// Apply the local rules files, if any, before registering the security protections
+	if len(localRulesFiles(a.cfg)) > 0 {
+		a.rulesMu.Lock()
+		err := a.loadLocalRules()
+		a.rulesMu.Unlock()
+		reportRulesUpdateTelemetry(err)
+		if err != nil {
+			log.Error("appsec: local rules: could not apply the security rules files: %v", err)
+		}
+	}
+
 	// Register dyngo listeners
 	if err := a.SwapRootOperation(); err != nil {
 		return err
@@ -167,6 +182,7 @@ func (a *appsec) start(telemetry *appsecTelemetry) error {
 
 	a.enableRCBlocking()
 	a.enableRASP()
+	a.startRulesReloader()
 
 	a.started = true
 	log.Info("appsec: up and running")
@@ -187,8 +203,10 @@ func (a *appsec) stop() {
 	defer telemetry.emit()
 
 	a.started = false
-	// Disable RC blocking first so that the following is guaranteed not to be concurrent anymore.
+	// Disable RC blocking and the local rules reloading first so that the following is guaranteed not
+	// to be concurrent anymore.
 	a.disableRCBlocking()
+	a.stopRulesReloader()
 
 	a.featuresMu.Lock()
 	defer a.featuresMu.Unlock()
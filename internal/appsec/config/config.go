@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	internal "github.com/DataDog/appsec-internal-go/appsec"
@@ -49,6 +50,16 @@ const (
 	EnvSCAEnabled = "DD_APPSEC_SCA_ENABLED"
 )
 
+// The following environment variables configure the local security rules files.
+const (
+	// EnvRulesEdits is a comma-separated list of paths to rules fragment files, holding custom rules,
+	// exclusions, actions, rules overrides or rules data, merged into the security rules.
+	EnvRulesEdits = "DD_APPSEC_RULES_EDITS"
+	// EnvRulesReloadInterval is the interval at which the local security rules files are checked for
+	// changes and reloaded. Hot reloading is disabled when not set.
+	EnvRulesReloadInterval = "DD_APPSEC_RULES_RELOAD_INTERVAL"
+)
+
 // StartOption is used to customize the AppSec configuration when invoked with appsec.Start()
 type StartOption func(c *Config)
 
@@ -70,6 +81,13 @@ type Config struct {
 	RASP bool
 	// SupportedAddresses are the addresses that the AppSec listener will bind to.
 	SupportedAddresses AddressSet
+	// RulesFile is the path of the rules file given with DD_APPSEC_RULES, empty if the builtin rules are used.
+	RulesFile string
+	// RulesEditFiles are the paths of the rules fragment files given with DD_APPSEC_RULES_EDITS.
+	RulesEditFiles []string
+	// RulesReloadInterval is the interval at which the local rules files are reloaded when they change.
+	// Zero if hot reloading is disabled.
+	RulesReloadInterval time.Duration
 }
 
 // AddressSet is a set of WAF addresses.
@@ -141,5 +159,35 @@ func NewConfig() (*Config, error) {
 		Obfuscator:     internal.NewObfuscatorConfig(),
 		APISec:         internal.NewAPISecConfig(),
 		RASP:           internal.RASPEnabled(),
+
+		RulesFile:           os.Getenv(internal.EnvRules),
+		RulesEditFiles:      rulesEditFilesFromEnv(),
+		RulesReloadInterval: rulesReloadIntervalFromEnv(),
 	}, nil
 }
+
+// rulesEditFilesFromEnv returns the paths of the rules fragment files given with DD_APPSEC_RULES_EDITS.
+func rulesEditFilesFromEnv() []string {
+	var files []string
+	for _, f := range strings.Split(os.Getenv(EnvRulesEdits), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// rulesReloadIntervalFromEnv returns the interval given with DD_APPSEC_RULES_RELOAD_INTERVAL, or zero
+// if it is not set or invalid.
+func rulesReloadIntervalFromEnv() time.Duration {
+	str := os.Getenv(EnvRulesReloadInterval)
+	if str == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil || d <= 0 {
+		log.Error("appsec: could not parse %s value `%s` as a positive duration: rules hot reloading is disabled", EnvRulesReloadInterval, str)
+		return 0
+	}
+	return d
+}
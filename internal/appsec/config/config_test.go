@@ -7,9 +7,12 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry/telemetrytest"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestSCAEnabled(t *testing.T) {
@@ -63,3 +66,26 @@ func TestSCAEnabled(t *testing.T) {
 		})
 	}
 }
+
+func TestLocalRulesFilesConfig(t *testing.T) {
+	t.Run("edits", func(t *testing.T) {
+		t.Setenv(EnvRulesEdits, " custom.json,, exclusions.json ")
+		require.Equal(t, []string{"custom.json", "exclusions.json"}, rulesEditFilesFromEnv())
+	})
+
+	for _, tc := range []struct {
+		name     string
+		env      string
+		expected time.Duration
+	}{
+		{name: "unset"},
+		{name: "valid", env: "10s", expected: 10 * time.Second},
+		{name: "negative", env: "-1s"},
+		{name: "invalid", env: "often"},
+	} {
+		t.Run("reload-interval/"+tc.name, func(t *testing.T) {
+			t.Setenv(EnvRulesReloadInterval, tc.env)
+			require.Equal(t, tc.expected, rulesReloadIntervalFromEnv())
+		})
+	}
+}
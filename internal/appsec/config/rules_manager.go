@@ -21,11 +21,13 @@ type (
 	// The `Base` fragment is the default rules (either local or received through ASM_DD),
 	// and the `Edits` fragments each represent a remote configuration update that affects the rules.
 	// `BasePath` is either empty if the local Base rules are used, or holds the path of the ASM_DD config.
+	// `LocalBase` holds the rules of the local rules file, if any, used as the base when no ASM_DD config is set.
 	RulesManager struct {
-		Latest   RulesFragment
-		Base     RulesFragment
-		BasePath string
-		Edits    map[string]RulesFragment
+		Latest    RulesFragment
+		Base      RulesFragment
+		BasePath  string
+		LocalBase RulesFragment
+		Edits     map[string]RulesFragment
 	}
 	// RulesFragment can represent a full ruleset or a fragment of it.
 	RulesFragment struct {
@@ -58,6 +60,8 @@ func DefaultRulesFragment() RulesFragment {
 func (f *RulesFragment) clone() (clone RulesFragment) {
 	clone.Version = f.Version
 	clone.Metadata = f.Metadata
+	clone.Rules = slices.Clone(f.Rules)
+	clone.Actions = slices.Clone(f.Actions)
 	clone.Overrides = slices.Clone(f.Overrides)
 	clone.Exclusions = slices.Clone(f.Exclusions)
 	clone.ExclusionData = slices.Clone(f.ExclusionData)
@@ -68,6 +72,31 @@ func (f *RulesFragment) clone() (clone RulesFragment) {
 	return
 }
 
+// RuleAddresses returns the addresses inspected by the conditions of the rules and custom rules of
+// the fragment. Unlike the addresses reported by the WAF, they exclude the addresses only read by
+// the processors, such as the API Security schemas extraction.
+func (f *RulesFragment) RuleAddresses() AddressSet {
+	set := AddressSet{}
+	for _, rules := range [][]any{f.Rules, f.CustomRules} {
+		for _, rule := range rules {
+			rule, _ := rule.(map[string]any)
+			conditions, _ := rule["conditions"].([]any)
+			for _, c := range conditions {
+				c, _ := c.(map[string]any)
+				params, _ := c["parameters"].(map[string]any)
+				inputs, _ := params["inputs"].([]any)
+				for _, in := range inputs {
+					in, _ := in.(map[string]any)
+					if addr, ok := in["address"].(string); ok {
+						set[addr] = struct{}{}
+					}
+				}
+			}
+		}
+	}
+	return set
+}
+
 // NewRulesManager initializes and returns a new RulesManager using the provided rules.
 // If no rules are provided (nil), the default rules are used instead.
 // If the provided rules are invalid, an error is returned
@@ -80,11 +109,16 @@ func NewRulesManager(rules []byte) (*RulesManager, error) {
 		log.Debug("appsec: cannot create RulesManager from specified rules")
 		return nil, err
 	}
-	return &RulesManager{
+	r := &RulesManager{
 		Latest: f,
 		Base:   f,
 		Edits:  map[string]RulesFragment{},
-	}, nil
+	}
+	if rules != nil {
+		r.LocalBase = f
+	}
+	r.Latest.addGRPCSchemaProcessor()
+	return r, nil
 }
 
 // Clone returns a duplicate of the current rules manager object
@@ -95,6 +129,7 @@ func (r *RulesManager) Clone() (clone RulesManager) {
 	}
 	clone.BasePath = r.BasePath
 	clone.Base = r.Base.clone()
+	clone.LocalBase = r.LocalBase.clone()
 	clone.Latest = r.Latest.clone()
 	return
 }
@@ -115,6 +150,24 @@ func (r *RulesManager) ChangeBase(f RulesFragment, basePath string) {
 	r.BasePath = basePath
 }
 
+// ChangeLocalBase sets the rules of the local rules file. They become the base rules unless an ASM_DD
+// config is currently used, in which case they are only used once the ASM_DD config is removed.
+func (r *RulesManager) ChangeLocalBase(f RulesFragment) {
+	r.LocalBase = f
+	if r.BasePath == "" {
+		r.Base = f
+	}
+}
+
+// RevertBase switches back to the local base rules: the local rules file if any, the default rules otherwise.
+func (r *RulesManager) RevertBase() {
+	base := r.LocalBase
+	if len(base.Rules) == 0 {
+		base = DefaultRulesFragment()
+	}
+	r.ChangeBase(base, "")
+}
+
 // Compile compiles the RulesManager fragments together stores the result in r.Latest
 func (r *RulesManager) Compile() {
 	if r.Base.Rules == nil || len(r.Base.Rules) == 0 {
@@ -133,6 +186,89 @@ func (r *RulesManager) Compile() {
 		r.Latest.Processors = append(r.Latest.Processors, v.Processors...)
 		r.Latest.Scanners = append(r.Latest.Scanners, v.Scanners...)
 	}
+
+	r.Latest.addGRPCSchemaProcessor()
+}
+
+// grpcSchemaProcessorID is the identifier of the API Security processor extracting the schemas of
+// gRPC requests added by addGRPCSchemaProcessor.
+const grpcSchemaProcessorID = "extract-grpc-content"
+
+// grpcSchemaAddresses are the addresses whose schemas are extracted by the processor added by
+// addGRPCSchemaProcessor.
+var grpcSchemaAddresses = []string{
+	"grpc.server.request.message",
+	"grpc.server.request.metadata",
+	"grpc.server.response.message",
+}
+
+// addGRPCSchemaProcessor adds an API Security processor extracting the schemas of the gRPC messages
+// and metadata when the rules support API Security, i.e. have an extract_schema processor, but none
+// of their processors already reads the gRPC addresses, so that rules handling them, such as
+// remote configuration rules, are left as they are.
+func (f *RulesFragment) addGRPCSchemaProcessor() {
+	var extractsSchemas bool
+	for _, p := range f.Processors {
+		p, _ := p.(map[string]any)
+		if p["id"] == grpcSchemaProcessorID {
+			return
+		}
+		if p["generator"] == "extract_schema" {
+			extractsSchemas = true
+		}
+		params, _ := p["parameters"].(map[string]any)
+		mappings, _ := params["mappings"].([]any)
+		for _, m := range mappings {
+			m, _ := m.(map[string]any)
+			inputs, _ := m["inputs"].([]any)
+			for _, in := range inputs {
+				in, _ := in.(map[string]any)
+				if addr, _ := in["address"].(string); slices.Contains(grpcSchemaAddresses, addr) {
+					return
+				}
+			}
+		}
+	}
+	if !extractsSchemas {
+		return
+	}
+	log.Debug("appsec: adding the processor %s extracting the schemas of gRPC requests to the rules", grpcSchemaProcessorID)
+
+	mapping := func(address, output string) any {
+		return map[string]any{
+			"inputs": []any{map[string]any{"address": address}},
+			"output": output,
+		}
+	}
+	// Clip the processors so that appending never writes into the base fragment's array
+	f.Processors = append(slices.Clip(f.Processors), map[string]any{
+		"id":        grpcSchemaProcessorID,
+		"generator": "extract_schema",
+		"conditions": []any{map[string]any{
+			"operator": "equals",
+			"parameters": map[string]any{
+				"inputs": []any{map[string]any{
+					"address":  "waf.context.processor",
+					"key_path": []any{"extract-schema"},
+				}},
+				"type":  "boolean",
+				"value": true,
+			},
+		}},
+		"parameters": map[string]any{
+			"mappings": []any{
+				mapping("grpc.server.request.message", "_dd.appsec.s.req.body"),
+				mapping("grpc.server.request.metadata", "_dd.appsec.s.req.headers"),
+				mapping("grpc.server.response.message", "_dd.appsec.s.res.body"),
+			},
+			"scanners": []any{
+				map[string]any{"tags": map[string]any{"category": "payment"}},
+				map[string]any{"tags": map[string]any{"category": "pii"}},
+			},
+		},
+		"evaluate": false,
+		"output":   true,
+	})
 }
 
 // Raw returns a compact json version of the rules
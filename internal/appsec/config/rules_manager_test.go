@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGRPCSchemaProcessor(t *testing.T) {
+	countGRPCProcessors := func(f RulesFragment) (n int) {
+		for _, p := range f.Processors {
+			if p, _ := p.(map[string]any); p["id"] == grpcSchemaProcessorID {
+				n++
+			}
+		}
+		return n
+	}
+
+	t.Run("default-rules", func(t *testing.T) {
+		r, err := NewRulesManager(nil)
+		require.NoError(t, err)
+		baseProcessors := len(r.Base.Processors)
+		// The initial rules already extract the schemas of gRPC requests
+		require.Equal(t, 1, countGRPCProcessors(r.Latest))
+
+		r.Compile()
+		require.Equal(t, 1, countGRPCProcessors(r.Latest))
+		require.Len(t, r.Latest.Processors, baseProcessors+1)
+		// The base rules are left untouched
+		require.Zero(t, countGRPCProcessors(r.Base))
+
+		// Compiling again doesn't add it twice
+		r.Compile()
+		require.Equal(t, 1, countGRPCProcessors(r.Latest))
+	})
+
+	t.Run("no-schema-extraction", func(t *testing.T) {
+		r, err := NewRulesManager([]byte(`{"version":"2.2","rules":[{"id":"1","name":"rule","tags":{"type":"test"},"conditions":[]}]}`))
+		require.NoError(t, err)
+		r.Compile()
+		require.Empty(t, r.Latest.Processors)
+	})
+
+	t.Run("already-extracted", func(t *testing.T) {
+		f := RulesFragment{Processors: []any{map[string]any{
+			"id":        "extract-content",
+			"generator": "extract_schema",
+			"parameters": map[string]any{
+				"mappings": []any{map[string]any{
+					"inputs": []any{map[string]any{"address": "grpc.server.request.message"}},
+					"output": "_dd.appsec.s.grpc.req",
+				}},
+			},
+		}}}
+		f.addGRPCSchemaProcessor()
+		require.Len(t, f.Processors, 1)
+	})
+
+	t.Run("asm-dd", func(t *testing.T) {
+		extractSchema := func(id, address string) any {
+			return map[string]any{
+				"id":        id,
+				"generator": "extract_schema",
+				"parameters": map[string]any{
+					"mappings": []any{map[string]any{
+						"inputs": []any{map[string]any{"address": address}},
+						"output": "_dd.appsec.s." + id,
+					}},
+				},
+			}
+		}
+		rules := []any{map[string]any{"id": "rule-1"}}
+
+		r, err := NewRulesManager(nil)
+		require.NoError(t, err)
+		// A remote config ruleset with its own processor reading the gRPC addresses is left as is
+		asmDD := RulesFragment{Version: "2.2", Rules: rules, Processors: []any{
+			extractSchema("extract-http", "server.request.body"),
+			extractSchema("extract-grpc-metadata", "grpc.server.request.metadata"),
+		}}
+		r.ChangeBase(asmDD, "datadog/2/ASM_DD/rules/config")
+		r.Compile()
+		require.Zero(t, countGRPCProcessors(r.Latest))
+		require.Equal(t, asmDD.Processors, r.Latest.Processors)
+
+		// Without any, the gRPC processor is added
+		asmDD.Processors = asmDD.Processors[:1]
+		r.ChangeBase(asmDD, "datadog/2/ASM_DD/rules/config")
+		r.Compile()
+		require.Equal(t, 1, countGRPCProcessors(r.Latest))
+		require.Len(t, r.Base.Processors, 1)
+	})
+}
+
+func TestRuleAddresses(t *testing.T) {
+	f := DefaultRulesFragment()
+	addrs := f.RuleAddresses()
+	require.Contains(t, addrs, "server.request.query")
+	// The response body is only read by the API Security processors
+	require.NotContains(t, addrs, "server.response.body")
+
+	f.CustomRules = []any{map[string]any{
+		"id": "custom-001",
+		"conditions": []any{map[string]any{
+			"operator":   "match_regex",
+			"parameters": map[string]any{"inputs": []any{map[string]any{"address": "server.response.body"}}, "regex": "secret"},
+		}},
+	}}
+	require.Contains(t, f.RuleAddresses(), "server.response.body")
+}
@@ -187,6 +187,14 @@ func RegisterOperation(ctx context.Context, op Operation) context.Context {
 	return orchestrion.CtxWithValue(ctx, contextKey{}, op)
 }
 
+// RegisterOperationValue registers the operation in a request context storing its values in place
+// rather than deriving new contexts, such as the fasthttp request context, using its setValue method.
+// The operation can then be retrieved from that request context using FromContext.
+func RegisterOperationValue(setValue func(key, value any), op Operation) {
+	op.unwrap().inContext = true
+	setValue(contextKey{}, op)
+}
+
 // FinishOperation finishes the operation along with its results and emits a
 // finish event with the operation results.
 // The operation is then disabled and its event listeners removed.
@@ -11,6 +11,9 @@ package graphqlsec
 
 import (
 	"context"
+	"sync/atomic"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/trace"
@@ -22,6 +25,9 @@ type (
 		dyngo.Operation
 		// used in case we don't have a parent operation
 		*waf.ContextOperation
+		// blocked is true once a blocking security event was raised during the request, such as
+		// user blocking when calling appsec.SetUser in a resolver.
+		blocked atomic.Bool
 	}
 
 	// RequestOperationArgs describes arguments passed to a GraphQL request.
@@ -65,5 +71,18 @@ func StartRequestOperation(ctx context.Context, args RequestOperationArgs) (cont
 		op.Operation = dyngo.NewOperation(parent)
 	}
 
+	dyngo.OnData(op, func(*events.BlockingSecurityEvent) {
+		op.blocked.Store(true)
+	})
+
+	ctx = context.WithValue(ctx, requestOperationKey{}, op)
 	return dyngo.StartAndRegisterOperation(ctx, op, args), op
 }
+
+// requestOperationKey is the context key of the current RequestOperation.
+type requestOperationKey struct{}
+
+// Blocked reports whether the request was blocked by a security event raised so far.
+func (op *RequestOperation) Blocked() bool {
+	return op.blocked.Load()
+}
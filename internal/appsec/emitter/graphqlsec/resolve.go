@@ -7,6 +7,9 @@ package graphqlsec
 
 import (
 	"context"
+	"sync/atomic"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 
@@ -16,6 +19,10 @@ import (
 type (
 	ResolveOperation struct {
 		dyngo.Operation
+		// request is the GraphQL request operation the field is resolved for, if any.
+		request *RequestOperation
+		// blocked is true when the field resolution was blocked by a security event.
+		blocked atomic.Bool
 	}
 
 	// ResolveOperationArgs describes arguments passed to a GraphQL field operation.
@@ -59,5 +66,20 @@ func StartResolveOperation(ctx context.Context, args ResolveOperationArgs) (cont
 	op := &ResolveOperation{
 		Operation: dyngo.NewOperation(parent),
 	}
+	op.request, _ = ctx.Value(requestOperationKey{}).(*RequestOperation)
+	dyngo.OnData(op, func(*events.BlockingSecurityEvent) {
+		op.blocked.Store(true)
+	})
 	return dyngo.StartAndRegisterOperation(ctx, op, args), op
 }
+
+// BlockingError returns a *events.BlockingSecurityEvent error when the field resolution or the
+// GraphQL request it belongs to was blocked, such as when a resolver of the request blocked a user
+// with appsec.SetUser. GraphQL integrations able to do so should then return this error instead of
+// resolving the field.
+func (q *ResolveOperation) BlockingError() error {
+	if q.blocked.Load() || (q.request != nil && q.request.Blocked()) {
+		return &events.BlockingSecurityEvent{}
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package httpsec
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ResponseBodyMaxSize is the maximum size, in bytes, of the response bodies recorded
+// by a ResponseBodyRecorder. Larger bodies are not monitored.
+const ResponseBodyMaxSize = 64 * 1024
+
+// bodyKind is the kind of encoding of a body that can be monitored.
+type bodyKind int
+
+const (
+	bodyKindNone bodyKind = iota
+	bodyKindJSON
+	bodyKindXML
+)
+
+// monitoredBodyKind returns the kind of encoding of bodies of the given content type,
+// or bodyKindNone if such bodies are not monitored.
+func monitoredBodyKind(contentType string) bodyKind {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return bodyKindNone
+	}
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return bodyKindJSON
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return bodyKindXML
+	}
+	return bodyKindNone
+}
+
+// parseBody parses the given body according to its kind of encoding.
+func parseBody(kind bodyKind, body []byte) (any, error) {
+	switch kind {
+	case bodyKindJSON:
+		var v any
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case bodyKindXML:
+		return parseXML(body)
+	}
+	return nil, errors.New("unsupported body encoding")
+}
+
+// parseXML parses an XML document into a value made of maps, slices and strings the
+// WAF can inspect. Each element is represented as a map holding its attributes, its
+// character data under the "#text" key and its children, grouped by name in slices.
+func parseXML(body []byte) (any, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	type element struct {
+		name string
+		node map[string]any
+		text strings.Builder
+	}
+	var (
+		stack []*element
+		root  map[string]any
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			el := &element{name: tok.Name.Local, node: make(map[string]any, len(tok.Attr))}
+			for _, attr := range tok.Attr {
+				el.node["@"+attr.Name.Local] = attr.Value
+			}
+			stack = append(stack, el)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, errors.New("unexpected end element")
+			}
+			el := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if text := strings.TrimSpace(el.text.String()); text != "" {
+				el.node["#text"] = text
+			}
+			if len(stack) == 0 {
+				root = map[string]any{el.name: el.node}
+				continue
+			}
+			parent := stack[len(stack)-1].node
+			children, _ := parent[el.name].([]any)
+			parent[el.name] = append(children, el.node)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(tok)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("empty XML document")
+	}
+	return root, nil
+}
+
+// ResponseBodyRecorder records a copy of the response body written by an HTTP handler
+// so that it can be monitored once the response is sent. Only JSON and XML bodies that
+// are not content-encoded and fit in ResponseBodyMaxSize are recorded. The response is
+// not delayed: the body is copied as it is written.
+//
+// The encoding of the body is known from the Content-Type header set by the handler
+// before its first write: bodies without a Content-Type header are not monitored. The
+// content type net/http sniffs for such bodies is not set in the response headers, and
+// cannot tell JSON apart from plain text anyway.
+type ResponseBodyRecorder struct {
+	mu sync.Mutex
+	// kind is the kind of encoding of the body, known on the first write.
+	kind    bodyKind
+	started bool
+	// skipped is true when the body is not to be monitored.
+	skipped bool
+	buf     bytes.Buffer
+}
+
+// Record records the chunk b of the response body. header are the response headers.
+func (r *ResponseBodyRecorder) Record(header http.Header, b []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.started {
+		r.started = true
+		r.kind = monitoredBodyKind(header.Get("Content-Type"))
+		r.skipped = r.kind == bodyKindNone || header.Get("Content-Encoding") != ""
+	}
+	if r.skipped {
+		return
+	}
+	if r.buf.Len()+len(b) > ResponseBodyMaxSize {
+		// A truncated body cannot be parsed
+		r.skipped = true
+		r.buf = bytes.Buffer{}
+		return
+	}
+	r.buf.Write(b)
+}
+
+// Body returns the parsed response body, or nil if it was not recorded or cannot be parsed.
+func (r *ResponseBodyRecorder) Body() any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.skipped || r.buf.Len() == 0 {
+		return nil
+	}
+	body, err := parseBody(r.kind, r.buf.Bytes())
+	if err != nil {
+		return nil
+	}
+	return body
+}
+
+// RequestBodyMaxSize is the maximum size, in bytes, of the request bodies read by
+// readRequestBody. JSON and XML bodies larger than this are not monitored, and only
+// the fields of form bodies found within this size are.
+const RequestBodyMaxSize = 64 * 1024
+
+// maxMultipartValueSize is the maximum size of the values of multipart form fields
+// monitored. The values of larger fields and of files are not monitored.
+const maxMultipartValueSize = 4 * 1024
+
+// readRequestBody reads and parses up to limit bytes of the body of r if it is a JSON,
+// XML, URL-encoded form or multipart form body. The body of r is replaced so that the
+// handler reads the full original body. It returns nil if the body is not parsed.
+func readRequestBody(r *http.Request, limit int) any {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 || r.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+	contentType := r.Header.Get("Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	kind := monitoredBodyKind(contentType)
+	form := mediaType == "application/x-www-form-urlencoded"
+	multipartForm := mediaType == "multipart/form-data" && params["boundary"] != ""
+	if kind == bodyKindNone && !form && !multipartForm {
+		return nil
+	}
+
+	// Read one more byte than the limit to know whether the body is truncated
+	buf, err := io.ReadAll(io.LimitReader(r.Body, int64(limit)+1))
+	r.Body = &restoredBody{Reader: io.MultiReader(bytes.NewReader(buf), &errReader{err}, r.Body), Closer: r.Body}
+	if err != nil {
+		return nil
+	}
+	truncated := len(buf) > limit
+	if truncated {
+		buf = buf[:limit]
+	}
+
+	switch {
+	case form:
+		if truncated {
+			// Drop the last, possibly truncated, field
+			if i := bytes.LastIndexByte(buf, '&'); i >= 0 {
+				buf = buf[:i]
+			} else {
+				return nil
+			}
+		}
+		values, err := url.ParseQuery(string(buf))
+		if err != nil || len(values) == 0 {
+			return nil
+		}
+		return map[string][]string(values)
+	case multipartForm:
+		return parseMultipart(buf, params["boundary"])
+	case truncated:
+		// A truncated JSON or XML body cannot be parsed
+		return nil
+	}
+	body, err := parseBody(kind, buf)
+	if err != nil {
+		return nil
+	}
+	return body
+}
+
+// parseMultipart parses the fields of a multipart form found in body, which may be
+// truncated. File contents and values larger than maxMultipartValueSize are omitted.
+func parseMultipart(body []byte, boundary string) any {
+	values := make(map[string][]string)
+	mr := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		name := part.FormName()
+		if name == "" {
+			continue
+		}
+		if part.FileName() != "" {
+			values[name] = append(values[name], part.FileName())
+			continue
+		}
+		v, err := io.ReadAll(io.LimitReader(part, maxMultipartValueSize+1))
+		if err != nil {
+			// The part is truncated
+			break
+		}
+		if len(v) > maxMultipartValueSize {
+			values[name] = append(values[name], "")
+			continue
+		}
+		values[name] = append(values[name], string(v))
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// restoredBody is a request body whose beginning was read by readRequestBody.
+type restoredBody struct {
+	io.Reader
+	io.Closer
+}
+
+// errReader returns the error that interrupted the reading of a request body by
+// readRequestBody, if any, so that it is returned to the handler.
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read([]byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	return 0, io.EOF
+}
@@ -0,0 +1,180 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package httpsec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/config"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf/addresses"
+)
+
+func TestResponseBodyRecorder(t *testing.T) {
+	for name, tc := range map[string]struct {
+		header http.Header
+		chunks []string
+		want   any
+	}{
+		"json": {
+			header: http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+			chunks: []string{`{"secret":`, `"s3cr3t","n":1}`},
+			want:   map[string]any{"secret": "s3cr3t", "n": json.Number("1")},
+		},
+		"json-suffix": {
+			header: http.Header{"Content-Type": {"application/problem+json"}},
+			chunks: []string{`["a","b"]`},
+			want:   []any{"a", "b"},
+		},
+		"xml": {
+			header: http.Header{"Content-Type": {"application/xml"}},
+			chunks: []string{`<user id="1"><name>admin</name><role>a</role>`, `<role>b</role></user>`},
+			want: map[string]any{"user": map[string]any{
+				"@id":  "1",
+				"name": []any{map[string]any{"#text": "admin"}},
+				"role": []any{map[string]any{"#text": "a"}, map[string]any{"#text": "b"}},
+			}},
+		},
+		"text": {
+			header: http.Header{"Content-Type": {"text/plain"}},
+			chunks: []string{`{"secret":"s3cr3t"}`},
+		},
+		"encoded": {
+			header: http.Header{"Content-Type": {"application/json"}, "Content-Encoding": {"gzip"}},
+			chunks: []string{`{"secret":"s3cr3t"}`},
+		},
+		"invalid": {
+			header: http.Header{"Content-Type": {"application/json"}},
+			chunks: []string{`{"secret":`},
+		},
+		"too-large": {
+			header: http.Header{"Content-Type": {"application/json"}},
+			chunks: []string{`["`, strings.Repeat("a", ResponseBodyMaxSize), `"]`},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var r ResponseBodyRecorder
+			for _, c := range tc.chunks {
+				r.Record(tc.header, []byte(c))
+			}
+			assert.Equal(t, tc.want, r.Body())
+		})
+	}
+}
+
+func TestReadRequestBody(t *testing.T) {
+	multipartBody := func(fields map[string]string, fileName string) (string, string) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		for k, v := range fields {
+			mw.WriteField(k, v)
+		}
+		if fileName != "" {
+			fw, _ := mw.CreateFormFile("upload", fileName)
+			fw.Write([]byte("file content"))
+		}
+		mw.Close()
+		return buf.String(), mw.FormDataContentType()
+	}
+	mpBody, mpContentType := multipartBody(map[string]string{
+		"user":  "admin",
+		"large": strings.Repeat("a", maxMultipartValueSize+1),
+	}, "passwd")
+
+	for name, tc := range map[string]struct {
+		contentType string
+		body        string
+		limit       int
+		want        any
+	}{
+		"json": {
+			contentType: "application/json",
+			body:        `{"user":"admin"}`,
+			want:        map[string]any{"user": "admin"},
+		},
+		"json-truncated": {
+			contentType: "application/json",
+			body:        `{"user":"admin"}`,
+			limit:       8,
+		},
+		"xml": {
+			contentType: "text/xml",
+			body:        `<user>admin</user>`,
+			want:        map[string]any{"user": map[string]any{"#text": "admin"}},
+		},
+		"form": {
+			contentType: "application/x-www-form-urlencoded",
+			body:        "user=admin&role=a&role=b",
+			want:        map[string][]string{"user": {"admin"}, "role": {"a", "b"}},
+		},
+		"form-truncated": {
+			contentType: "application/x-www-form-urlencoded",
+			body:        "user=admin&role=a&role=b",
+			limit:       20,
+			want:        map[string][]string{"user": {"admin"}, "role": {"a"}},
+		},
+		"multipart": {
+			contentType: mpContentType,
+			body:        mpBody,
+			want:        map[string][]string{"user": {"admin"}, "large": {""}, "upload": {"passwd"}},
+		},
+		"text": {
+			contentType: "text/plain",
+			body:        `{"user":"admin"}`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			limit := tc.limit
+			if limit == 0 {
+				limit = RequestBodyMaxSize
+			}
+			r := httptest.NewRequest("POST", "/", strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", tc.contentType)
+
+			assert.Equal(t, tc.want, readRequestBody(r, limit))
+
+			// The handler reads the full original body
+			b, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.body, string(b))
+			assert.NoError(t, r.Body.Close())
+		})
+	}
+}
+
+func TestMonitorsResponseBody(t *testing.T) {
+	assert.False(t, MonitorsResponseBody(context.Background()))
+
+	op, _, ctx := StartOperation(context.Background(), HandlerOperationArgs{})
+	assert.False(t, MonitorsResponseBody(ctx))
+
+	op.SetSupportedAddresses(config.NewAddressSet([]string{addresses.ServerRequestQueryAddr}))
+	assert.False(t, MonitorsResponseBody(ctx))
+
+	// Only read by the API Security processor, for the sampled requests
+	op.SetSupportedAddresses(config.NewAddressSet([]string{addresses.ServerRequestQueryAddr, addresses.ServerResponseBodyAddr}))
+	assert.False(t, MonitorsResponseBody(ctx))
+	op.ExtractSchemas(func() bool { return true })
+	assert.True(t, MonitorsResponseBody(ctx))
+
+	unsampled, _, ctx := StartOperation(context.Background(), HandlerOperationArgs{})
+	unsampled.SetSupportedAddresses(config.NewAddressSet([]string{addresses.ServerResponseBodyAddr}))
+	unsampled.ExtractSchemas(func() bool { return false })
+	assert.False(t, MonitorsResponseBody(ctx))
+
+	// Inspected by a security rule
+	unsampled.SetRuleAddresses(config.NewAddressSet([]string{addresses.ServerResponseBodyAddr}))
+	assert.True(t, MonitorsResponseBody(ctx))
+}
@@ -15,6 +15,9 @@ type Config struct {
 	// apply synchronization if they allow http.ResponseWriter objects to be
 	// accessed by multiple goroutines.
 	ResponseHeaderCopier func(http.ResponseWriter) http.Header
+	// ParseRequestBody enables reading and parsing the request body before the handler
+	// runs in order to monitor it. The handler still reads the full original body.
+	ParseRequestBody bool
 }
 
 var defaultWrapHandlerConfig = &Config{
@@ -44,12 +44,16 @@ type (
 		Cookies     map[string][]string
 		QueryParams map[string][]string
 		PathParams  map[string]string
+		// Body is the parsed request body, if it was read before the handler runs.
+		Body any
 	}
 
 	// HandlerOperationRes is the HTTP handler operation results.
 	HandlerOperationRes struct {
 		Headers    map[string][]string
 		StatusCode int
+		// Body is the parsed response body, if it was recorded.
+		Body any
 	}
 )
 
@@ -95,6 +99,59 @@ func MonitorParsedBody(ctx context.Context, body any) error {
 	)
 }
 
+const monitorResponseBodyErrorLog = `
+"appsec: http response body monitoring ignored: could not find the http handler instrumentation metadata in the request context:
+	the request handler is not being monitored by a middleware function or the provided context is not the expected request context
+`
+
+// MonitorResponseBody runs the WAF on the given parsed response body.
+// This function should not be called when AppSec is disabled in order to
+// get preciser error logs.
+func MonitorResponseBody(ctx context.Context, body any) error {
+	return waf.RunSimple(ctx,
+		addresses.NewAddressesBuilder().
+			WithResponseBody(body).
+			Build(),
+		monitorResponseBodyErrorLog,
+	)
+}
+
+const monitorPathParamsErrorLog = `
+"appsec: http path parameters monitoring ignored: could not find the http handler instrumentation metadata in the request context:
+	the request handler is not being monitored by a middleware function or the provided context is not the expected request context
+`
+
+// MonitorPathParams runs the WAF on the given path parameters of the request. It is meant for the
+// frameworks only resolving them after their middleware functions started the HTTP handler operation.
+// This function should not be called when AppSec is disabled in order to
+// get preciser error logs.
+func MonitorPathParams(ctx context.Context, params map[string]string) error {
+	if len(params) == 0 {
+		return nil
+	}
+	return waf.RunSimple(ctx,
+		addresses.NewAddressesBuilder().
+			WithPathParams(params).
+			Build(),
+		monitorPathParamsErrorLog,
+	)
+}
+
+// MonitorsResponseBody returns true if the response body of the request whose handler
+// operation is found in ctx is monitored by the WAF, i.e. if it is worth recording.
+func MonitorsResponseBody(ctx context.Context) bool {
+	op, _ := dyngo.FromContext(ctx)
+	hop, ok := op.(*HandlerOperation)
+	return ok && hop.monitorsResponseBody()
+}
+
+// monitorsResponseBody returns true if a security rule inspects the response body, or if the
+// request was sampled for the API Security schemas extraction, which also reads it.
+func (op *HandlerOperation) monitorsResponseBody() bool {
+	return op.RulesInspectAddress(addresses.ServerResponseBodyAddr) ||
+		(op.SchemasSampled() && op.SupportsAddress(addresses.ServerResponseBodyAddr))
+}
+
 // Return the map of parsed cookies if any and following the specification of
 // the rule address `server.request.cookies`.
 func makeCookies(parsed []*http.Cookie) map[string][]string {
@@ -125,6 +182,11 @@ func BeforeHandle(
 		opts.ResponseHeaderCopier = defaultWrapHandlerConfig.ResponseHeaderCopier
 	}
 
+	var body any
+	if opts.ParseRequestBody {
+		body = readRequestBody(r, RequestBodyMaxSize)
+	}
+
 	op, blockAtomic, ctx := StartOperation(r.Context(), HandlerOperationArgs{
 		Method:      r.Method,
 		RequestURI:  r.RequestURI,
@@ -134,17 +196,25 @@ func BeforeHandle(
 		Cookies:     makeCookies(r.Cookies()),
 		QueryParams: r.URL.Query(),
 		PathParams:  pathParams,
+		Body:        body,
 	})
 	tr := r.WithContext(ctx)
 
 	afterHandle := func() {
-		var statusCode int
+		var (
+			statusCode int
+			body       any
+		)
 		if res, ok := w.(interface{ Status() int }); ok {
 			statusCode = res.Status()
 		}
+		if res, ok := w.(interface{ ResponseBody() any }); ok && op.monitorsResponseBody() {
+			body = res.ResponseBody()
+		}
 		op.Finish(HandlerOperationRes{
 			Headers:    opts.ResponseHeaderCopier(w),
 			StatusCode: statusCode,
+			Body:       body,
 		}, span)
 
 		// Execute the onBlock functions to make sure blocking works properly
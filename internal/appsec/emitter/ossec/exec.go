@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package ossec
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+var badExecContextOnce sync.Once
+
+type (
+	// ExecOperation type embodies any kind of function calls that will result in a call to an execve(2) syscall
+	ExecOperation struct {
+		dyngo.Operation
+	}
+
+	// ExecOperationArgs is the arguments for an exec operation
+	ExecOperationArgs struct {
+		// Args is the command line of the process to be started, including the program name.
+		// It corresponds to the address `server.sys.exec.cmd`, which has no rule in the
+		// embedded security rules
+		Args []string
+		// ShellCmd is the command passed to a shell when the process to be started is a shell
+		// invoked with -c. It corresponds to the address `server.sys.shell.cmd`
+		ShellCmd string
+	}
+
+	// ExecOperationRes is the result of an exec operation
+	ExecOperationRes struct{}
+)
+
+func (ExecOperationArgs) IsArgOf(*ExecOperation)   {}
+func (ExecOperationRes) IsResultOf(*ExecOperation) {}
+
+// shells are the programs whose -c argument is monitored as a shell command.
+var shells = map[string]struct{}{
+	"sh":   {},
+	"bash": {},
+	"dash": {},
+	"zsh":  {},
+	"ksh":  {},
+	"ash":  {},
+	"fish": {},
+}
+
+// ShellCommand returns the command run by the process started with the given command
+// line if it is a shell invoked with -c, e.g. `sh -c "ls /tmp"`, including when -c
+// is combined with other single-letter options, e.g. `bash -lc "ls /tmp"`.
+func ShellCommand(args []string) (string, bool) {
+	if len(args) < 3 {
+		return "", false
+	}
+	if _, ok := shells[filepath.Base(args[0])]; !ok {
+		return "", false
+	}
+	for i := 1; i < len(args)-1; i++ {
+		arg := args[i]
+		if len(arg) < 2 || (arg[0] != '-' && arg[0] != '+') || arg == "--" {
+			// the options end at the first operand, e.g. a script whose
+			// own arguments are not the shell's
+			return "", false
+		}
+		if arg[0] == '-' && arg[1] != '-' && strings.ContainsRune(arg[1:], 'c') {
+			return args[i+1], true
+		}
+		switch arg {
+		case "-o", "+o", "-O", "+O":
+			i++ // skip the name of the option
+		}
+	}
+	return "", false
+}
+
+// ProtectExecOperation runs the WAF on the command line of a process about to be started
+// with the request context found in ctx. It returns a *events.BlockingSecurityEvent error
+// when the process must not be started.
+func ProtectExecOperation(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+
+	parent, _ := dyngo.FromContext(ctx)
+	if parent == nil { // No parent operation => we can't monitor the request
+		badExecContextOnce.Do(func() {
+			log.Debug("appsec: command execution monitoring ignored: could not find the handler " +
+				"instrumentation metadata in the request context: the request handler is not being monitored by a " +
+				"middleware function or the incoming request context has not be forwarded correctly to the command")
+		})
+		return nil
+	}
+
+	opArgs := ExecOperationArgs{Args: args}
+	opArgs.ShellCmd, _ = ShellCommand(args)
+
+	op := &ExecOperation{
+		Operation: dyngo.NewOperation(parent),
+	}
+
+	var err *events.BlockingSecurityEvent
+	dyngo.OnData(op, func(e *events.BlockingSecurityEvent) {
+		err = e
+	})
+
+	dyngo.StartOperation(op, opArgs)
+	dyngo.FinishOperation(op, ExecOperationRes{})
+
+	if err != nil {
+		log.Debug("appsec: command execution blocked by the WAF")
+		return err
+	}
+
+	return nil
+}
@@ -6,11 +6,18 @@
 package ossec
 
 import (
+	"context"
 	"io/fs"
+	"os"
+	"sync"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
 
+var badOpenContextOnce sync.Once
+
 type (
 	// OpenOperation type embodies any kind of function calls that will result in a call to an open(2) syscall
 	OpenOperation struct {
@@ -39,3 +46,47 @@ type (
 
 func (OpenOperationArgs) IsArgOf(*OpenOperation)         {}
 func (OpenOperationRes[File]) IsResultOf(*OpenOperation) {}
+
+// ProtectOpenOperation runs the WAF on the path of a file about to be opened with the
+// request context found in ctx. It returns a *events.BlockingSecurityEvent error when the
+// file must not be opened.
+func ProtectOpenOperation(ctx context.Context, path string, flags int, perms fs.FileMode) error {
+	parent, _ := dyngo.FromContext(ctx)
+	if parent == nil { // No parent operation => we can't monitor the request
+		badOpenContextOnce.Do(func() {
+			log.Debug("appsec: file opening monitoring ignored: could not find the handler " +
+				"instrumentation metadata in the request context: the request handler is not being monitored by a " +
+				"middleware function or the incoming request context has not be forwarded correctly to the file operation")
+		})
+		return nil
+	}
+
+	op := &OpenOperation{
+		Operation: dyngo.NewOperation(parent),
+	}
+
+	var blockErr *events.BlockingSecurityEvent
+	dyngo.OnData(op, func(e *events.BlockingSecurityEvent) {
+		blockErr = e
+	})
+
+	dyngo.StartOperation(op, OpenOperationArgs{
+		Path:  path,
+		Flags: flags,
+		Perms: perms,
+	})
+
+	// The file is not opened yet: the operation is finished without a file.
+	var (
+		file *os.File
+		err  error
+	)
+	dyngo.FinishOperation(op, OpenOperationRes[*os.File]{File: &file, Err: &err})
+
+	if blockErr != nil {
+		log.Debug("appsec: file opening blocked by the WAF")
+		return blockErr
+	}
+
+	return nil
+}
@@ -55,6 +55,17 @@ func (op *ServiceEntrySpanOperation) SetTag(key string, value any) {
 	op.tags[key] = value
 }
 
+// Tag returns the value of the tag previously added to the service entry span with the given key, if any.
+func (op *ServiceEntrySpanOperation) Tag(key string) (any, bool) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	if v, ok := op.tags[key]; ok {
+		return v, true
+	}
+	v, ok := op.jsonTags[key]
+	return v, ok
+}
+
 // SetSerializableTag adds the key/value pair to the tags to add to the service entry span.
 // The value MAY be serialized as JSON if necessary but simple types will not be serialized.
 func (op *ServiceEntrySpanOperation) SetSerializableTag(key string, value any) {
@@ -7,19 +7,24 @@ package usersec
 
 import (
 	"context"
+	"sync"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
 
 const errorLog = `
-appsec: user login monitoring ignored: could not find the http handler instrumentation metadata in the request context:
+appsec: user login monitoring ignored: could not find the http, grpc or graphql handler instrumentation metadata in the request context:
 	the request handler is not being monitored by a middleware function or the provided context is not the expected request context
 `
 
+var badContextOnce sync.Once
+
 type (
 	// UserLoginOperation type representing a call to appsec.SetUser(). It gets both created and destroyed in a single
-	// call to ExecuteUserIDOperation
+	// call to ExecuteUserIDOperation. Its parent is the operation found in the request context, such as the HTTP,
+	// gRPC or GraphQL handler operation, so that the blocking actions it triggers apply to the request.
 	UserLoginOperation struct {
 		dyngo.Operation
 	}
@@ -35,7 +40,10 @@ type (
 )
 
 func StartUserLoginOperation(ctx context.Context, args UserLoginOperationArgs) (*UserLoginOperation, *error) {
-	parent, _ := dyngo.FromContext(ctx)
+	parent, ok := dyngo.FromContext(ctx)
+	if !ok {
+		badContextOnce.Do(func() { log.Warn(errorLog) })
+	}
 	op := &UserLoginOperation{Operation: dyngo.NewOperation(parent)}
 	var err error
 	dyngo.OnData(op, func(e *events.BlockingSecurityEvent) { err = e })
@@ -6,6 +6,8 @@
 package actions
 
 import (
+	"maps"
+
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
@@ -34,7 +36,8 @@ func registerActionHandler(aType string, handler actionHandler) {
 // SendActionEvents sends the relevant actions to the operation's data listener.
 // It returns true if at least one of those actions require interrupting the request handler
 // When SDKError is not nil, this error is sent to the op with EmitData so that the invoked SDK can return it
-func SendActionEvents(op dyngo.Operation, actions map[string]any) {
+// ruleID is the identifier of the rule that triggered the actions, if known, and is added to their parameters.
+func SendActionEvents(op dyngo.Operation, actions map[string]any, ruleID string) {
 	for aType, params := range actions {
 		log.Debug("appsec: processing %s action with params %v", aType, params)
 		params, ok := params.(map[string]any)
@@ -42,6 +45,10 @@ func SendActionEvents(op dyngo.Operation, actions map[string]any) {
 			log.Debug("appsec: could not cast action params to map[string]any from %T", params)
 			continue
 		}
+		if _, ok := params["rule_id"]; !ok && ruleID != "" {
+			params = maps.Clone(params)
+			params["rule_id"] = ruleID
+		}
 
 		actionHandler, ok := actionHandlers[aType]
 		if !ok {
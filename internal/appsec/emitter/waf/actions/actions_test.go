@@ -6,12 +6,17 @@
 package actions
 
 import (
+	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
 )
 
 func TestNewHTTPBlockRequestAction(t *testing.T) {
@@ -313,3 +318,102 @@ func TestNewRedirectParams(t *testing.T) {
 		})
 	}
 }
+
+func TestPrefersHTML(t *testing.T) {
+	for _, tc := range []struct {
+		accept   string
+		expected bool
+	}{
+		{accept: "", expected: false},
+		{accept: "text/html", expected: true},
+		{accept: "application/json", expected: false},
+		{accept: "*/*", expected: false},
+		{accept: "text/*", expected: true},
+		{accept: "text/html;q=0.5,application/json", expected: false},
+		{accept: "application/json;q=0.8, text/html", expected: true},
+		{accept: "text/html;q=0", expected: false},
+		{accept: "text/html;q=0,*/*", expected: false},
+		{accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", expected: true},
+		{accept: "application/json, text/plain, */*", expected: false},
+		{accept: "text/html;q=invalid,application/json", expected: false},
+	} {
+		t.Run(tc.accept, func(t *testing.T) {
+			require.Equal(t, tc.expected, prefersHTML([]string{tc.accept}))
+		})
+	}
+}
+
+func TestCustomBlockHandlers(t *testing.T) {
+	actions := NewBlockAction(map[string]any{"status_code": 403, "type": "auto", "rule_id": "rule-1"})
+	require.Len(t, actions, 2)
+	httpAction, ok := actions[0].(*BlockHTTP)
+	require.True(t, ok)
+	grpcAction, ok := actions[1].(*BlockGRPC)
+	require.True(t, ok)
+	require.Equal(t, "rule-1", grpcAction.Info.RuleID)
+	require.NotEmpty(t, grpcAction.Info.SecurityResponseID)
+
+	t.Run("http", func(t *testing.T) {
+		SetHTTPBlockHandler("/api/", func(w http.ResponseWriter, _ *http.Request, info *events.BlockInfo) {
+			w.WriteHeader(info.StatusCode)
+			w.Write([]byte(`{"error":"blocked","rule":"` + info.RuleID + `"}`))
+		})
+		defer SetHTTPBlockHandler("/api/", nil)
+
+		serve := func(path string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			httpAction.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+			return rec
+		}
+
+		rec := serve("/api/users")
+		require.Equal(t, 403, rec.Code)
+		require.Equal(t, `{"error":"blocked","rule":"rule-1"}`, rec.Body.String())
+
+		// Requests not matching the pattern get the default response
+		rec = serve("/")
+		require.Equal(t, 403, rec.Code)
+		require.Equal(t, blockedTemplateJSON, rec.Body.Bytes())
+
+		// The global handler is used when no pattern matches
+		SetHTTPBlockHandler("", func(w http.ResponseWriter, _ *http.Request, _ *events.BlockInfo) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+		defer SetHTTPBlockHandler("", nil)
+		require.Equal(t, http.StatusTeapot, serve("/").Code)
+		require.Equal(t, 403, serve("/api/users").Code)
+
+		// Invalid patterns panic without altering the registered handlers
+		require.Panics(t, func() {
+			SetHTTPBlockHandler("GET  /a{", func(http.ResponseWriter, *http.Request, *events.BlockInfo) {})
+		})
+		require.Equal(t, `{"error":"blocked","rule":"rule-1"}`, serve("/api/users").Body.String())
+	})
+
+	t.Run("grpc", func(t *testing.T) {
+		require.NoError(t, grpcAction.CustomError(context.Background(), "/pkg.Service/Method"))
+
+		errService := errors.New("service")
+		errMethod := errors.New("method")
+		errGlobal := errors.New("global")
+		SetGRPCBlockHandler("/pkg.Service/*", func(context.Context, string, *events.BlockInfo) error { return errService })
+		defer SetGRPCBlockHandler("/pkg.Service/*", nil)
+		SetGRPCBlockHandler("/pkg.Service/Method", func(context.Context, string, *events.BlockInfo) error { return errMethod })
+		defer SetGRPCBlockHandler("/pkg.Service/Method", nil)
+		SetGRPCBlockHandler("", func(context.Context, string, *events.BlockInfo) error { return errGlobal })
+		defer SetGRPCBlockHandler("", nil)
+
+		require.Equal(t, errMethod, grpcAction.CustomError(context.Background(), "/pkg.Service/Method"))
+		require.Equal(t, errService, grpcAction.CustomError(context.Background(), "/pkg.Service/Other"))
+		require.Equal(t, errGlobal, grpcAction.CustomError(context.Background(), "/pkg.Other/Method"))
+	})
+}
+
+func TestSendActionEventsRuleID(t *testing.T) {
+	op := dyngo.NewRootOperation()
+	var block *BlockGRPC
+	dyngo.OnData(op, func(a *BlockGRPC) { block = a })
+	SendActionEvents(op, map[string]any{"block_request": map[string]any{"status_code": 403}}, "rule-1")
+	require.NotNil(t, block)
+	require.Equal(t, "rule-1", block.Info.RuleID)
+}
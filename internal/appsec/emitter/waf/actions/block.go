@@ -6,11 +6,15 @@
 package actions
 
 import (
+	"context"
 	_ "embed" // embed is used to embed the blocked-template.json and blocked-template.html files
+	"mime"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
+	"github.com/google/uuid"
 	"github.com/mitchellh/mapstructure"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
@@ -56,6 +60,10 @@ type (
 		GRPCStatusCode *int   `mapstructure:"grpc_status_code,omitempty"`
 		StatusCode     int    `mapstructure:"status_code"`
 		Type           string `mapstructure:"type,omitempty"`
+		// SecurityResponseID identifies the blocking response, it is generated when not provided by the WAF.
+		SecurityResponseID string `mapstructure:"security_response_id,omitempty"`
+		// RuleID is the identifier of the rule that triggered the action, as added by SendActionEvents.
+		RuleID string `mapstructure:"rule_id,omitempty"`
 	}
 	// GRPCWrapper is an opaque prototype abstraction for a gRPC handler (to avoid importing grpc)
 	// that returns a status code and an error
@@ -64,11 +72,15 @@ type (
 	// BlockGRPC are actions that interact with a GRPC request flow
 	BlockGRPC struct {
 		GRPCWrapper
+		// Info describes the blocking action for the custom gRPC block handlers.
+		Info events.BlockInfo
 	}
 
 	// BlockHTTP are actions that interact with an HTTP request flow
 	BlockHTTP struct {
 		http.Handler
+		// Info describes the blocking action for the custom HTTP block handlers.
+		Info events.BlockInfo
 	}
 )
 
@@ -86,6 +98,17 @@ func newGRPCBlockRequestAction(status int) *BlockGRPC {
 	return &BlockGRPC{GRPCWrapper: newGRPCBlockHandler(status)}
 }
 
+// CustomError returns the error returned by the custom gRPC block handler registered for the
+// given full method name, if any. It returns nil when the default blocking status must be used.
+func (a *BlockGRPC) CustomError(ctx context.Context, method string) error {
+	h := lookupGRPCBlockHandler(method)
+	if h == nil {
+		return nil
+	}
+	info := a.Info
+	return h(ctx, method, &info)
+}
+
 func newGRPCBlockHandler(status int) GRPCWrapper {
 	return func() (uint32, error) {
 		return uint32(status), &events.BlockingSecurityEvent{}
@@ -108,9 +131,23 @@ func blockParamsFromMap(params map[string]any) (blockActionParams, error) {
 		p.GRPCStatusCode = &grpcCode
 	}
 
+	if p.SecurityResponseID == "" {
+		p.SecurityResponseID = uuid.NewString()
+	}
+
 	return p, nil
 }
 
+func (p *blockActionParams) blockInfo() events.BlockInfo {
+	return events.BlockInfo{
+		RuleID:             p.RuleID,
+		SecurityResponseID: p.SecurityResponseID,
+		StatusCode:         p.StatusCode,
+		GRPCStatusCode:     *p.GRPCStatusCode,
+		Type:               p.Type,
+	}
+}
+
 // NewBlockAction creates an action for the "block_request" action type
 func NewBlockAction(params map[string]any) []Action {
 	p, err := blockParamsFromMap(params)
@@ -118,10 +155,13 @@ func NewBlockAction(params map[string]any) []Action {
 		log.Debug("appsec: couldn't decode redirect action parameters")
 		return nil
 	}
-	return []Action{
-		newHTTPBlockRequestAction(p.StatusCode, p.Type),
-		newGRPCBlockRequestAction(*p.GRPCStatusCode),
-	}
+	info := p.blockInfo()
+	httpAction := newHTTPBlockRequestAction(p.StatusCode, p.Type)
+	httpAction.Handler = newCustomizableBlockHandler(httpAction.Handler, info)
+	httpAction.Info = info
+	grpcAction := newGRPCBlockRequestAction(*p.GRPCStatusCode)
+	grpcAction.Info = info
+	return []Action{httpAction, grpcAction}
 }
 
 func newHTTPBlockRequestAction(status int, template string) *BlockHTTP {
@@ -140,11 +180,7 @@ func newBlockHandler(status int, template string) http.Handler {
 	default:
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			h := jsonHandler
-			hdr := r.Header.Get("Accept")
-			htmlIdx := strings.Index(hdr, "text/html")
-			jsonIdx := strings.Index(hdr, "application/json")
-			// Switch to html handler if text/html comes before application/json in the Accept header
-			if htmlIdx != -1 && (jsonIdx == -1 || htmlIdx < jsonIdx) {
+			if prefersHTML(r.Header.Values("Accept")) {
 				h = htmlHandler
 			}
 			h.ServeHTTP(w, r)
@@ -152,6 +188,64 @@ func newBlockHandler(status int, template string) http.Handler {
 	}
 }
 
+// prefersHTML reports whether the given Accept header values prefer text/html over application/json,
+// according to their quality values and, for equal qualities, to their order. JSON is preferred when
+// neither is accepted.
+func prefersHTML(accept []string) bool {
+	html := mediaRangeMatch{quality: -1}
+	json := mediaRangeMatch{quality: -1}
+	pos := 0
+	for _, value := range accept {
+		for _, mediaRange := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(mediaRange)
+			if err != nil {
+				continue
+			}
+			quality := 1.0
+			if q, ok := params["q"]; ok {
+				if quality, err = strconv.ParseFloat(q, 64); err != nil {
+					continue
+				}
+			}
+			html.update(mediaType, "text/html", quality, pos)
+			json.update(mediaType, "application/json", quality, pos)
+			pos++
+		}
+	}
+	if html.quality <= 0 {
+		return false
+	}
+	if html.quality != json.quality {
+		return html.quality > json.quality
+	}
+	return html.pos < json.pos
+}
+
+// mediaRangeMatch is the most specific media range of an Accept header matching a given media type.
+type mediaRangeMatch struct {
+	// specificity is 3 for an exact match, 2 for a type/* match and 1 for a */* match
+	specificity int
+	quality     float64
+	pos         int
+}
+
+func (m *mediaRangeMatch) update(mediaRange, mediaType string, quality float64, pos int) {
+	var specificity int
+	switch {
+	case mediaRange == mediaType:
+		specificity = 3
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		specificity = 2
+	case mediaRange == "*/*":
+		specificity = 1
+	default:
+		return
+	}
+	if specificity > m.specificity {
+		*m = mediaRangeMatch{specificity: specificity, quality: quality, pos: pos}
+	}
+}
+
 func newBlockRequestHandler(status int, ct string, payload []byte) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.Header().Set("Content-Type", ct)
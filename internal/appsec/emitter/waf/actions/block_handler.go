@@ -0,0 +1,136 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package actions
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sort"
+	"sync"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
+)
+
+type (
+	// HTTPBlockHandler writes the response of an HTTP request blocked by appsec.
+	HTTPBlockHandler func(w http.ResponseWriter, r *http.Request, info *events.BlockInfo)
+	// GRPCBlockHandler returns the error of a gRPC call blocked by appsec.
+	GRPCBlockHandler func(ctx context.Context, method string, info *events.BlockInfo) error
+)
+
+var (
+	blockHandlersMu sync.RWMutex
+	// httpBlockHandlers are the custom HTTP block handlers, by http.ServeMux pattern
+	httpBlockHandlers = map[string]HTTPBlockHandler{}
+	// httpBlockMux is used to match requests against the patterns of httpBlockHandlers
+	httpBlockMux = http.NewServeMux()
+	// grpcBlockHandlers are the custom gRPC block handlers, by full method name pattern
+	grpcBlockHandlers = map[string]GRPCBlockHandler{}
+	// grpcBlockPatterns are the patterns of grpcBlockHandlers, from the longest to the shortest
+	grpcBlockPatterns []string
+)
+
+// SetHTTPBlockHandler registers h as the handler writing the responses of the HTTP requests blocked
+// by appsec matching the given http.ServeMux pattern, such as "/api/" or "GET /users/{id}", in place
+// of the default blocking responses. The empty pattern registers h for the requests not matching any
+// other pattern. A nil handler unregisters the handler of the pattern. It panics if the pattern is
+// invalid or conflicts with an already registered pattern, as http.ServeMux.Handle does.
+func SetHTTPBlockHandler(pattern string, h HTTPBlockHandler) {
+	blockHandlersMu.Lock()
+	defer blockHandlersMu.Unlock()
+	handlers := make(map[string]HTTPBlockHandler, len(httpBlockHandlers)+1)
+	for p, h := range httpBlockHandlers {
+		handlers[p] = h
+	}
+	if h == nil {
+		delete(handlers, pattern)
+	} else {
+		handlers[pattern] = h
+	}
+	// Build the new mux before updating the handlers so that they are left untouched if the pattern
+	// makes the mux panic
+	mux := http.NewServeMux()
+	for p := range handlers {
+		if p != "" {
+			mux.Handle(p, http.NotFoundHandler())
+		}
+	}
+	httpBlockHandlers = handlers
+	httpBlockMux = mux
+}
+
+// SetGRPCBlockHandler registers h as the handler returning the errors of the gRPC calls blocked by
+// appsec whose full method name, such as "/package.Service/Method", matches the given pattern, in
+// place of the default blocking status. Patterns use the path.Match syntax, such as
+// "/package.Service/*". The empty pattern registers h for the calls not matching any other pattern.
+// A nil handler unregisters the handler of the pattern.
+func SetGRPCBlockHandler(pattern string, h GRPCBlockHandler) {
+	blockHandlersMu.Lock()
+	defer blockHandlersMu.Unlock()
+	if h == nil {
+		delete(grpcBlockHandlers, pattern)
+	} else {
+		grpcBlockHandlers[pattern] = h
+	}
+	grpcBlockPatterns = grpcBlockPatterns[:0]
+	for p := range grpcBlockHandlers {
+		if p != "" {
+			grpcBlockPatterns = append(grpcBlockPatterns, p)
+		}
+	}
+	// Match the most specific patterns first
+	sort.Slice(grpcBlockPatterns, func(i, j int) bool {
+		if len(grpcBlockPatterns[i]) != len(grpcBlockPatterns[j]) {
+			return len(grpcBlockPatterns[i]) > len(grpcBlockPatterns[j])
+		}
+		return grpcBlockPatterns[i] < grpcBlockPatterns[j]
+	})
+}
+
+// lookupHTTPBlockHandler returns the custom HTTP block handler for the request r, if any.
+func lookupHTTPBlockHandler(r *http.Request) HTTPBlockHandler {
+	blockHandlersMu.RLock()
+	defer blockHandlersMu.RUnlock()
+	if len(httpBlockHandlers) == 0 {
+		return nil
+	}
+	if _, pattern := httpBlockMux.Handler(r); pattern != "" {
+		if h, ok := httpBlockHandlers[pattern]; ok {
+			return h
+		}
+	}
+	return httpBlockHandlers[""]
+}
+
+// lookupGRPCBlockHandler returns the custom gRPC block handler for the given full method name, if any.
+func lookupGRPCBlockHandler(method string) GRPCBlockHandler {
+	blockHandlersMu.RLock()
+	defer blockHandlersMu.RUnlock()
+	if h, ok := grpcBlockHandlers[method]; ok {
+		return h
+	}
+	for _, p := range grpcBlockPatterns {
+		if ok, _ := path.Match(p, method); ok {
+			return grpcBlockHandlers[p]
+		}
+	}
+	return grpcBlockHandlers[""]
+}
+
+// newCustomizableBlockHandler returns an HTTP handler writing the blocking response with the custom
+// HTTP block handler registered for the request, if any, and with defaultHandler otherwise.
+func newCustomizableBlockHandler(defaultHandler http.Handler, info events.BlockInfo) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := lookupHTTPBlockHandler(r)
+		if h == nil {
+			defaultHandler.ServeHTTP(w, r)
+			return
+		}
+		info := info
+		h(w, r, &info)
+	})
+}
@@ -15,6 +15,7 @@ const (
 	ServerRequestBodyAddr              = "server.request.body"
 	ServerResponseStatusAddr           = "server.response.status"
 	ServerResponseHeadersNoCookiesAddr = "server.response.headers.no_cookies"
+	ServerResponseBodyAddr             = "server.response.body"
 
 	ClientIPAddr = "http.client_ip"
 
@@ -27,6 +28,8 @@ const (
 	ServerIOFSFileAddr    = "server.io.fs.file"
 	ServerDBStatementAddr = "server.db.statement"
 	ServerDBTypeAddr      = "server.db.system"
+	ServerSysExecCmdAddr  = "server.sys.exec.cmd"
+	ServerSysShellCmdAddr = "server.sys.shell.cmd"
 
 	GRPCServerMethodAddr                   = "grpc.server.method"
 	GRPCServerRequestMetadataAddr          = "grpc.server.request.metadata"
@@ -93,6 +93,14 @@ func (b *RunAddressDataBuilder) WithResponseHeadersNoCookies(headers map[string]
 	return b
 }
 
+func (b *RunAddressDataBuilder) WithResponseBody(body any) *RunAddressDataBuilder {
+	if body == nil {
+		return b
+	}
+	b.Persistent[ServerResponseBodyAddr] = body
+	return b
+}
+
 func (b *RunAddressDataBuilder) WithClientIP(ip netip.Addr) *RunAddressDataBuilder {
 	if !ip.IsValid() {
 		return b
@@ -164,6 +172,24 @@ func (b *RunAddressDataBuilder) WithDBType(driver string) *RunAddressDataBuilder
 	return b
 }
 
+func (b *RunAddressDataBuilder) WithExecCommand(args []string) *RunAddressDataBuilder {
+	if len(args) == 0 {
+		return b
+	}
+	b.Ephemeral[ServerSysExecCmdAddr] = args
+	b.Scope = waf.RASPScope
+	return b
+}
+
+func (b *RunAddressDataBuilder) WithShellCommand(cmd string) *RunAddressDataBuilder {
+	if cmd == "" {
+		return b
+	}
+	b.Ephemeral[ServerSysShellCmdAddr] = cmd
+	b.Scope = waf.RASPScope
+	return b
+}
+
 func (b *RunAddressDataBuilder) WithGRPCMethod(method string) *RunAddressDataBuilder {
 	if method == "" {
 		return b
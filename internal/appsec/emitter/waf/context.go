@@ -8,10 +8,12 @@ package waf
 import (
 	"context"
 	"maps"
+	"math/rand"
 	"slices"
 	"sync"
 	"sync/atomic"
 
+	"github.com/DataDog/appsec-internal-go/appsec"
 	"github.com/DataDog/appsec-internal-go/limiter"
 	waf "github.com/DataDog/go-libddwaf/v3"
 
@@ -21,6 +23,7 @@ import (
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf/addresses"
 )
 
 type (
@@ -35,21 +38,36 @@ type (
 		limiter limiter.Limiter
 		// events is where we store WAF events received from the WAF over the course of the request.
 		events []any
+		// allEvents holds every WAF event received over the course of the request, regardless
+		// of the limits applied to events, when keepAllEvents returns true at the time it is received.
+		allEvents     []any
+		keepAllEvents func() bool
 		// stacks is where we store stack traces received from the WAF over the course of the request.
 		stacks []*stacktrace.Event
 		// derivatives is where we store any span tags generated by the WAF over the course of the request.
 		derivatives map[string]any
 		// supportedAddresses is the set of addresses supported by the WAF.
 		supportedAddresses config.AddressSet
-		// mu protects the events, stacks, and derivatives, supportedAddresses slices.
+		// ruleAddresses is the set of addresses inspected by the security rules, as opposed to
+		// the addresses only used by the processors.
+		ruleAddresses config.AddressSet
+		// userID is the last user ID the WAF was run with over the course of the request.
+		userID string
+		// mu protects the events, stacks, and derivatives, supportedAddresses slices and the userID.
 		mu sync.Mutex
 		// logOnce is used to log a warning once when a request has too many WAF events via the built-in limiter or the max value.
 		logOnce sync.Once
+		// extractSchemasDecided and extractSchemas hold the API Security sampling decision of the request.
+		extractSchemasDecided bool
+		extractSchemas        bool
 	}
 
 	ContextArgs struct{}
 
-	ContextRes struct{}
+	ContextRes struct {
+		// Span is the service entry span the operation's tags are written to.
+		Span trace.TagSetter
+	}
 
 	// RunEvent is the type of event that should be emitted to child operations to run the WAF
 	RunEvent struct {
@@ -71,7 +89,7 @@ func StartContextOperation(ctx context.Context) (*ContextOperation, context.Cont
 }
 
 func (op *ContextOperation) Finish(span trace.TagSetter) {
-	dyngo.FinishOperation(op, ContextRes{})
+	dyngo.FinishOperation(op, ContextRes{Span: span})
 	op.ServiceEntrySpanOperation.Finish(span)
 }
 
@@ -83,11 +101,24 @@ func (op *ContextOperation) SetLimiter(limiter limiter.Limiter) {
 	op.limiter = limiter
 }
 
+// SetKeepAllEvents sets the function reporting whether the WAF events of the request must be kept,
+// regardless of the limits of the events reported in the service entry span, to be returned by
+// AllEvents. It is called every time events are added, so that its result can change during the request.
+func (op *ContextOperation) SetKeepAllEvents(keep func() bool) {
+	op.keepAllEvents = keep
+}
+
 func (op *ContextOperation) AddEvents(events ...any) {
 	if len(events) == 0 {
 		return
 	}
 
+	if op.keepAllEvents != nil && op.keepAllEvents() {
+		op.mu.Lock()
+		op.allEvents = append(op.allEvents, events...)
+		op.mu.Unlock()
+	}
+
 	if !op.limiter.Allow() {
 		log.Warn("appsec: too many WAF events, stopping further reporting")
 		return
@@ -145,12 +176,68 @@ func (op *ContextOperation) Events() []any {
 	return slices.Clone(op.events)
 }
 
+// AllEvents returns every WAF event of the request, including those not reported in the
+// service entry span because of the events limits, that were added while the function set with
+// SetKeepAllEvents returned true.
+func (op *ContextOperation) AllEvents() []any {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	return slices.Clone(op.allEvents)
+}
+
 func (op *ContextOperation) StackTraces() []*stacktrace.Event {
 	op.mu.Lock()
 	defer op.mu.Unlock()
 	return slices.Clone(op.stacks)
 }
 
+// ExtractSchemas reports whether the API Security schemas of the request must be extracted. The decision
+// is made by calling sample the first time only, so that every operation monitoring the request, such as
+// the HTTP handler and GraphQL request operations, shares the same sampling decision.
+func (op *ContextOperation) ExtractSchemas(sample func() bool) bool {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	if !op.extractSchemasDecided {
+		op.extractSchemas = sample()
+		op.extractSchemasDecided = true
+	}
+	return op.extractSchemas
+}
+
+// CanExtractSchemas checks that API Security is enabled and that sampling rate
+// allows extracting schemas. The sampling decision is made once per request and
+// shared by every operation of the request monitored by op.
+func CanExtractSchemas(op *ContextOperation, cfg appsec.APISecConfig) bool {
+	return op.ExtractSchemas(func() bool {
+		return cfg.Enabled && cfg.SampleRate >= rand.Float64()
+	})
+}
+
+// SchemasSampled reports whether the request was sampled for the API Security schemas extraction,
+// without making the sampling decision: it is false until ExtractSchemas is called.
+func (op *ContextOperation) SchemasSampled() bool {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	return op.extractSchemas
+}
+
+// UserID returns the last user ID the WAF was run with, if any.
+func (op *ContextOperation) UserID() string {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	return op.userID
+}
+
+func (op *ContextOperation) setUserID(addrs waf.RunAddressData) {
+	userID, ok := addrs.Persistent[addresses.UserIDAddr].(string)
+	if !ok || userID == "" {
+		return
+	}
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.userID = userID
+}
+
 func (op *ContextOperation) OnEvent(event RunEvent) {
 	op.Run(event.Operation, event.RunAddressData)
 }
@@ -158,3 +245,21 @@ func (op *ContextOperation) OnEvent(event RunEvent) {
 func (op *ContextOperation) SetSupportedAddresses(addrs config.AddressSet) {
 	op.supportedAddresses = addrs
 }
+
+func (op *ContextOperation) SetRuleAddresses(addrs config.AddressSet) {
+	op.ruleAddresses = addrs
+}
+
+// RulesInspectAddress returns true if some loaded security rule inspects the given address, as
+// opposed to the addresses only read by processors such as the API Security schemas extraction.
+func (op *ContextOperation) RulesInspectAddress(addr string) bool {
+	_, ok := op.ruleAddresses[addr]
+	return ok
+}
+
+// SupportsAddress returns true if the WAF supports the given address, i.e. if some loaded
+// rule uses it. Unsupported addresses are not worth computing.
+func (op *ContextOperation) SupportsAddress(addr string) bool {
+	_, ok := op.supportedAddresses[addr]
+	return ok
+}
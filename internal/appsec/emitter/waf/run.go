@@ -24,6 +24,9 @@ import (
 // the event receiver can be the same os the method receiver but not always
 // the event receiver is the one that will receive the actions events generated by the WAF
 func (op *ContextOperation) Run(eventReceiver dyngo.Operation, addrs waf.RunAddressData) {
+	// Keep track of the user ID, even when not supported by the WAF, to report it along with the security events
+	op.setUserID(addrs)
+
 	ctx := op.context.Load()
 	if ctx == nil { // Context was closed concurrently
 		return
@@ -41,22 +44,43 @@ func (op *ContextOperation) Run(eventReceiver dyngo.Operation, addrs waf.RunAddr
 	}
 
 	result, err := ctx.Run(addrs)
-	if errors.Is(err, wafErrors.ErrTimeout) {
+	timeout := errors.Is(err, wafErrors.ErrTimeout)
+	if timeout {
 		log.Debug("appsec: WAF timeout value reached: %v", err)
 	} else if err != nil {
 		log.Error("appsec: unexpected WAF error: %v", err)
 	}
 
+	if addrs.Scope == waf.RASPScope {
+		reportRASPTelemetry(addrs, result.HasEvents(), timeout)
+	}
+
 	op.AddEvents(result.Events...)
 	op.AbsorbDerivatives(result.Derivatives)
 
-	actions.SendActionEvents(eventReceiver, result.Actions)
+	actions.SendActionEvents(eventReceiver, result.Actions, triggeringRuleID(result.Events))
 
 	if result.HasEvents() {
 		log.Debug("appsec: WAF detected a suspicious event")
 	}
 }
 
+// triggeringRuleID returns the identifier of the first rule of the given WAF events configured to
+// trigger actions, or an empty string if none is.
+func triggeringRuleID(events []any) string {
+	for _, event := range events {
+		event, _ := event.(map[string]any)
+		rule, _ := event["rule"].(map[string]any)
+		if onMatch, _ := rule["on_match"].([]any); len(onMatch) == 0 {
+			continue
+		}
+		if id, ok := rule["id"].(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
 // RunSimple runs the WAF with the given address data and returns an error that should be forwarded to the caller
 func RunSimple(ctx context.Context, addrs waf.RunAddressData, errorLog string) error {
 	parent, _ := dyngo.FromContext(ctx)
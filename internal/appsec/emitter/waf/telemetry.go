@@ -0,0 +1,63 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package waf
+
+import (
+	waf "github.com/DataDog/go-libddwaf/v3"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf/addresses"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
+)
+
+// RASP rule types, as reported in the rule_type tag of the RASP telemetry metrics.
+const (
+	raspRuleTypeLFI              = "lfi"
+	raspRuleTypeSSRF             = "ssrf"
+	raspRuleTypeSQLi             = "sql_injection"
+	raspRuleTypeCommandInjection = "command_injection"
+	raspRuleTypeShellInjection   = "shell_injection"
+)
+
+// raspRuleTypes maps the RASP addresses to the type of the rules evaluating them,
+// by order of priority: a shell command is also sent as a command line, and is
+// reported as a shell injection.
+var raspRuleTypes = []struct {
+	addr     string
+	ruleType string
+}{
+	{addresses.ServerSysShellCmdAddr, raspRuleTypeShellInjection},
+	{addresses.ServerSysExecCmdAddr, raspRuleTypeCommandInjection},
+	{addresses.ServerIOFSFileAddr, raspRuleTypeLFI},
+	{addresses.ServerIoNetURLAddr, raspRuleTypeSSRF},
+	{addresses.ServerDBStatementAddr, raspRuleTypeSQLi},
+}
+
+// raspRuleType returns the type of the RASP rules evaluating the given addresses.
+func raspRuleType(addrs waf.RunAddressData) (string, bool) {
+	for _, t := range raspRuleTypes {
+		if _, ok := addrs.Ephemeral[t.addr]; ok {
+			return t.ruleType, true
+		}
+	}
+	return "", false
+}
+
+// reportRASPTelemetry counts a RASP evaluation of the WAF, and whether it matched or
+// timed out, in the appsec telemetry metrics.
+func reportRASPTelemetry(addrs waf.RunAddressData, matched, timeout bool) {
+	ruleType, ok := raspRuleType(addrs)
+	if !ok {
+		return
+	}
+	tags := []string{"rule_type:" + ruleType, "waf_version:" + waf.Version()}
+	telemetry.GlobalClient.Count(telemetry.NamespaceAppSec, "rasp.rule.eval", 1, tags, true)
+	if matched {
+		telemetry.GlobalClient.Count(telemetry.NamespaceAppSec, "rasp.rule.match", 1, tags, true)
+	}
+	if timeout {
+		telemetry.GlobalClient.Count(telemetry.NamespaceAppSec, "rasp.timeout", 1, tags, true)
+	}
+}
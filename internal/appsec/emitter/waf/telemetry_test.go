@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package waf
+
+import (
+	"testing"
+
+	waf "github.com/DataDog/go-libddwaf/v3"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf/addresses"
+)
+
+func TestRASPRuleType(t *testing.T) {
+	// a shell command is sent along with its command line
+	addrs := waf.RunAddressData{Ephemeral: map[string]any{
+		addresses.ServerSysExecCmdAddr:  []string{"sh", "-c", "ls"},
+		addresses.ServerSysShellCmdAddr: "ls",
+	}}
+	for i := 0; i < 10; i++ {
+		ruleType, ok := raspRuleType(addrs)
+		assert.True(t, ok)
+		assert.Equal(t, raspRuleTypeShellInjection, ruleType)
+	}
+
+	ruleType, ok := raspRuleType(waf.RunAddressData{Ephemeral: map[string]any{addresses.ServerSysExecCmdAddr: []string{"ls"}}})
+	assert.True(t, ok)
+	assert.Equal(t, raspRuleTypeCommandInjection, ruleType)
+
+	_, ok = raspRuleType(waf.RunAddressData{Persistent: map[string]any{addresses.ServerSysExecCmdAddr: []string{"ls"}}})
+	assert.False(t, ok)
+}
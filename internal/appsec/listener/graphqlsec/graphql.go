@@ -6,6 +6,8 @@
 package graphqlsec
 
 import (
+	"github.com/DataDog/appsec-internal-go/appsec"
+
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/config"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/graphqlsec"
@@ -14,7 +16,9 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/listener"
 )
 
-type Feature struct{}
+type Feature struct {
+	APISec appsec.APISecConfig
+}
 
 func (*Feature) String() string {
 	return "GraphQL Security"
@@ -22,6 +26,15 @@ func (*Feature) String() string {
 
 func (*Feature) Stop() {}
 
+// OnRequest decides whether to extract the API Security schemas of the GraphQL request when it is not
+// monitored by a parent operation, such as an HTTP handler operation, which otherwise takes the decision.
+func (f *Feature) OnRequest(op *graphqlsec.RequestOperation, _ graphqlsec.RequestOperationArgs) {
+	if op.ContextOperation == nil || !waf.CanExtractSchemas(op.ContextOperation, f.APISec) {
+		return
+	}
+	op.ContextOperation.Run(op, addresses.NewAddressesBuilder().ExtractSchema().Build())
+}
+
 func (f *Feature) OnResolveField(op *graphqlsec.ResolveOperation, args graphqlsec.ResolveOperationArgs) {
 	dyngo.EmitData(op, waf.RunEvent{
 		Operation: op,
@@ -36,7 +49,10 @@ func NewGraphQLSecFeature(config *config.Config, rootOp dyngo.Operation) (listen
 		return nil, nil
 	}
 
-	feature := &Feature{}
+	feature := &Feature{
+		APISec: config.APISec,
+	}
+	dyngo.On(rootOp, feature.OnRequest)
 	dyngo.On(rootOp, feature.OnResolveField)
 
 	return feature, nil
@@ -6,17 +6,22 @@
 package grpcsec
 
 import (
+	"github.com/DataDog/appsec-internal-go/appsec"
+
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/config"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/grpcsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf/addresses"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/listener"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/listener/httpsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
 
-type Feature struct{}
+type Feature struct {
+	APISec appsec.APISecConfig
+}
 
 func (*Feature) String() string {
 	return "gRPC Security"
@@ -37,7 +42,9 @@ func NewGRPCSecFeature(config *config.Config, rootOp dyngo.Operation) (listener.
 		return nil, nil
 	}
 
-	feature := &Feature{}
+	feature := &Feature{
+		APISec: config.APISec,
+	}
 	dyngo.On(rootOp, feature.OnStart)
 	dyngo.OnFinish(rootOp, feature.OnFinish)
 	return feature, nil
@@ -51,13 +58,17 @@ func (f *Feature) OnStart(op *grpcsec.HandlerOperation, args grpcsec.HandlerOper
 
 	SetRequestMetadataTags(op, args.Metadata)
 
-	op.Run(op,
-		addresses.NewAddressesBuilder().
-			WithGRPCMethod(args.Method).
-			WithGRPCRequestMetadata(args.Metadata).
-			WithClientIP(clientIP).
-			Build(),
-	)
+	builder := addresses.NewAddressesBuilder().
+		WithGRPCMethod(args.Method).
+		WithGRPCRequestMetadata(args.Metadata).
+		WithClientIP(clientIP)
+
+	// The messages are sent to the WAF over the course of the call, after this first WAF run
+	if waf.CanExtractSchemas(op.ContextOperation, f.APISec) {
+		builder = builder.ExtractSchema()
+	}
+
+	op.Run(op, builder.Build())
 }
 
 func (f *Feature) OnFinish(op *grpcsec.HandlerOperation, res grpcsec.HandlerOperationRes) {
@@ -6,8 +6,6 @@
 package httpsec
 
 import (
-	"math/rand"
-
 	"github.com/DataDog/appsec-internal-go/appsec"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/listener"
@@ -15,6 +13,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/config"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/httpsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf/addresses"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
@@ -38,7 +37,8 @@ func NewHTTPSecFeature(config *config.Config, rootOp dyngo.Operation) (listener.
 		addresses.ServerRequestPathParamsAddr,
 		addresses.ServerRequestBodyAddr,
 		addresses.ServerResponseStatusAddr,
-		addresses.ServerResponseHeadersNoCookiesAddr) {
+		addresses.ServerResponseHeadersNoCookiesAddr,
+		addresses.ServerResponseBodyAddr) {
 		return nil, nil
 	}
 
@@ -61,17 +61,23 @@ func (feature *Feature) OnRequest(op *httpsec.HandlerOperation, args httpsec.Han
 
 	setRequestHeadersTags(op, headers)
 
-	op.Run(op,
-		addresses.NewAddressesBuilder().
-			WithMethod(args.Method).
-			WithRawURI(args.RequestURI).
-			WithHeadersNoCookies(headers).
-			WithCookies(args.Cookies).
-			WithQuery(args.QueryParams).
-			WithPathParams(args.PathParams).
-			WithClientIP(ip).
-			Build(),
-	)
+	builder := addresses.NewAddressesBuilder().
+		WithMethod(args.Method).
+		WithRawURI(args.RequestURI).
+		WithHeadersNoCookies(headers).
+		WithCookies(args.Cookies).
+		WithQuery(args.QueryParams).
+		WithPathParams(args.PathParams).
+		WithRequestBody(args.Body).
+		WithClientIP(ip)
+
+	// Decide whether to extract the schemas as soon as the request starts so that the addresses only
+	// sent over the course of the request, such as the GraphQL resolvers, are extracted too.
+	if waf.CanExtractSchemas(op.ContextOperation, feature.APISec) {
+		builder = builder.ExtractSchema()
+	}
+
+	op.Run(op, builder.Build())
 }
 
 func (feature *Feature) OnResponse(op *httpsec.HandlerOperation, resp httpsec.HandlerOperationRes) {
@@ -80,17 +86,8 @@ func (feature *Feature) OnResponse(op *httpsec.HandlerOperation, resp httpsec.Ha
 
 	builder := addresses.NewAddressesBuilder().
 		WithResponseHeadersNoCookies(headers).
-		WithResponseStatus(resp.StatusCode)
-
-	if feature.canExtractSchemas() {
-		builder = builder.ExtractSchema()
-	}
+		WithResponseStatus(resp.StatusCode).
+		WithResponseBody(resp.Body)
 
 	op.Run(op, builder.Build())
 }
-
-// canExtractSchemas checks that API Security is enabled and that sampling rate
-// allows extracting schemas
-func (feature *Feature) canExtractSchemas() bool {
-	return feature.APISec.Enabled && feature.APISec.SampleRate >= rand.Float64()
-}
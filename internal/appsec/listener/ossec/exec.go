@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package ossec
+
+import (
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/config"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/ossec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf/addresses"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/listener"
+)
+
+type ExecFeature struct{}
+
+func (*ExecFeature) String() string {
+	return "Command Injection Protection"
+}
+
+func (*ExecFeature) Stop() {}
+
+func NewExecSecFeature(cfg *config.Config, rootOp dyngo.Operation) (listener.Feature, error) {
+	if !cfg.RASP || !cfg.SupportedAddresses.AnyOf(addresses.ServerSysExecCmdAddr, addresses.ServerSysShellCmdAddr) {
+		return nil, nil
+	}
+
+	feature := &ExecFeature{}
+	dyngo.On(rootOp, feature.OnStart)
+	return feature, nil
+}
+
+func (*ExecFeature) OnStart(op *ossec.ExecOperation, args ossec.ExecOperationArgs) {
+	dyngo.EmitData(op, waf.RunEvent{
+		Operation: op,
+		RunAddressData: addresses.NewAddressesBuilder().
+			WithExecCommand(args.Args).
+			WithShellCommand(args.ShellCmd).
+			Build(),
+	})
+}
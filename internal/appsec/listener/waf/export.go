@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package waf
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+var (
+	exportersMu sync.RWMutex
+	exporters   = map[int]func(*events.SecurityEvent){}
+	exporterID  int
+)
+
+// AddEventExporter registers fn to be called with every security event detected by the WAF,
+// regardless of the sampling decision of the request's trace. The returned function
+// unregisters fn.
+func AddEventExporter(fn func(*events.SecurityEvent)) (remove func()) {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+	id := exporterID
+	exporterID++
+	exporters[id] = fn
+	return func() {
+		exportersMu.Lock()
+		defer exportersMu.Unlock()
+		delete(exporters, id)
+	}
+}
+
+// hasEventExporters returns true if an event exporter is registered.
+func hasEventExporters() bool {
+	exportersMu.RLock()
+	defer exportersMu.RUnlock()
+	return len(exporters) > 0
+}
+
+// exportEvents converts the WAF events of the operation into security events and calls the
+// registered exporters with them.
+func exportEvents(op *waf.ContextOperation, span trace.TagSetter, wafEvents []any) {
+	if len(wafEvents) == 0 {
+		return
+	}
+	exportersMu.RLock()
+	fns := make([]func(*events.SecurityEvent), 0, len(exporters))
+	for _, fn := range exporters {
+		fns = append(fns, fn)
+	}
+	exportersMu.RUnlock()
+	if len(fns) == 0 {
+		return
+	}
+
+	base := events.SecurityEvent{
+		Time:   time.Now(),
+		UserID: op.UserID(),
+	}
+	if ip, ok := op.Tag(ext.HTTPClientIP); ok {
+		base.ClientIP, _ = ip.(string)
+	}
+	if blocked, ok := op.Tag(BlockedRequestTag); ok {
+		base.Blocked, _ = blocked.(bool)
+	}
+	if id, ok := op.Tag(SecurityResponseIDTag); ok {
+		base.SecurityResponseID, _ = id.(string)
+	}
+	if s, ok := span.(interface{ Context() ddtrace.SpanContext }); ok && s.Context() != nil {
+		base.TraceID = s.Context().TraceID()
+		base.SpanID = s.Context().SpanID()
+	}
+
+	for _, wafEvent := range wafEvents {
+		e := base
+		if err := fillSecurityEvent(&e, wafEvent); err != nil {
+			log.Debug("appsec: failed to export security event: %v", err)
+			continue
+		}
+		for _, export := range fns {
+			export(&e)
+		}
+	}
+}
+
+// fillSecurityEvent fills e with the rule and rule matches of the WAF event wafEvent.
+func fillSecurityEvent(e *events.SecurityEvent, wafEvent any) error {
+	event, ok := wafEvent.(map[string]any)
+	if !ok {
+		return fmt.Errorf("unexpected WAF event type %T", wafEvent)
+	}
+	rule, ok := event["rule"].(map[string]any)
+	if !ok {
+		return fmt.Errorf("unexpected WAF event without rule")
+	}
+	e.RuleID, _ = rule["id"].(string)
+	e.RuleName, _ = rule["name"].(string)
+	if tags, ok := rule["tags"].(map[string]any); ok {
+		e.Tags = make(map[string]string, len(tags))
+		for k, v := range tags {
+			e.Tags[k] = fmt.Sprint(v)
+		}
+	}
+	e.Actions = stringSlice(rule["on_match"])
+
+	matches, _ := event["rule_matches"].([]any)
+	for _, match := range matches {
+		match, ok := match.(map[string]any)
+		if !ok {
+			continue
+		}
+		params, _ := match["parameters"].([]any)
+		for _, param := range params {
+			param, ok := param.(map[string]any)
+			if !ok {
+				continue
+			}
+			p := events.SecurityEventParameter{
+				Highlight: stringSlice(param["highlight"]),
+			}
+			p.Address, _ = param["address"].(string)
+			p.Value, _ = param["value"].(string)
+			p.KeyPath, _ = param["key_path"].([]any)
+			e.Parameters = append(e.Parameters, p)
+		}
+	}
+	return nil
+}
+
+func stringSlice(v any) []string {
+	values, _ := v.([]any)
+	if len(values) == 0 {
+		return nil
+	}
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
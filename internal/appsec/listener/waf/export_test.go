@@ -0,0 +1,159 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package waf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	wafv3 "github.com/DataDog/go-libddwaf/v3"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec/events"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf/addresses"
+)
+
+type testSpanContext struct {
+	traceID, spanID uint64
+}
+
+func (c testSpanContext) TraceID() uint64                         { return c.traceID }
+func (c testSpanContext) SpanID() uint64                          { return c.spanID }
+func (testSpanContext) ForeachBaggageItem(func(k, v string) bool) {}
+
+type testSpan struct {
+	trace.TestTagSetter
+	ctx testSpanContext
+}
+
+func (s testSpan) Context() ddtrace.SpanContext { return s.ctx }
+
+func testWAFEvent() map[string]any {
+	return map[string]any{
+		"rule": map[string]any{
+			"id":       "crs-942-100",
+			"name":     "SQL Injection Attack Detected via libinjection",
+			"tags":     map[string]any{"type": "sql_injection", "category": "attack_attempt"},
+			"on_match": []any{"block"},
+		},
+		"rule_matches": []any{
+			map[string]any{
+				"operator": "is_sqli",
+				"parameters": []any{
+					map[string]any{
+						"address":   "server.request.query",
+						"key_path":  []any{"id", 0},
+						"value":     "1' OR 1=1 --",
+						"highlight": []any{"s&1c"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestFillSecurityEvent(t *testing.T) {
+	var e events.SecurityEvent
+	require.NoError(t, fillSecurityEvent(&e, testWAFEvent()))
+	require.Equal(t, events.SecurityEvent{
+		RuleID:   "crs-942-100",
+		RuleName: "SQL Injection Attack Detected via libinjection",
+		Tags:     map[string]string{"type": "sql_injection", "category": "attack_attempt"},
+		Actions:  []string{"block"},
+		Parameters: []events.SecurityEventParameter{
+			{
+				Address:   "server.request.query",
+				KeyPath:   []any{"id", 0},
+				Value:     "1' OR 1=1 --",
+				Highlight: []string{"s&1c"},
+			},
+		},
+	}, e)
+
+	require.Error(t, fillSecurityEvent(&e, "not an event"))
+	require.Error(t, fillSecurityEvent(&e, map[string]any{}))
+}
+
+func TestExportEvents(t *testing.T) {
+	op, _ := waf.StartContextOperation(context.Background())
+	op.SetTag(ext.HTTPClientIP, "1.2.3.4")
+	op.SetTag(BlockedRequestTag, true)
+	op.Run(op, wafv3.RunAddressData{Persistent: map[string]any{addresses.UserIDAddr: "user-1"}})
+	span := testSpan{TestTagSetter: make(trace.TestTagSetter), ctx: testSpanContext{traceID: 1, spanID: 2}}
+
+	var buf bytes.Buffer
+	w := events.NewJSONLinesWriter(&buf)
+	remove := AddEventExporter(w.Export)
+	exportEvents(op, span, []any{testWAFEvent(), testWAFEvent()})
+	remove()
+	// The exporter is not called anymore once removed
+	exportEvents(op, span, []any{testWAFEvent()})
+	require.NoError(t, w.Err())
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	for _, line := range lines {
+		var e events.SecurityEvent
+		require.NoError(t, json.Unmarshal(line, &e))
+		require.Equal(t, "crs-942-100", e.RuleID)
+		require.Equal(t, "1.2.3.4", e.ClientIP)
+		require.Equal(t, "user-1", e.UserID)
+		require.True(t, e.Blocked)
+		require.Equal(t, uint64(1), e.TraceID)
+		require.Equal(t, uint64(2), e.SpanID)
+		require.False(t, e.Time.IsZero())
+	}
+}
+
+func TestExportEventsNotLimited(t *testing.T) {
+	var exported []*events.SecurityEvent
+	remove := AddEventExporter(func(e *events.SecurityEvent) { exported = append(exported, e) })
+	defer remove()
+
+	op, _ := waf.StartContextOperation(context.Background())
+	op.SetKeepAllEvents(hasEventExporters)
+	// no event is allowed in the span
+	op.SetLimiter(denyLimiter{})
+	op.SetTag(BlockedRequestTag, true)
+	op.SetTag(SecurityResponseIDTag, "response-1")
+	for i := 0; i < 20; i++ {
+		op.AddEvents(testWAFEvent())
+	}
+	require.Empty(t, op.Events())
+
+	span := testSpan{TestTagSetter: make(trace.TestTagSetter), ctx: testSpanContext{traceID: 1, spanID: 2}}
+	exportEvents(op, span, op.AllEvents())
+	require.Len(t, exported, 20)
+	require.Equal(t, "response-1", exported[0].SecurityResponseID)
+}
+
+func TestExportEventsExporterAddedDuringRequest(t *testing.T) {
+	op, _ := waf.StartContextOperation(context.Background())
+	op.SetKeepAllEvents(hasEventExporters)
+	op.SetLimiter(denyLimiter{})
+	// no exporter is registered yet, so the event is not kept
+	op.AddEvents(testWAFEvent())
+	require.Empty(t, op.AllEvents())
+
+	var exported []*events.SecurityEvent
+	remove := AddEventExporter(func(e *events.SecurityEvent) { exported = append(exported, e) })
+	defer remove()
+	op.AddEvents(testWAFEvent())
+
+	span := testSpan{TestTagSetter: make(trace.TestTagSetter), ctx: testSpanContext{traceID: 1, spanID: 2}}
+	exportEvents(op, span, op.AllEvents())
+	require.Len(t, exported, 1)
+}
+
+type denyLimiter struct{}
+
+func (denyLimiter) Allow() bool { return false }
@@ -28,6 +28,8 @@ const (
 
 	// BlockedRequestTag used to convey whether a request is blocked
 	BlockedRequestTag = "appsec.blocked"
+	// SecurityResponseIDTag holds the identifier of the blocking response of a blocked request
+	SecurityResponseIDTag = "appsec.security_response_id"
 )
 
 // AddRulesMonitoringTags adds the tags related to security rules monitoring
@@ -29,6 +29,7 @@ type Feature struct {
 	limiter         *limiter.TokenTicker
 	handle          *wafv3.Handle
 	supportedAddrs  config.AddressSet
+	ruleAddrs       config.AddressSet
 	reportRulesTags sync.Once
 }
 
@@ -59,6 +60,7 @@ func NewWAFFeature(cfg *config.Config, rootOp dyngo.Operation) (listener.Feature
 		timeout:        cfg.WAFTimeout,
 		limiter:        tokenTicker,
 		supportedAddrs: cfg.SupportedAddresses,
+		ruleAddrs:      cfg.RulesManager.Latest.RuleAddresses(),
 	}
 
 	dyngo.On(rootOp, feature.onStart)
@@ -80,6 +82,10 @@ func (waf *Feature) onStart(op *waf.ContextOperation, _ waf.ContextArgs) {
 	op.SwapContext(ctx)
 	op.SetLimiter(waf.limiter)
 	op.SetSupportedAddresses(waf.supportedAddrs)
+	op.SetRuleAddresses(waf.ruleAddrs)
+	// The exported events are not subject to the limits of the events reported in the span. Whether
+	// an exporter is registered is checked for every event, as they can be added during the request.
+	op.SetKeepAllEvents(hasEventExporters)
 
 	// Run the WAF with the given address data
 	dyngo.OnData(op, op.OnEvent)
@@ -93,13 +99,28 @@ func (waf *Feature) SetupActionHandlers(op *waf.ContextOperation) {
 		op.SetTag(BlockedRequestTag, true)
 	})
 
+	// Set the security response ID of the blocking action on the operation, so that the
+	// blocking response shown to the user can be looked up
+	dyngo.OnData(op, func(a *actions.BlockHTTP) {
+		setSecurityResponseID(op, a.Info)
+	})
+	dyngo.OnData(op, func(a *actions.BlockGRPC) {
+		setSecurityResponseID(op, a.Info)
+	})
+
 	// Register the stacktrace if one is requested by a WAF action
 	dyngo.OnData(op, func(err *actions.StackTraceAction) {
 		op.AddStackTraces(err.Event)
 	})
 }
 
-func (waf *Feature) onFinish(op *waf.ContextOperation, _ waf.ContextRes) {
+func setSecurityResponseID(op *waf.ContextOperation, info events.BlockInfo) {
+	if info.SecurityResponseID != "" {
+		op.SetTag(SecurityResponseIDTag, info.SecurityResponseID)
+	}
+}
+
+func (waf *Feature) onFinish(op *waf.ContextOperation, res waf.ContextRes) {
 	ctx := op.SwapContext(nil)
 	if ctx == nil {
 		return
@@ -108,9 +129,11 @@ func (waf *Feature) onFinish(op *waf.ContextOperation, _ waf.ContextRes) {
 	ctx.Close()
 
 	AddWAFMonitoringTags(op, waf.handle.Diagnostics().Version, ctx.Stats().Metrics())
-	if err := SetEventSpanTags(op, op.Events()); err != nil {
+	wafEvents := op.Events()
+	if err := SetEventSpanTags(op, wafEvents); err != nil {
 		log.Debug("appsec: failed to set event span tags: %v", err)
 	}
+	exportEvents(op, res.Span, op.AllEvents())
 
 	op.SetSerializableTags(op.Derivatives())
 	if stacks := op.StackTraces(); len(stacks) > 0 {
@@ -0,0 +1,30 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package waf
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf/actions"
+)
+
+func TestSecurityResponseIDTag(t *testing.T) {
+	op, _ := waf.StartContextOperation(context.Background())
+	(&Feature{}).SetupActionHandlers(op)
+
+	for _, a := range actions.NewBlockAction(map[string]any{"security_response_id": "response-1"}) {
+		a.EmitData(op)
+	}
+
+	blocked, _ := op.Tag(BlockedRequestTag)
+	require.Equal(t, true, blocked)
+	id, _ := op.Tag(SecurityResponseIDTag)
+	require.Equal(t, "response-1", id)
+}
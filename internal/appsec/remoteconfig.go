@@ -92,12 +92,12 @@ updateLoop:
 				newBasePath = path
 				newBaseData = data
 			}
-			// update with data = nil means the config was removed, so we switch back to the default rules
+			// update with data = nil means the config was removed, so we switch back to the local rules file, or the default rules
 			// only happens if no update was found, otherwise it could revert the switch to the new base rules
 			if newBaseData == nil {
 				if removalFound {
-					log.Debug("appsec: Remote config: ASM_DD config removed. Switching back to default rules")
-					r.ChangeBase(config.DefaultRulesFragment(), "")
+					log.Debug("appsec: Remote config: ASM_DD config removed. Switching back to the local or default rules")
+					r.RevertBase()
 					maps.Copy(statuses, statusesFromUpdate(u, true, nil))
 				}
 				continue
@@ -165,6 +165,9 @@ func (a *appsec) onRCRulesUpdate(updates map[string]remoteconfig.ProductUpdate)
 		return map[string]rc.ApplyStatus{}
 	}
 
+	a.rulesMu.Lock()
+	defer a.rulesMu.Unlock()
+
 	// Create a new local RulesManager
 	r := a.cfg.RulesManager.Clone()
 	statuses, err := combineRCRulesUpdates(&r, updates)
@@ -0,0 +1,246 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package appsec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/config"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
+
+	waf "github.com/DataDog/go-libddwaf/v3"
+)
+
+// localRulesEditPrefix prefixes the RulesManager edit entries of the local rules fragment files so
+// that they don't collide with the ones received through remote config.
+const localRulesEditPrefix = "local:"
+
+// rulesFileState is the state of a local rules file used to detect its changes.
+type rulesFileState struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+func statRulesFile(path string) rulesFileState {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return rulesFileState{}
+	}
+	return rulesFileState{exists: true, modTime: fi.ModTime(), size: fi.Size()}
+}
+
+// rulesReloader periodically checks the local rules files for changes and reloads the security rules
+// when they change.
+type rulesReloader struct {
+	a        *appsec
+	interval time.Duration
+	states   map[string]rulesFileState
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// localRulesFiles returns the paths of the local rules files: the rules file given with DD_APPSEC_RULES,
+// if any, followed by the rules fragment files.
+func localRulesFiles(cfg *config.Config) []string {
+	var files []string
+	if cfg.RulesFile != "" {
+		files = append(files, cfg.RulesFile)
+	}
+	return append(files, cfg.RulesEditFiles...)
+}
+
+// loadLocalRules applies the local rules files to the RulesManager, following the same path as the
+// remote config rules updates. The rules are only applied when the WAF successfully validates them,
+// otherwise the current RulesManager is left untouched and the error is returned. a.rulesMu must be held.
+func (a *appsec) loadLocalRules() error {
+	r := a.cfg.RulesManager.Clone()
+	if err := combineLocalRulesFiles(&r, a.cfg); err != nil {
+		return err
+	}
+	r.Compile()
+	if err := validateRules(r.Latest, a.cfg); err != nil {
+		return err
+	}
+	log.Debug("appsec: local rules: final compiled rules: %s", r.String())
+	a.cfg.RulesManager = &r
+	return nil
+}
+
+// combineLocalRulesFiles updates the state of the given RulesManager with the content of the local
+// rules files. The rules file replaces the local base rules, which remain overridden by the ASM_DD rules
+// received through remote config if any, while each rules fragment file is stored as a RulesManager edit entry.
+func combineLocalRulesFiles(r *config.RulesManager, cfg *config.Config) error {
+	if cfg.RulesFile != "" {
+		f, err := readRulesFragment(cfg.RulesFile)
+		if err != nil {
+			return err
+		}
+		r.ChangeLocalBase(f)
+	}
+	for _, path := range cfg.RulesEditFiles {
+		f, err := readRulesFragment(path)
+		if err != nil {
+			return err
+		}
+		r.AddEdit(localRulesEditPrefix+path, f)
+	}
+	return nil
+}
+
+func readRulesFragment(path string) (config.RulesFragment, error) {
+	var f config.RulesFragment
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return f, fmt.Errorf("could not read rules file %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("could not parse rules file %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// validateRules checks that the WAF can be instantiated with the given rules without any error,
+// including the errors reported by the WAF diagnostics for individual rules, exclusions, actions, etc.
+func validateRules(rules config.RulesFragment, cfg *config.Config) error {
+	// Go through JSON so that the empty top level fields are omitted instead of being reported by the WAF
+	// diagnostics as null arrays.
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	handle, err := waf.NewHandle(obj, cfg.Obfuscator.KeyRegex, cfg.Obfuscator.ValueRegex)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+
+	diags := handle.Diagnostics()
+	if err := diags.TopLevelError(); err != nil {
+		return err
+	}
+	entries := map[string]*waf.DiagnosticEntry{
+		"rules":          diags.Rules,
+		"custom_rules":   diags.CustomRules,
+		"actions":        diags.Actions,
+		"exclusions":     diags.Exclusions,
+		"rules_override": diags.RulesOverrides,
+		"rules_data":     diags.RulesData,
+		"processors":     diags.Processors,
+		"scanners":       diags.Scanners,
+	}
+	var errs []error
+	for field, entry := range entries {
+		if entry == nil {
+			continue
+		}
+		for msg, ids := range entry.Errors {
+			errs = append(errs, fmt.Errorf("%s %s: %s", field, strings.Join(ids, ", "), msg))
+		}
+	}
+	// Sort the errors to log them in a stable order
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+// reportRulesUpdateTelemetry reports the outcome of a local rules update.
+func reportRulesUpdateTelemetry(err error) {
+	tags := []string{"waf_version:" + waf.Version(), fmt.Sprintf("success:%t", err == nil)}
+	telemetry.GlobalClient.Count(telemetry.NamespaceAppSec, "waf.updates", 1, tags, true)
+}
+
+// startRulesReloader starts watching the local rules files when hot reloading is enabled.
+func (a *appsec) startRulesReloader() {
+	files := localRulesFiles(a.cfg)
+	if a.cfg.RulesReloadInterval <= 0 || len(files) == 0 {
+		return
+	}
+	w := &rulesReloader{
+		a:        a,
+		interval: a.cfg.RulesReloadInterval,
+		states:   make(map[string]rulesFileState, len(files)),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, path := range files {
+		w.states[path] = statRulesFile(path)
+	}
+	a.rulesReloader = w
+	log.Debug("appsec: local rules: watching %v for changes every %s", files, w.interval)
+	go w.run()
+}
+
+// stopRulesReloader stops watching the local rules files and waits for the watcher to return.
+func (a *appsec) stopRulesReloader() {
+	if a.rulesReloader == nil {
+		return
+	}
+	close(a.rulesReloader.stop)
+	<-a.rulesReloader.done
+	a.rulesReloader = nil
+}
+
+func (w *rulesReloader) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			if w.changed() {
+				w.a.reloadLocalRules()
+			}
+		}
+	}
+}
+
+// changed reports whether any of the watched files changed since the last check.
+func (w *rulesReloader) changed() bool {
+	changed := false
+	for path, prev := range w.states {
+		cur := statRulesFile(path)
+		if cur != prev {
+			log.Debug("appsec: local rules: %s changed", path)
+			w.states[path] = cur
+			changed = true
+		}
+	}
+	return changed
+}
+
+// reloadLocalRules reloads the local rules files and swaps the root operation with the new rules.
+// The current rules are kept when the new ones are invalid.
+func (a *appsec) reloadLocalRules() {
+	a.rulesMu.Lock()
+	defer a.rulesMu.Unlock()
+
+	prev := a.cfg.RulesManager
+	err := a.loadLocalRules()
+	if err == nil {
+		if err = a.SwapRootOperation(); err != nil {
+			a.cfg.RulesManager = prev
+		}
+	}
+	reportRulesUpdateTelemetry(err)
+	if err != nil {
+		log.Error("appsec: local rules: could not apply the new security rules, the current ones are kept: %v", err)
+		return
+	}
+	log.Info("appsec: local rules: security rules reloaded")
+}
@@ -0,0 +1,151 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package appsec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/config"
+
+	rc "github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
+	waf "github.com/DataDog/go-libddwaf/v3"
+	"github.com/stretchr/testify/require"
+)
+
+const testCustomRules = `{
+  "custom_rules": [
+    {
+      "id": "custom-001",
+      "name": "Custom rule",
+      "tags": {"type": "security_scanner", "category": "attack_attempt"},
+      "conditions": [
+        {
+          "operator": "match_regex",
+          "parameters": {
+            "inputs": [{"address": "server.request.uri.raw"}],
+            "regex": "custom-attack"
+          }
+        }
+      ]
+    }
+  ]
+}`
+
+func TestCombineLocalRulesFiles(t *testing.T) {
+	dir := t.TempDir()
+	edits := filepath.Join(dir, "custom.json")
+	require.NoError(t, os.WriteFile(edits, []byte(testCustomRules), 0o600))
+
+	t.Run("edits", func(t *testing.T) {
+		r, err := config.NewRulesManager(nil)
+		require.NoError(t, err)
+		cfg := &config.Config{RulesEditFiles: []string{edits}}
+		require.NoError(t, combineLocalRulesFiles(r, cfg))
+		require.Contains(t, r.Edits, localRulesEditPrefix+edits)
+		require.Empty(t, r.BasePath)
+		r.Compile()
+		require.Len(t, r.Latest.CustomRules, 1)
+		require.Equal(t, config.DefaultRulesFragment().Rules, r.Latest.Rules)
+	})
+
+	t.Run("base", func(t *testing.T) {
+		base := filepath.Join(dir, "rules.json")
+		require.NoError(t, os.WriteFile(base, []byte(`{"version":"2.2","metadata":{"rules_version":"1.2.3"},"rules":[{"id":"rule-1"}]}`), 0o600))
+		r, err := config.NewRulesManager(nil)
+		require.NoError(t, err)
+		require.NoError(t, combineLocalRulesFiles(r, &config.Config{RulesFile: base}))
+		r.Compile()
+		require.Len(t, r.Latest.Rules, 1)
+	})
+
+	t.Run("asm-dd-base", func(t *testing.T) {
+		base := filepath.Join(dir, "rules.json")
+		require.NoError(t, os.WriteFile(base, []byte(`{"version":"2.2","metadata":{"rules_version":"1.2.3"},"rules":[{"id":"rule-1"}]}`), 0o600))
+		r, err := config.NewRulesManager(nil)
+		require.NoError(t, err)
+		cfg := &config.Config{RulesFile: base}
+		require.NoError(t, combineLocalRulesFiles(r, cfg))
+
+		// The ASM_DD rules take precedence over the local rules file, even when it gets reloaded
+		asmDD := config.RulesFragment{Version: "2.2", Rules: []any{map[string]any{"id": "rule-2"}, map[string]any{"id": "rule-3"}}}
+		_, err = combineRCRulesUpdates(r, craftRCUpdates(map[string]config.RulesFragment{"rules/asm-dd": asmDD}))
+		require.NoError(t, err)
+		require.NoError(t, combineLocalRulesFiles(r, cfg))
+		r.Compile()
+		require.Equal(t, "rules/asm-dd", r.BasePath)
+		require.Len(t, r.Latest.Rules, 2)
+
+		// Removing the ASM_DD config switches back to the local rules file
+		updates := craftRCUpdates(map[string]config.RulesFragment{"rules/asm-dd": asmDD})
+		updates[rc.ProductASMDD]["rules/asm-dd"] = nil
+		clone := r.Clone()
+		_, err = combineRCRulesUpdates(&clone, updates)
+		require.NoError(t, err)
+		clone.Compile()
+		require.Empty(t, clone.BasePath)
+		require.Len(t, clone.Latest.Rules, 1)
+	})
+
+	t.Run("missing-file", func(t *testing.T) {
+		r, err := config.NewRulesManager(nil)
+		require.NoError(t, err)
+		err = combineLocalRulesFiles(r, &config.Config{RulesEditFiles: []string{filepath.Join(dir, "missing.json")}})
+		require.ErrorContains(t, err, "could not read rules file")
+		require.Empty(t, r.Edits)
+	})
+
+	t.Run("invalid-json", func(t *testing.T) {
+		invalid := filepath.Join(dir, "invalid.json")
+		require.NoError(t, os.WriteFile(invalid, []byte(`{"custom_rules":`), 0o600))
+		r, err := config.NewRulesManager(nil)
+		require.NoError(t, err)
+		err = combineLocalRulesFiles(r, &config.Config{RulesEditFiles: []string{invalid}})
+		require.ErrorContains(t, err, "could not parse rules file")
+	})
+}
+
+func TestRulesReloaderChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.json")
+	w := &rulesReloader{states: map[string]rulesFileState{path: statRulesFile(path)}}
+	require.False(t, w.changed())
+
+	require.NoError(t, os.WriteFile(path, []byte(testCustomRules), 0o600))
+	require.True(t, w.changed())
+	require.False(t, w.changed())
+
+	// Changing the modification time is enough to be detected
+	mtime := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(path, mtime, mtime))
+	require.True(t, w.changed())
+
+	require.NoError(t, os.Remove(path))
+	require.True(t, w.changed())
+}
+
+func TestLoadLocalRules(t *testing.T) {
+	if supported, _ := waf.Health(); !supported {
+		t.Skip("WAF needs to be available for this test")
+	}
+
+	path := filepath.Join(t.TempDir(), "custom.json")
+	require.NoError(t, os.WriteFile(path, []byte(testCustomRules), 0o600))
+	cfg, err := config.NewConfig()
+	require.NoError(t, err)
+	cfg.RulesEditFiles = []string{path}
+	a := newAppSec(cfg)
+
+	require.NoError(t, a.loadLocalRules())
+	require.Len(t, a.cfg.RulesManager.Latest.CustomRules, 1)
+	valid := a.cfg.RulesManager
+
+	// Invalid rules are rejected by the WAF and the current rules are kept
+	require.NoError(t, os.WriteFile(path, []byte(`{"custom_rules":[{"id":"custom-002","conditions":[{"operator":"unknown"}]}]}`), 0o600))
+	require.Error(t, a.loadLocalRules())
+	require.Same(t, valid, a.cfg.RulesManager)
+}
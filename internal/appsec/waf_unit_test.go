@@ -14,6 +14,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/config"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/emitter/waf/addresses"
 )
 
@@ -184,3 +185,45 @@ func TestAPISecuritySchemaCollection(t *testing.T) {
 		})
 	}
 }
+
+func TestAPISecurityGRPCSchemaCollection(t *testing.T) {
+	if wafOk, err := waf.Health(); !wafOk {
+		t.Skipf("WAF must be usable for this test to run correctly: %v", err)
+	}
+	rules, err := config.NewRulesManager(nil)
+	require.NoError(t, err)
+	rules.Compile()
+	handle, err := waf.NewHandle(rules.Latest, "", "")
+	require.NoError(t, err)
+	defer handle.Close()
+
+	wafCtx, err := handle.NewContext()
+	require.NoError(t, err)
+	defer wafCtx.Close()
+
+	res, err := wafCtx.Run(waf.RunAddressData{
+		Persistent: map[string]any{
+			"waf.context.processor":                 map[string]any{"extract-schema": true},
+			addresses.GRPCServerRequestMetadataAddr: map[string][]string{"my-metadata": {"is-beautiful"}},
+		},
+		Ephemeral: map[string]any{
+			addresses.GRPCServerRequestMessageAddr: map[string]any{"name": "hello"},
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, res.HasDerivatives())
+	schema, err := json.Marshal(res.Derivatives)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"_dd.appsec.s.req.body":[{"name":[8]}],"_dd.appsec.s.req.headers":[{"my-metadata":[[[8]],{"len":1}]}]}`, string(schema))
+
+	// The response message is only extracted when sent later on
+	res, err = wafCtx.Run(waf.RunAddressData{
+		Ephemeral: map[string]any{
+			addresses.GRPCServerResponseMessageAddr: map[string]any{"message": "passed"},
+		},
+	})
+	require.NoError(t, err)
+	schema, err = json.Marshal(res.Derivatives)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"_dd.appsec.s.res.body":[{"message":[8]}]}`, string(schema))
+}
@@ -54,6 +54,12 @@ type StatsBucket struct {
 	Stats []StatsPoint
 	// Backlogs store information used to compute queue backlog
 	Backlogs []Backlog
+	// Transactions holds the transactions tracked during this bucket, each
+	// encoded as [checkpoint id][timestamp][id length][id].
+	Transactions []byte
+	// TransactionCheckpointIds maps the checkpoint ids used in Transactions to
+	// checkpoint names, encoded as [checkpoint id][name length][name].
+	TransactionCheckpointIds []byte
 }
 
 // TimestampType can be either current or origin.
@@ -60,6 +60,7 @@ type bucket struct {
 	latestCommitOffsets        map[partitionConsumerKey]int64
 	latestProduceOffsets       map[partitionKey]int64
 	latestHighWatermarkOffsets map[partitionKey]int64
+	transactions               []byte
 	start                      uint64
 	duration                   uint64
 }
@@ -105,10 +106,11 @@ func (b bucket) export(timestampType TimestampType) StatsBucket {
 		})
 	}
 	exported := StatsBucket{
-		Start:    b.start,
-		Duration: b.duration,
-		Stats:    stats,
-		Backlogs: make([]Backlog, 0, len(b.latestCommitOffsets)+len(b.latestProduceOffsets)+len(b.latestHighWatermarkOffsets)),
+		Start:        b.start,
+		Duration:     b.duration,
+		Stats:        stats,
+		Backlogs:     make([]Backlog, 0, len(b.latestCommitOffsets)+len(b.latestProduceOffsets)+len(b.latestHighWatermarkOffsets)),
+		Transactions: b.transactions,
 	}
 	for key, offset := range b.latestProduceOffsets {
 		exported.Backlogs = append(exported.Backlogs, Backlog{Tags: []string{fmt.Sprintf("partition:%d", key.partition), fmt.Sprintf("topic:%s", key.topic), "type:kafka_produce"}, Value: offset})
@@ -127,21 +129,24 @@ type pointType int
 const (
 	pointTypeStats pointType = iota
 	pointTypeKafkaOffset
+	pointTypeTransaction
 )
 
 type processorInput struct {
 	point       statsPoint
 	kafkaOffset kafkaOffset
+	transaction transaction
 	typ         pointType
 	queuePos    int64
 }
 
 type processorStats struct {
-	payloadsIn      int64
-	flushedPayloads int64
-	flushedBuckets  int64
-	flushErrors     int64
-	dropped         int64
+	payloadsIn          int64
+	flushedPayloads     int64
+	flushedBuckets      int64
+	flushErrors         int64
+	dropped             int64
+	droppedTransactions int64
 }
 
 type partitionKey struct {
@@ -178,6 +183,8 @@ type Processor struct {
 	inKafka              chan kafkaOffset
 	tsTypeCurrentBuckets map[int64]bucket
 	tsTypeOriginBuckets  map[int64]bucket
+	checkpoints          transactionCheckpoints
+	schemaSampler        schemaSampler
 	wg                   sync.WaitGroup
 	stopped              uint64
 	stop                 chan struct{} // closing this channel triggers shutdown
@@ -289,12 +296,31 @@ func (p *Processor) addKafkaOffset(o kafkaOffset) {
 	}] = o.offset
 }
 
+func (p *Processor) addTransaction(t transaction) {
+	checkpointID, ok := p.checkpoints.id(t.checkpoint)
+	if !ok {
+		atomic.AddInt64(&p.stats.droppedTransactions, 1)
+		return
+	}
+	btime := alignTs(t.timestamp, bucketDuration.Nanoseconds())
+	b := p.getBucket(btime, p.tsTypeCurrentBuckets)
+	if len(b.transactions) >= maxTransactionBytesPerBucket {
+		atomic.AddInt64(&p.stats.droppedTransactions, 1)
+		return
+	}
+	b.transactions = appendTransaction(b.transactions, checkpointID, t.timestamp, t.id)
+	p.tsTypeCurrentBuckets[btime] = b
+}
+
 func (p *Processor) processInput(in *processorInput) {
 	atomic.AddInt64(&p.stats.payloadsIn, 1)
-	if in.typ == pointTypeStats {
+	switch in.typ {
+	case pointTypeStats:
 		p.add(in.point)
-	} else if in.typ == pointTypeKafkaOffset {
+	case pointTypeKafkaOffset:
 		p.addKafkaOffset(in.kafkaOffset)
+	case pointTypeTransaction:
+		p.addTransaction(in.transaction)
 	}
 }
 
@@ -389,13 +415,18 @@ func (p *Processor) reportStats() {
 		p.statsd.Count("datadog.datastreams.processor.flushed_buckets", atomic.SwapInt64(&p.stats.flushedBuckets, 0), nil, 1)
 		p.statsd.Count("datadog.datastreams.processor.flush_errors", atomic.SwapInt64(&p.stats.flushErrors, 0), nil, 1)
 		p.statsd.Count("datadog.datastreams.processor.dropped_payloads", atomic.SwapInt64(&p.stats.dropped, 0), nil, 1)
+		p.statsd.Count("datadog.datastreams.processor.dropped_transactions", atomic.SwapInt64(&p.stats.droppedTransactions, 0), nil, 1)
 	}
 }
 
 func (p *Processor) flushBucket(buckets map[int64]bucket, bucketStart int64, timestampType TimestampType) StatsBucket {
 	bucket := buckets[bucketStart]
 	delete(buckets, bucketStart)
-	return bucket.export(timestampType)
+	exported := bucket.export(timestampType)
+	if len(exported.Transactions) > 0 {
+		exported.TransactionCheckpointIds = p.checkpoints.encoded
+	}
+	return exported
 }
 
 func (p *Processor) flush(now time.Time) StatsPayload {
@@ -508,3 +539,19 @@ func (p *Processor) TrackKafkaHighWatermarkOffset(_ string, topic string, partit
 		atomic.AddInt64(&p.stats.dropped, 1)
 	}
 }
+
+// TrackTransaction records that the transaction with the given ID, e.g. the
+// ID of an order, went through the checkpoint with the given name. This allows
+// following a specific message across the queues and services of a pathway.
+// IDs and checkpoint names longer than 255 bytes are truncated, and only the
+// first 255 distinct checkpoint names are tracked.
+func (p *Processor) TrackTransaction(transactionID, checkpointName string) {
+	dropped := p.in.push(&processorInput{typ: pointTypeTransaction, transaction: transaction{
+		id:         truncateTransactionField(transactionID),
+		checkpoint: truncateTransactionField(checkpointName),
+		timestamp:  p.time().UnixNano(),
+	}})
+	if dropped {
+		atomic.AddInt64(&p.stats.dropped, 1)
+	}
+}
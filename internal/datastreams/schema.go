@@ -0,0 +1,276 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package datastreams
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Schema types.
+const (
+	SchemaTypeProtobuf = "protobuf"
+	SchemaTypeAvro     = "avro"
+	SchemaTypeJSON     = "json"
+)
+
+const (
+	// schemaSampleInterval is the minimum interval between two samples of
+	// the schema of the same key.
+	schemaSampleInterval = 30 * time.Second
+	// maxJSONSchemaDepth is the maximum depth of the payloads inferred by
+	// InferJSONSchema. Deeper values are reported without a type.
+	maxJSONSchemaDepth = 10
+	// confluentWireHeaderSize is the size of the header prepended to payloads
+	// serialized with a Confluent Schema Registry serializer: a zero magic
+	// byte followed by the 4 bytes schema ID.
+	confluentWireHeaderSize = 5
+)
+
+// Schema describes the schema of a payload.
+type Schema struct {
+	// Type is one of SchemaTypeProtobuf, SchemaTypeAvro or SchemaTypeJSON.
+	Type string
+	// Name is the fully qualified name of the schema, if any.
+	Name string
+	// Definition is the canonical JSON definition of the schema.
+	Definition string
+	// ID is the fingerprint of the definition.
+	ID string
+}
+
+func newSchema(typ, name, definition string) Schema {
+	h := fnv.New64a()
+	h.Write([]byte(definition))
+	return Schema{
+		Type:       typ,
+		Name:       name,
+		Definition: definition,
+		ID:         strconv.FormatUint(h.Sum64(), 10),
+	}
+}
+
+// ProtobufSchema returns the schema of the protobuf message described by md.
+// The definition is an OpenAPI document holding the message and all the
+// messages it references.
+func ProtobufSchema(md protoreflect.MessageDescriptor) Schema {
+	schemas := make(map[string]any)
+	addProtobufMessage(md, schemas)
+	definition, _ := json.Marshal(map[string]any{
+		"openapi":    "3.0.0",
+		"components": map[string]any{"schemas": schemas},
+	})
+	return newSchema(SchemaTypeProtobuf, string(md.FullName()), string(definition))
+}
+
+func addProtobufMessage(md protoreflect.MessageDescriptor, schemas map[string]any) {
+	name := string(md.FullName())
+	if _, ok := schemas[name]; ok {
+		return
+	}
+	properties := make(map[string]any)
+	// registered before walking the fields to stop on recursive messages
+	schemas[name] = map[string]any{"type": "object", "properties": properties}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		var property map[string]any
+		switch {
+		case fd.IsMap():
+			property = map[string]any{
+				"type":                 "object",
+				"additionalProperties": protobufFieldSchema(fd.MapValue(), schemas),
+			}
+		case fd.IsList():
+			property = map[string]any{
+				"type":  "array",
+				"items": protobufFieldSchema(fd, schemas),
+			}
+		default:
+			property = protobufFieldSchema(fd, schemas)
+		}
+		property["extensions"] = map[string]any{"x-protobuf-number": fd.Number()}
+		properties[string(fd.Name())] = property
+	}
+}
+
+// protobufFieldSchema returns the schema of a single value of the field fd.
+func protobufFieldSchema(fd protoreflect.FieldDescriptor, schemas map[string]any) map[string]any {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case protoreflect.FloatKind:
+		return map[string]any{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return map[string]any{"type": "number", "format": "double"}
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		names := make([]string, values.Len())
+		for i := range names {
+			names[i] = string(values.Get(i).Name())
+		}
+		return map[string]any{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		addProtobufMessage(fd.Message(), schemas)
+		return map[string]any{"$ref": "#/components/schemas/" + string(fd.Message().FullName())}
+	}
+	return map[string]any{}
+}
+
+// AvroSchema returns the schema of the Avro payloads described by the JSON
+// definition. The definition is canonicalized, so that equivalent
+// definitions get the same ID.
+func AvroSchema(definition string) (Schema, error) {
+	var v any
+	if err := json.Unmarshal([]byte(definition), &v); err != nil {
+		return Schema{}, err
+	}
+	// encoding/json sorts the keys of maps and strips whitespace
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return Schema{}, err
+	}
+	var name string
+	if record, ok := v.(map[string]any); ok {
+		name, _ = record["name"].(string)
+		if ns, ok := record["namespace"].(string); ok && ns != "" && !strings.Contains(name, ".") {
+			name = ns + "." + name
+		}
+	}
+	return newSchema(SchemaTypeAvro, name, string(canonical)), nil
+}
+
+var errNotJSONDocument = errors.New("payload is not a JSON object or array")
+
+// InferJSONSchema returns the JSON schema inferred from a JSON object or
+// array payload. Arrays are described by the schema of their first item.
+func InferJSONSchema(payload []byte) (Schema, error) {
+	payload = bytes.TrimSpace(payload)
+	if len(payload) == 0 || (payload[0] != '{' && payload[0] != '[') {
+		return Schema{}, errNotJSONDocument
+	}
+	d := json.NewDecoder(bytes.NewReader(payload))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return Schema{}, err
+	}
+	definition, err := json.Marshal(inferJSONSchema(v, 0))
+	if err != nil {
+		return Schema{}, err
+	}
+	return newSchema(SchemaTypeJSON, "", string(definition)), nil
+}
+
+func inferJSONSchema(v any, depth int) map[string]any {
+	if depth >= maxJSONSchemaDepth {
+		return map[string]any{}
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		properties := make(map[string]any, len(v))
+		for k, e := range v {
+			properties[k] = inferJSONSchema(e, depth+1)
+		}
+		return map[string]any{"type": "object", "properties": properties}
+	case []any:
+		if len(v) == 0 {
+			return map[string]any{"type": "array"}
+		}
+		return map[string]any{"type": "array", "items": inferJSONSchema(v[0], depth+1)}
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return map[string]any{"type": "integer"}
+		}
+		return map[string]any{"type": "number"}
+	case string:
+		return map[string]any{"type": "string"}
+	case bool:
+		return map[string]any{"type": "boolean"}
+	}
+	return map[string]any{"type": "null"}
+}
+
+var topicSchemas sync.Map // topic -> Schema
+
+// RegisterTopicSchema registers s as the schema of the payloads of topic, to
+// be reported by PayloadSchema.
+func RegisterTopicSchema(topic string, s Schema) {
+	topicSchemas.Store(topic, s)
+}
+
+// PayloadSchema returns the schema of a payload of topic: the schema
+// registered with RegisterTopicSchema if any, or the schema inferred from the
+// payload if it is a JSON document, possibly framed by a Confluent Schema
+// Registry serializer.
+func PayloadSchema(topic string, payload []byte) (Schema, bool) {
+	if s, ok := topicSchemas.Load(topic); ok {
+		return s.(Schema), true
+	}
+	if len(payload) > confluentWireHeaderSize && payload[0] == 0 {
+		payload = payload[confluentWireHeaderSize:]
+	}
+	s, err := InferJSONSchema(payload)
+	return s, err == nil
+}
+
+// schemaSampler samples schemas per key, at most once per
+// schemaSampleInterval.
+type schemaSampler struct {
+	states sync.Map // key -> *schemaSamplerState
+}
+
+type schemaSamplerState struct {
+	weight     atomic.Int64
+	lastSample atomic.Int64
+}
+
+// trySample returns the weight of the sample, i.e. the number of payloads it
+// stands for, or 0 if the schema of key should not be sampled now.
+func (s *schemaSampler) trySample(key string, now time.Time) int64 {
+	v, ok := s.states.Load(key)
+	if !ok {
+		v, _ = s.states.LoadOrStore(key, &schemaSamplerState{})
+	}
+	state := v.(*schemaSamplerState)
+	state.weight.Add(1)
+	last := state.lastSample.Load()
+	nowNano := now.UnixNano()
+	if nowNano-last < schemaSampleInterval.Nanoseconds() {
+		return 0
+	}
+	if !state.lastSample.CompareAndSwap(last, nowNano) {
+		return 0
+	}
+	return state.weight.Swap(0)
+}
+
+// TrySampleSchema returns the weight of the sample if the schema of the
+// payloads identified by key, e.g. a topic and an operation, should be
+// computed and reported now, or 0 otherwise. This caps the overhead of schema
+// tracking to one schema per key every 30 seconds.
+func (p *Processor) TrySampleSchema(key string) int64 {
+	return p.schemaSampler.trySample(key, p.time())
+}
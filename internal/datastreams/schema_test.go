@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package datastreams
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestProtobufSchema(t *testing.T) {
+	s := ProtobufSchema((&structpb.Struct{}).ProtoReflect().Descriptor())
+	assert.Equal(t, SchemaTypeProtobuf, s.Type)
+	assert.Equal(t, "google.protobuf.Struct", s.Name)
+	assert.NotEmpty(t, s.ID)
+
+	var definition struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]any
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(s.Definition), &definition))
+	// Struct and Value reference each other, through a map and a list.
+	schemas := definition.Components.Schemas
+	assert.Contains(t, schemas, "google.protobuf.Struct")
+	assert.Contains(t, schemas, "google.protobuf.Value")
+	assert.Contains(t, schemas, "google.protobuf.ListValue")
+	assert.Equal(t, map[string]any{"$ref": "#/components/schemas/google.protobuf.Value"},
+		schemas["google.protobuf.Struct"].Properties["fields"]["additionalProperties"])
+	assert.Equal(t, "string", schemas["google.protobuf.Value"].Properties["null_value"]["type"])
+	assert.Equal(t, "array", schemas["google.protobuf.ListValue"].Properties["values"]["type"])
+
+	// The definition is deterministic.
+	assert.Equal(t, s, ProtobufSchema((&structpb.Struct{}).ProtoReflect().Descriptor()))
+	assert.NotEqual(t, s.ID, ProtobufSchema((&timestamppb.Timestamp{}).ProtoReflect().Descriptor()).ID)
+}
+
+func TestAvroSchema(t *testing.T) {
+	s1, err := AvroSchema(`{"type": "record", "name": "Order", "namespace": "shop",
+		"fields": [{"name": "id", "type": "string"}]}`)
+	require.NoError(t, err)
+	s2, err := AvroSchema(`{"namespace":"shop","fields":[{"type":"string","name":"id"}],"name":"Order","type":"record"}`)
+	require.NoError(t, err)
+	assert.Equal(t, s1, s2)
+	assert.Equal(t, SchemaTypeAvro, s1.Type)
+	assert.Equal(t, "shop.Order", s1.Name)
+
+	_, err = AvroSchema(`{"type":`)
+	assert.Error(t, err)
+}
+
+func TestInferJSONSchema(t *testing.T) {
+	s, err := InferJSONSchema([]byte(`{"id": 42, "price": 1.5, "items": [{"sku": "a"}], "gift": false, "note": null}`))
+	require.NoError(t, err)
+	assert.Equal(t, SchemaTypeJSON, s.Type)
+	assert.JSONEq(t, `{"type": "object", "properties": {
+		"id": {"type": "integer"},
+		"price": {"type": "number"},
+		"items": {"type": "array", "items": {"type": "object", "properties": {"sku": {"type": "string"}}}},
+		"gift": {"type": "boolean"},
+		"note": {"type": "null"}
+	}}`, s.Definition)
+
+	// Values do not change the schema.
+	other, err := InferJSONSchema([]byte(`{"note": null, "gift": true, "items": [{"sku": "b"}], "price": 2.5, "id": 7}`))
+	require.NoError(t, err)
+	assert.Equal(t, s.ID, other.ID)
+
+	for _, payload := range []string{``, `"string"`, `42`, `{"id":`, "\x00\x01"} {
+		_, err := InferJSONSchema([]byte(payload))
+		assert.Error(t, err, payload)
+	}
+}
+
+func TestPayloadSchema(t *testing.T) {
+	payload := []byte(`{"id": 42}`)
+	inferred, ok := PayloadSchema("payload-schema-json", payload)
+	require.True(t, ok)
+	assert.Equal(t, SchemaTypeJSON, inferred.Type)
+
+	// Confluent Schema Registry framing is skipped.
+	framed, ok := PayloadSchema("payload-schema-json", append([]byte{0, 0, 0, 0, 1}, payload...))
+	require.True(t, ok)
+	assert.Equal(t, inferred, framed)
+
+	_, ok = PayloadSchema("payload-schema-binary", []byte{0x0a, 0x02, 0x34, 0x32})
+	assert.False(t, ok)
+
+	registered, err := AvroSchema(`"string"`)
+	require.NoError(t, err)
+	RegisterTopicSchema("payload-schema-registered", registered)
+	s, ok := PayloadSchema("payload-schema-registered", []byte{0x0a, 0x02, 0x34, 0x32})
+	require.True(t, ok)
+	assert.Equal(t, registered, s)
+}
+
+func TestSchemaSampler(t *testing.T) {
+	var s schemaSampler
+	now := time.Now()
+	assert.Equal(t, int64(1), s.trySample("topic-1", now))
+	assert.Equal(t, int64(0), s.trySample("topic-1", now.Add(time.Second)))
+	assert.Equal(t, int64(0), s.trySample("topic-1", now.Add(2*time.Second)))
+	// Keys are sampled independently.
+	assert.Equal(t, int64(1), s.trySample("topic-2", now.Add(2*time.Second)))
+	// The weight accounts for the payloads that were not sampled.
+	assert.Equal(t, int64(3), s.trySample("topic-1", now.Add(schemaSampleInterval)))
+	assert.Equal(t, int64(0), s.trySample("topic-1", now.Add(schemaSampleInterval+time.Second)))
+}
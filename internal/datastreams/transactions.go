@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package datastreams
+
+import (
+	"encoding/binary"
+	"unicode/utf8"
+)
+
+const (
+	// maxTransactionCheckpoints is the maximum number of distinct checkpoint
+	// names, as checkpoints are identified by a single byte in the payload.
+	maxTransactionCheckpoints = 255
+	// maxTransactionIDLength is the maximum length of a transaction ID and of a
+	// checkpoint name. Longer values are truncated.
+	maxTransactionIDLength = 255
+	// maxTransactionBytesPerBucket caps the size of the transactions recorded
+	// in a single bucket. Transactions exceeding it are dropped.
+	maxTransactionBytesPerBucket = 512 * 1024
+)
+
+// transaction is a transaction ID seen at a checkpoint.
+type transaction struct {
+	id         string
+	checkpoint string
+	timestamp  int64
+}
+
+// transactionCheckpoints assigns a stable single byte ID to each checkpoint
+// name. It is only accessed by the processor goroutine.
+type transactionCheckpoints struct {
+	ids map[string]uint8
+	// encoded holds the [id][name length][name] of all the checkpoints, as
+	// sent in the payload alongside the transactions.
+	encoded []byte
+}
+
+// id returns the ID of the checkpoint name, assigning a new one if needed.
+// It returns false if all the IDs are taken.
+func (c *transactionCheckpoints) id(name string) (uint8, bool) {
+	if id, ok := c.ids[name]; ok {
+		return id, true
+	}
+	if len(c.ids) >= maxTransactionCheckpoints {
+		return 0, false
+	}
+	if c.ids == nil {
+		c.ids = make(map[string]uint8)
+	}
+	// IDs start at 1, 0 being the zero value of a missing checkpoint.
+	id := uint8(len(c.ids) + 1)
+	c.ids[name] = id
+	c.encoded = append(c.encoded, id, uint8(len(name)))
+	c.encoded = append(c.encoded, name...)
+	return id, true
+}
+
+// appendTransaction appends the [checkpoint id][timestamp][id length][id]
+// encoding of a transaction to b.
+func appendTransaction(b []byte, checkpointID uint8, timestamp int64, id string) []byte {
+	b = append(b, checkpointID)
+	b = binary.BigEndian.AppendUint64(b, uint64(timestamp))
+	b = append(b, uint8(len(id)))
+	return append(b, id...)
+}
+
+// truncateTransactionField truncates s to maxTransactionIDLength bytes,
+// without splitting a UTF-8 character.
+func truncateTransactionField(s string) string {
+	if len(s) <= maxTransactionIDLength {
+		return s
+	}
+	n := maxTransactionIDLength
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
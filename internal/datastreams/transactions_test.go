@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package datastreams
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestTransactions(t *testing.T) {
+	p := NewProcessor(nil, "env", "service", "v1", &url.URL{Scheme: "http", Host: "agent-address"}, nil)
+	now := time.Now().Truncate(bucketDuration)
+	p.timeSource = func() time.Time { return now }
+
+	p.TrackTransaction("order-1", "validated")
+	p.TrackTransaction("order-2", "validated")
+	p.TrackTransaction("order-1", "shipped")
+	p.TrackTransaction(strings.Repeat("x", 300), "shipped")
+	p.flushInput()
+
+	sp := p.flush(now.Add(bucketDuration))
+	require.Len(t, sp.Stats, 1)
+	var expected []byte
+	expected = appendTransaction(expected, 1, now.UnixNano(), "order-1")
+	expected = appendTransaction(expected, 1, now.UnixNano(), "order-2")
+	expected = appendTransaction(expected, 2, now.UnixNano(), "order-1")
+	expected = appendTransaction(expected, 2, now.UnixNano(), strings.Repeat("x", 255))
+	assert.Equal(t, expected, sp.Stats[0].Transactions)
+	assert.Equal(t, []byte("\x01\x09validated\x02\x07shipped"), sp.Stats[0].TransactionCheckpointIds)
+
+	// Checkpoint IDs are stable across buckets.
+	p.TrackTransaction("order-3", "shipped")
+	p.flushInput()
+	sp = p.flush(now.Add(bucketDuration))
+	require.Len(t, sp.Stats, 1)
+	assert.Equal(t, appendTransaction(nil, 2, now.UnixNano(), "order-3"), sp.Stats[0].Transactions)
+	assert.Equal(t, []byte("\x01\x09validated\x02\x07shipped"), sp.Stats[0].TransactionCheckpointIds)
+}
+
+func TestTransactionsLimits(t *testing.T) {
+	t.Run("checkpoints", func(t *testing.T) {
+		p := NewProcessor(nil, "env", "service", "v1", &url.URL{Scheme: "http", Host: "agent-address"}, nil)
+		for i := 0; i < maxTransactionCheckpoints+10; i++ {
+			p.addTransaction(transaction{id: "id", checkpoint: fmt.Sprintf("checkpoint-%d", i), timestamp: 1})
+		}
+		assert.Len(t, p.checkpoints.ids, maxTransactionCheckpoints)
+		assert.Equal(t, int64(10), p.stats.droppedTransactions)
+	})
+
+	t.Run("bucket", func(t *testing.T) {
+		p := NewProcessor(nil, "env", "service", "v1", &url.URL{Scheme: "http", Host: "agent-address"}, nil)
+		id := strings.Repeat("x", maxTransactionIDLength)
+		n := 0
+		for ; p.stats.droppedTransactions == 0; n++ {
+			p.addTransaction(transaction{id: id, checkpoint: "checkpoint", timestamp: 1})
+		}
+		b := p.tsTypeCurrentBuckets[0]
+		assert.Less(t, len(b.transactions), maxTransactionBytesPerBucket+1+8+1+maxTransactionIDLength)
+		assert.Equal(t, len(b.transactions), (n-1)*(1+8+1+maxTransactionIDLength))
+	})
+}
+
+func TestTruncateTransactionField(t *testing.T) {
+	assert.Equal(t, "order-1", truncateTransactionField("order-1"))
+	assert.Equal(t, strings.Repeat("x", 255), truncateTransactionField(strings.Repeat("x", 300)))
+	// "é" is encoded on 2 bytes, the last one not fitting in the limit
+	s := truncateTransactionField(strings.Repeat("x", 254) + "é")
+	assert.Equal(t, strings.Repeat("x", 254), s)
+	assert.True(t, utf8.ValidString(s))
+}
+
+func TestStatsBucketTransactionsEncoding(t *testing.T) {
+	in := StatsBucket{
+		Start:                    1,
+		Duration:                 2,
+		Transactions:             appendTransaction(nil, 1, 3, "order-1"),
+		TransactionCheckpointIds: []byte("\x01\x09validated"),
+	}
+	var buf bytes.Buffer
+	require.NoError(t, msgp.Encode(&buf, &in))
+	assert.LessOrEqual(t, buf.Len(), in.Msgsize())
+	var out StatsBucket
+	require.NoError(t, msgp.Decode(&buf, &out))
+	assert.Equal(t, in, out)
+}
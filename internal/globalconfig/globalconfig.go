@@ -30,6 +30,11 @@ type config struct {
 	headersAsTags *internal.LockMap
 	dogstatsdAddr string
 	statsTags     []string
+	// tracerEnv and tracerVersion are the environment and version of the running tracer, if
+	// tracerRunning is true.
+	tracerEnv     string
+	tracerVersion string
+	tracerRunning bool
 }
 
 // AnalyticsRate returns the sampling rate at which events should be marked. It uses
@@ -62,6 +67,28 @@ func SetServiceName(name string) {
 	cfg.serviceName = name
 }
 
+// TracerEnvVersion returns the environment and version of the running tracer. It reports false
+// if no tracer is running.
+func TracerEnvVersion() (env, version string, ok bool) {
+	cfg.mu.RLock()
+	defer cfg.mu.RUnlock()
+	return cfg.tracerEnv, cfg.tracerVersion, cfg.tracerRunning
+}
+
+// SetTracerEnvVersion sets the environment and version of the running tracer.
+func SetTracerEnvVersion(env, version string) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.tracerEnv, cfg.tracerVersion, cfg.tracerRunning = env, version, true
+}
+
+// ClearTracerEnvVersion clears the environment and version of the tracer once it is stopped.
+func ClearTracerEnvVersion() {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.tracerEnv, cfg.tracerVersion, cfg.tracerRunning = "", "", false
+}
+
 // DogstatsdAddr returns the destination for tracer and contrib statsd clients
 func DogstatsdAddr() string {
 	cfg.mu.RLock()
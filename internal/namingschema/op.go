@@ -27,6 +27,9 @@ const (
 	KafkaInbound
 	GCPPubSubInbound
 	GCPPubSubOutbound
+	NATSInbound
+	NATSOutbound
+	NATSRequest
 
 	// cache
 	MemcachedOutbound
@@ -69,6 +72,12 @@ func opV1(t IntegrationType) string {
 		return "gcp.pubsub.process"
 	case GCPPubSubOutbound:
 		return "gcp.pubsub.send"
+	case NATSInbound:
+		return "nats.process"
+	case NATSOutbound:
+		return "nats.send"
+	case NATSRequest:
+		return "nats.client.request"
 
 	// Cache
 	case MemcachedOutbound:
@@ -117,6 +126,12 @@ func opV0(t IntegrationType) string {
 		return "pubsub.receive"
 	case GCPPubSubOutbound:
 		return "pubsub.publish"
+	case NATSInbound:
+		return "nats.consume"
+	case NATSOutbound:
+		return "nats.publish"
+	case NATSRequest:
+		return "nats.request"
 	case MemcachedOutbound:
 		return "memcached.query"
 	case RedisOutbound:
@@ -54,6 +54,30 @@ func TestOpName(t *testing.T) {
 			wantV0: "pubsub.receive",
 			wantV1: "gcp.pubsub.process",
 		},
+		{
+			name: "nats outbound",
+			newSchema: func() string {
+				return namingschema.OpName(namingschema.NATSOutbound)
+			},
+			wantV0: "nats.publish",
+			wantV1: "nats.send",
+		},
+		{
+			name: "nats inbound",
+			newSchema: func() string {
+				return namingschema.OpName(namingschema.NATSInbound)
+			},
+			wantV0: "nats.consume",
+			wantV1: "nats.process",
+		},
+		{
+			name: "nats request",
+			newSchema: func() string {
+				return namingschema.OpName(namingschema.NATSRequest)
+			},
+			wantV0: "nats.request",
+			wantV1: "nats.client.request",
+		},
 		{
 			name: "override",
 			newSchema: func() string {
@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package traceprof
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// SpanInfo describes a span to a SpanObserver.
+type SpanInfo struct {
+	SpanID   uint64
+	TraceID  uint64
+	Service  string
+	Resource string
+	Start    time.Time
+	// Duration and Error are only set when the span finishes.
+	Duration time.Duration
+	Error    bool
+}
+
+// SpanObserver is notified by the tracer when spans start and finish, e.g. to
+// record an execution trace when a span is slow. Its methods are called
+// synchronously by the tracer and must not block.
+type SpanObserver interface {
+	SpanStarted(SpanInfo)
+	// SpanResourceChanged is called when the resource of a span is set,
+	// which may happen after the span started, or before it starts when
+	// the resource is a start option.
+	SpanResourceChanged(SpanInfo)
+	SpanFinished(SpanInfo)
+}
+
+var spanObserver atomic.Pointer[SpanObserver]
+
+// SetSpanObserver sets the observer notified of the spans by the tracer, or
+// removes it if o is nil.
+func SetSpanObserver(o SpanObserver) {
+	if o == nil {
+		spanObserver.Store(nil)
+		return
+	}
+	spanObserver.Store(&o)
+}
+
+// GetSpanObserver returns the observer notified of the spans by the tracer,
+// nil if there is none.
+func GetSpanObserver() SpanObserver {
+	if o := spanObserver.Load(); o != nil {
+		return *o
+	}
+	return nil
+}
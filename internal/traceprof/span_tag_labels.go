@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package traceprof
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// SpanTagLabelOverflow is the pprof label value used in place of the values of
+// a span tag beyond its cardinality limit.
+const SpanTagLabelOverflow = "_other"
+
+// SpanTagLabels are the span tags applied by the tracer as pprof labels, so
+// that profiles can be broken down by them. The number of distinct values of
+// each tag is bounded to limit the cardinality of the labels.
+type SpanTagLabels struct {
+	keys      []string
+	maxValues int
+
+	mu     sync.RWMutex
+	values map[string]map[string]struct{} // values are the label values seen per key
+}
+
+var spanTagLabels atomic.Pointer[SpanTagLabels]
+
+// SetSpanTagLabels configures the span tags applied as pprof labels, with at
+// most maxValues distinct values per tag, or none if keys is empty.
+func SetSpanTagLabels(keys []string, maxValues int) {
+	if len(keys) == 0 {
+		spanTagLabels.Store(nil)
+		return
+	}
+	values := make(map[string]map[string]struct{}, len(keys))
+	for _, k := range keys {
+		values[k] = make(map[string]struct{})
+	}
+	spanTagLabels.Store(&SpanTagLabels{
+		keys:      append([]string(nil), keys...),
+		maxValues: maxValues,
+		values:    values,
+	})
+}
+
+// GetSpanTagLabels returns the span tags applied as pprof labels, nil if
+// there are none.
+func GetSpanTagLabels() *SpanTagLabels {
+	return spanTagLabels.Load()
+}
+
+// Keys returns the span tags applied as pprof labels. It must not be modified.
+func (l *SpanTagLabels) Keys() []string {
+	return l.keys
+}
+
+// Has returns true if the span tag key is applied as a pprof label.
+func (l *SpanTagLabels) Has(key string) bool {
+	_, ok := l.values[key]
+	return ok
+}
+
+// Value returns the pprof label value of the span tag, which is
+// SpanTagLabelOverflow when the tag exceeded its cardinality limit.
+func (l *SpanTagLabels) Value(key, value string) string {
+	l.mu.RLock()
+	_, seen := l.values[key][value]
+	l.mu.RUnlock()
+	if seen {
+		return value
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	values, ok := l.values[key]
+	if !ok {
+		return value
+	}
+	if _, seen := values[value]; seen {
+		return value
+	}
+	if len(values) >= l.maxValues {
+		return SpanTagLabelOverflow
+	}
+	values[value] = struct{}{}
+	return value
+}
@@ -0,0 +1,37 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package traceprof
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanTagLabels(t *testing.T) {
+	t.Cleanup(func() { SetSpanTagLabels(nil, 0) })
+
+	SetSpanTagLabels(nil, 10)
+	require.Nil(t, GetSpanTagLabels())
+
+	SetSpanTagLabels([]string{"tenant", "tier"}, 2)
+	l := GetSpanTagLabels()
+	require.NotNil(t, l)
+	assert.Equal(t, []string{"tenant", "tier"}, l.Keys())
+	assert.True(t, l.Has("tier"))
+	assert.False(t, l.Has("http.route"))
+
+	assert.Equal(t, "a", l.Value("tenant", "a"))
+	assert.Equal(t, "b", l.Value("tenant", "b"))
+	// Beyond the limit, only the values already seen are kept
+	assert.Equal(t, SpanTagLabelOverflow, l.Value("tenant", "c"))
+	assert.Equal(t, "a", l.Value("tenant", "a"))
+	// The limit is per key
+	assert.Equal(t, "c", l.Value("tier", "c"))
+	// Keys which are not configured are not limited
+	assert.Equal(t, "c", l.Value("http.route", "c"))
+}
@@ -0,0 +1,386 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package profiler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+var (
+	errNotRunning        = errors.New("profiler is not running")
+	errCaptureInProgress = errors.New("an on-demand capture is already in progress")
+)
+
+// defaultCaptureDuration is the duration of the captures triggered by
+// CaptureHandler when not specified, matching net/http/pprof.
+const defaultCaptureDuration = 30 * time.Second
+
+// Capture holds the profiles collected by CaptureNow.
+type Capture struct {
+	// Start and End delimit the capture.
+	Start, End time.Time
+	// Reason is the reason given with WithCaptureReason.
+	Reason string
+	// Profiles are the captured profiles.
+	Profiles []ExportedProfile
+}
+
+// CaptureOption configures CaptureNow.
+type CaptureOption func(*captureConfig)
+
+type captureConfig struct {
+	reason         string
+	executionTrace bool
+}
+
+// WithCaptureReason sets the reason of the capture, e.g. an incident
+// identifier. It is attached to the uploaded profiles with the
+// "capture_reason" tag.
+func WithCaptureReason(reason string) CaptureOption {
+	return func(cfg *captureConfig) {
+		cfg.reason = reason
+	}
+}
+
+// WithCaptureExecutionTrace records a runtime execution trace during the
+// capture, in addition to the requested profile types. The trace size is
+// bounded like the traces of the profiling cycle.
+func WithCaptureExecutionTrace() CaptureOption {
+	return func(cfg *captureConfig) {
+		cfg.executionTrace = true
+	}
+}
+
+// CaptureNow immediately captures the given profile types for the given
+// duration, out of band of the regular profiling cycle of the running
+// profiler. The CPU profile reports the CPU usage during the capture, the
+// heap, block and mutex profiles report the events which happened during the
+// capture, when delta profiles are enabled, and the goroutine profile is a
+// snapshot taken at the end of the capture.
+//
+// The captured profiles are uploaded like the profiles of the regular
+// profiling cycle, tagged with the reason of the capture, and returned. Only
+// one capture can run at a time. Capturing a CPU profile cuts short the CPU
+// profile of the ongoing profiling cycle, as the Go runtime only supports one
+// CPU profile at a time.
+//
+// The capture is canceled when ctx is done or when the profiler is stopped.
+// If some profile types could not be captured, the other profiles are still
+// returned along with an error.
+func CaptureNow(ctx context.Context, types []ProfileType, duration time.Duration, opts ...CaptureOption) (*Capture, error) {
+	mu.Lock()
+	p := activeProfiler
+	mu.Unlock()
+	if p == nil {
+		return nil, errNotRunning
+	}
+	var cfg captureConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return p.capture(ctx, types, duration, cfg)
+}
+
+func (p *profiler) capture(ctx context.Context, types []ProfileType, duration time.Duration, cfg captureConfig) (*Capture, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("invalid capture duration %s", duration)
+	}
+	if len(types) == 0 && !cfg.executionTrace {
+		return nil, errors.New("no profile type to capture")
+	}
+	for _, t := range types {
+		if _, ok := captureTypes[t]; !ok {
+			return nil, fmt.Errorf("profile type %s can't be captured on demand", t)
+		}
+	}
+	if !p.capturing.CompareAndSwap(false, true) {
+		return nil, errCaptureInProgress
+	}
+	defer p.capturing.Store(false)
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.exit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if cfg.executionTrace {
+		types = append(slices.Clip(types), executionTrace)
+	}
+	c := &Capture{Start: now(), Reason: cfg.reason}
+	var (
+		wg      sync.WaitGroup
+		results = make([]*profile, len(types))
+		errs    = make([]error, len(types))
+	)
+	for i, t := range types {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = captureTypes[t](p, ctx)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("capturing %s profile: %w", t, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	c.End = now()
+	select {
+	case <-p.exit:
+		return nil, errProfilerStopped
+	default:
+	}
+
+	bat := batch{
+		seq:   p.seq.Add(1) - 1,
+		host:  p.cfg.hostname,
+		start: c.Start,
+		end:   c.End,
+		extraTags: []string{
+			"_dd.profiler.on_demand:true",
+			pgoTag(),
+		},
+		customAttributes: p.cfg.customProfilerLabels,
+	}
+	if cfg.reason != "" {
+		// Commas separate the tags of the uploaded profiles
+		bat.extraTags = append(bat.extraTags, "capture_reason:"+strings.ReplaceAll(cfg.reason, ",", "_"))
+	}
+	for _, prof := range results {
+		if prof == nil {
+			continue
+		}
+		if prof.pt == executionTrace {
+			bat.extraTags = append(bat.extraTags, "go_execution_traced:yes")
+		}
+		bat.addProfile(prof)
+		c.Profiles = append(c.Profiles, ExportedProfile{Name: prof.name, Data: prof.data})
+	}
+	err := errors.Join(errs...)
+	if err != nil {
+		log.Error("On-demand profile capture: %v", err)
+	}
+	if len(c.Profiles) == 0 {
+		return nil, err
+	}
+	p.enqueueUpload(bat)
+	return c, err
+}
+
+// captureTypes maps the profile types which can be captured on demand to
+// their implementation.
+var captureTypes = map[ProfileType]func(p *profiler, ctx context.Context) (*profile, error){
+	CPUProfile:       (*profiler).captureCPU,
+	HeapProfile:      captureGenericProfile(HeapProfile),
+	BlockProfile:     captureGenericProfile(BlockProfile),
+	MutexProfile:     captureGenericProfile(MutexProfile),
+	GoroutineProfile: captureGenericProfile(GoroutineProfile),
+	executionTrace:   (*profiler).captureExecutionTrace,
+}
+
+// captureCPU profiles the CPU until ctx is done. The CPU profile of the
+// profiling cycle is preempted if it is running.
+func (p *profiler) captureCPU(ctx context.Context) (*profile, error) {
+	if !p.cpuMu.TryLock() {
+		select {
+		case p.cpuPreempt <- struct{}{}:
+		default:
+		}
+		p.cpuMu.Lock()
+	}
+	defer p.cpuMu.Unlock()
+	// Discard the preemption request if the profiling cycle stopped its CPU
+	// profile before receiving it, so that it doesn't preempt the next one.
+	select {
+	case <-p.cpuPreempt:
+	default:
+	}
+
+	if p.cfg.cpuProfileRate != 0 {
+		runtime.SetCPUProfileRate(p.cfg.cpuProfileRate)
+	}
+	var buf bytes.Buffer
+	if err := p.startCPUProfile(&buf); err != nil {
+		return nil, err
+	}
+	<-ctx.Done()
+	p.stopCPUProfile()
+	return &profile{name: CPUProfile.Filename(), pt: CPUProfile, data: buf.Bytes()}, nil
+}
+
+// captureGenericProfile returns the capture implementation of a profile type
+// looked up by name. Profile types supporting delta profiles are diffed with
+// a dedicated delta computer, leaving the state of the profiling cycle's
+// delta computation untouched.
+func captureGenericProfile(pt ProfileType) func(p *profiler, ctx context.Context) (*profile, error) {
+	return func(p *profiler, ctx context.Context) (*profile, error) {
+		t := pt.lookup()
+		var dp *fastDeltaProfiler
+		if p.cfg.deltaProfiles && len(t.DeltaValues) > 0 {
+			dp = newFastDeltaProfiler(t.DeltaValues...)
+			var buf bytes.Buffer
+			if err := p.lookupProfile(t.Name, &buf, 0); err != nil {
+				return nil, err
+			}
+			if _, err := dp.Delta(buf.Bytes()); err != nil {
+				return nil, fmt.Errorf("delta profile error: %s", err)
+			}
+		}
+		<-ctx.Done()
+
+		var buf bytes.Buffer
+		if err := p.lookupProfile(t.Name, &buf, 0); err != nil {
+			return nil, err
+		}
+		if dp == nil {
+			return &profile{name: t.Filename, pt: pt, data: buf.Bytes()}, nil
+		}
+		delta, err := dp.Delta(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("delta profile error: %s", err)
+		}
+		return &profile{name: "delta-" + t.Filename, pt: pt, data: delta}, nil
+	}
+}
+
+// captureExecutionTrace records an execution trace until ctx is done or the
+// trace size limit is exceeded. It fails if an execution trace is already
+// being recorded.
+func (p *profiler) captureExecutionTrace(ctx context.Context) (*profile, error) {
+	buf := new(bytes.Buffer)
+	lt := newLimitedTraceCollector(buf, int64(p.cfg.traceConfig.Limit))
+	stop, err := p.startExecutionTrace(lt)
+	if err != nil {
+		return nil, err
+	}
+	traceLogCPUProfileRate(p.cfg.cpuProfileRate)
+	select {
+	case <-ctx.Done():
+	case <-lt.done:
+	}
+	stop()
+	return &profile{name: executionTrace.Filename(), pt: executionTrace, data: buf.Bytes()}, nil
+}
+
+// CaptureHandler returns an HTTP handler triggering on-demand captures with
+// CaptureNow. The capture is configured with the following query parameters:
+//
+//   - types: comma-separated profile types among cpu, heap, block, mutex,
+//     goroutine and trace (execution trace). Defaults to cpu.
+//   - seconds: duration of the capture. Defaults to 30 seconds.
+//   - reason: reason of the capture, attached to the uploaded profiles.
+//
+// The response is the captured profile, or a zip archive of the captured
+// profiles when several are requested. The handler should only be exposed to
+// trusted clients, e.g. on an internal debugging port.
+func CaptureHandler() http.Handler {
+	return http.HandlerFunc(serveCapture)
+}
+
+func serveCapture(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	duration := defaultCaptureDuration
+	if v := query.Get("seconds"); v != "" {
+		sec, err := strconv.ParseFloat(v, 64)
+		if err != nil || sec <= 0 {
+			http.Error(w, "invalid seconds parameter", http.StatusBadRequest)
+			return
+		}
+		duration = time.Duration(sec * float64(time.Second))
+	}
+	opts := []CaptureOption{WithCaptureReason(query.Get("reason"))}
+	var types []ProfileType
+	names := query.Get("types")
+	if names == "" {
+		names = CPUProfile.String()
+	}
+	for _, name := range strings.Split(names, ",") {
+		t, ok := captureTypeByName(strings.TrimSpace(name))
+		if !ok {
+			http.Error(w, fmt.Sprintf("unsupported profile type %q", name), http.StatusBadRequest)
+			return
+		}
+		if t == executionTrace {
+			opts = append(opts, WithCaptureExecutionTrace())
+			continue
+		}
+		types = append(types, t)
+	}
+
+	c, err := CaptureNow(r.Context(), types, duration, opts...)
+	switch {
+	case errors.Is(err, errNotRunning):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, errCaptureInProgress):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case c == nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if len(c.Profiles) == 1 {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", c.Profiles[0].Name))
+		w.Write(c.Profiles[0].Data)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="profiles.zip"`)
+	zw := zip.NewWriter(w)
+	for _, prof := range c.Profiles {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: prof.Name, Method: zip.Store, Modified: c.End})
+		if err != nil {
+			log.Error("On-demand profile capture: writing the response: %v", err)
+			return
+		}
+		if _, err := f.Write(prof.Data); err != nil {
+			log.Error("On-demand profile capture: writing the response: %v", err)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Error("On-demand profile capture: writing the response: %v", err)
+	}
+}
+
+// captureTypeByName returns the profile type which can be captured on demand
+// with the given name.
+func captureTypeByName(name string) (ProfileType, bool) {
+	if name == "trace" {
+		return executionTrace, true
+	}
+	for t := range captureTypes {
+		if t != executionTrace && t.String() == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,127 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package profiler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptureNow(t *testing.T) {
+	t.Run("not-running", func(t *testing.T) {
+		_, err := CaptureNow(context.Background(), []ProfileType{HeapProfile}, time.Millisecond)
+		require.ErrorIs(t, err, errNotRunning)
+	})
+
+	t.Run("upload", func(t *testing.T) {
+		// The long period makes sure the uploaded profiles come from the
+		// capture, and that the CPU profile of the profiling cycle is
+		// running when the capture starts.
+		profiles := startTestProfiler(t, 1,
+			WithProfileTypes(CPUProfile, HeapProfile),
+			WithPeriod(time.Hour),
+		)
+
+		start := time.Now()
+		c, err := CaptureNow(context.Background(), []ProfileType{CPUProfile, HeapProfile}, 100*time.Millisecond, WithCaptureReason("incident,42"))
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), 10*time.Second)
+		assert.Equal(t, "incident,42", c.Reason)
+		assert.False(t, c.End.Before(c.Start.Add(100*time.Millisecond)))
+		var names []string
+		for _, prof := range c.Profiles {
+			names = append(names, prof.Name)
+			assert.NotEmpty(t, prof.Data)
+		}
+		assert.Equal(t, []string{"cpu.pprof", "delta-heap.pprof"}, names)
+
+		profile := <-profiles
+		assert.Contains(t, profile.tags, "_dd.profiler.on_demand:true")
+		assert.Contains(t, profile.tags, "capture_reason:incident_42")
+		assert.Equal(t, c.Profiles[0].Data, profile.attachments["cpu.pprof"])
+		assert.Equal(t, c.Profiles[1].Data, profile.attachments["delta-heap.pprof"])
+	})
+
+	t.Run("in-progress", func(t *testing.T) {
+		startTestProfiler(t, 1, WithProfileTypes(HeapProfile), WithPeriod(time.Hour))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			CaptureNow(context.Background(), []ProfileType{GoroutineProfile}, time.Second)
+		}()
+		require.Eventually(t, activeProfiler.capturing.Load, time.Second, time.Millisecond)
+		_, err := CaptureNow(context.Background(), []ProfileType{GoroutineProfile}, time.Millisecond)
+		require.ErrorIs(t, err, errCaptureInProgress)
+		<-done
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		startTestProfiler(t, 1, WithProfileTypes(HeapProfile), WithPeriod(time.Hour))
+
+		_, err := CaptureNow(context.Background(), []ProfileType{MetricsProfile}, time.Millisecond)
+		require.Error(t, err)
+		_, err = CaptureNow(context.Background(), nil, time.Millisecond)
+		require.Error(t, err)
+		_, err = CaptureNow(context.Background(), []ProfileType{HeapProfile}, 0)
+		require.Error(t, err)
+	})
+}
+
+func TestCaptureHandler(t *testing.T) {
+	serve := func(url string) *http.Response {
+		rec := httptest.NewRecorder()
+		CaptureHandler().ServeHTTP(rec, httptest.NewRequest("POST", url, nil))
+		return rec.Result()
+	}
+
+	t.Run("not-running", func(t *testing.T) {
+		res := serve("/?types=heap&seconds=0.01")
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	})
+
+	startTestProfiler(t, 2, WithProfileTypes(HeapProfile), WithPeriod(time.Hour))
+
+	t.Run("single", func(t *testing.T) {
+		res := serve("/?types=goroutine&seconds=0.01&reason=test")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, `attachment; filename="goroutines.pprof"`, res.Header.Get("Content-Disposition"))
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		assert.True(t, isGzipData(data))
+	})
+
+	t.Run("multiple", func(t *testing.T) {
+		res := serve("/?types=heap,goroutine&seconds=0.01")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/zip", res.Header.Get("Content-Type"))
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		assert.ElementsMatch(t, []string{"delta-heap.pprof", "goroutines.pprof"}, names)
+	})
+
+	t.Run("bad-request", func(t *testing.T) {
+		for _, url := range []string{"/?types=metrics", "/?types=heap&seconds=-1", "/?seconds=abc"} {
+			res := serve(url)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, url)
+		}
+	})
+}
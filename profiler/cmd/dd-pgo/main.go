@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+// dd-pgo generates a profile for profile-guided optimization from the CPU
+// profiles exported to a local directory with profiler.NewDirectoryExporter.
+//
+// Usage:
+//
+//	dd-pgo -dir /var/lib/profiles -since 24h -o ./cmd/server/default.pgo
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler/pgo"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "dd-pgo: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	var (
+		fs    = flag.NewFlagSet("dd-pgo", flag.ContinueOnError)
+		dir   = fs.String("dir", "", "directory of the exported profiles (required)")
+		out   = fs.String("o", "default.pgo", "output file")
+		since = fs.Duration("since", 0, "only merge the profiles of the given last duration, e.g. 24h")
+		start = fs.String("start", "", "only merge the profiles after the given RFC 3339 time")
+		end   = fs.String("end", "", "only merge the profiles before the given RFC 3339 time")
+		prune = fs.Bool("prune-tracer", false, "prune the samples of the goroutines of dd-trace-go")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *dir == "" {
+		fs.Usage()
+		return fmt.Errorf("missing -dir")
+	}
+
+	cfg := pgo.Config{PruneTracer: *prune}
+	if *since > 0 {
+		cfg.Start = time.Now().Add(-*since)
+	}
+	for _, v := range []struct {
+		s string
+		t *time.Time
+	}{{*start, &cfg.Start}, {*end, &cfg.End}} {
+		if v.s == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v.s)
+		if err != nil {
+			return err
+		}
+		*v.t = t
+	}
+
+	// Generate the profile in memory first to avoid leaving a truncated file
+	// behind on error.
+	var buf bytes.Buffer
+	if err := pgo.Generate(&buf, *dir, cfg); err != nil {
+		return err
+	}
+	return os.WriteFile(*out, buf.Bytes(), 0644)
+}
@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+// dd-pprof-diff compares two pprof profiles, typically collected from two
+// versions of a service, and reports the functions whose allocations, live
+// heap or CPU usage changed.
+//
+// Usage:
+//
+//	dd-pprof-diff -threshold 0.1 -fail base.pprof target.pprof
+//
+// With -fail, the exit status is 3 when regressions are found, so that the
+// command can gate deployments.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler/pprofdiff"
+)
+
+// errRegression is returned when regressions are found with -fail.
+var errRegression = errors.New("regressions found")
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	switch {
+	case errors.Is(err, errRegression):
+		os.Exit(3)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "dd-pprof-diff: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	var (
+		fs          = flag.NewFlagSet("dd-pprof-diff", flag.ContinueOnError)
+		format      = fs.String("format", "text", "output format: text or json")
+		sampleTypes = fs.String("sample-types", "", "comma-separated sample types to compare (default: alloc_space, inuse_space and cpu when present)")
+		threshold   = fs.Float64("threshold", 0.1, "relative increase of the flat value of a function reported as a regression")
+		minDelta    = fs.Int64("min-delta", 0, "absolute increase of the flat value of a function below which it is not a regression")
+		normalize   = fs.Bool("normalize", false, "scale the target profile to the totals of the base profile")
+		top         = fs.Int("top", 20, "maximum number of functions listed per sample type in the text format, 0 for all")
+		fail        = fs.Bool("fail", false, "exit with status 3 when regressions are found")
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: dd-pprof-diff [flags] <base profile> <target profile>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("expected a base and a target profile")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	var types []string
+	if *sampleTypes != "" {
+		for _, st := range strings.Split(*sampleTypes, ",") {
+			if st = strings.TrimSpace(st); st != "" {
+				types = append(types, st)
+			}
+		}
+		if len(types) == 0 {
+			return fmt.Errorf("invalid sample types %q", *sampleTypes)
+		}
+	}
+
+	base, err := pprofdiff.ReadProfile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	target, err := pprofdiff.ReadProfile(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	r, err := pprofdiff.Diff(base, target, pprofdiff.Options{
+		SampleTypes: types,
+		Threshold:   *threshold,
+		MinDelta:    *minDelta,
+		Normalize:   *normalize,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		err = r.WriteJSON(stdout)
+	} else {
+		err = r.WriteText(stdout, *top)
+	}
+	if err != nil {
+		return err
+	}
+	if *fail && len(r.Regressions()) > 0 {
+		return errRegression
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// uploadCompression is the compression of the pprof profiles uploaded to
+// Datadog, see WithUploadCompression.
+type uploadCompression string
+
+const (
+	// gzipCompression uploads the profiles as compressed by the Go runtime.
+	gzipCompression uploadCompression = "gzip"
+	// zstdCompression recompresses the profiles with zstd, which is usually
+	// smaller and faster to decompress.
+	zstdCompression uploadCompression = "zstd"
+)
+
+// zstdEncoder is shared by the uploads, EncodeAll being safe for concurrent
+// use.
+var zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+	return zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+})
+
+// isGzip returns true if data starts with the gzip magic number, like the
+// pprof profiles.
+func isGzip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
+
+// compress returns the gzipped pprof profile data with the compression c.
+// Other data is returned as is.
+func (c uploadCompression) compress(data []byte) ([]byte, error) {
+	if c != zstdCompression || !isGzip(data) {
+		return data, nil
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	raw, err := io.ReadAll(gr)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing gzip: %w", err)
+	}
+	enc, err := zstdEncoder()
+	if err != nil {
+		return nil, err
+	}
+	return enc.EncodeAll(raw, make([]byte, 0, len(data))), nil
+}
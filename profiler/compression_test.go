@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipData(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	_, err := gw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+func TestUploadCompression(t *testing.T) {
+	raw := bytes.Repeat([]byte("pprof data "), 1000)
+	gzipped := gzipData(t, raw)
+
+	data, err := gzipCompression.compress(gzipped)
+	require.NoError(t, err)
+	assert.Equal(t, gzipped, data)
+
+	data, err = zstdCompression.compress(gzipped)
+	require.NoError(t, err)
+	dec, err := zstd.NewReader(nil)
+	require.NoError(t, err)
+	defer dec.Close()
+	decoded, err := dec.DecodeAll(data, nil)
+	require.NoError(t, err)
+	assert.Equal(t, raw, decoded)
+
+	// Only the gzipped profiles are recompressed
+	data, err = zstdCompression.compress([]byte(`{"metrics":[]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []byte(`{"metrics":[]}`), data)
+}
@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package profiler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	pprofile "github.com/google/pprof/profile"
+)
+
+const (
+	// prunedSamplesFunction is the function of the sample aggregating the
+	// samples removed by downsampleProfile.
+	prunedSamplesFunction = "[pruned samples]"
+	// maxDownsampleAttempts bounds the number of times a profile is
+	// downsampled and encoded to fit within its size limit.
+	maxDownsampleAttempts = 4
+)
+
+var errProfileTooLarge = errors.New("profile exceeds its size limit")
+
+// downsampleProfile removes the lowest-weight samples of the pprof profile
+// data, whose size is size bytes once compressed with c, until it fits within
+// limit bytes. The values of
+// the removed samples are aggregated into a single sample, so the totals of
+// the profile are unchanged. The weight of a sample is its value of the
+// default sample type, e.g. the in-use space of heap profiles or the number
+// of goroutines of goroutine profiles.
+func downsampleProfile(data []byte, size, limit int, c uploadCompression) ([]byte, error) {
+	p, err := pprofile.ParseData(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing profile: %w", err)
+	}
+	if len(p.SampleType) == 0 {
+		return nil, errProfileTooLarge
+	}
+	idx := len(p.SampleType) - 1
+	for i, st := range p.SampleType {
+		if st.Type == p.DefaultSampleType {
+			idx = i
+		}
+	}
+	samples := append([]*pprofile.Sample(nil), p.Sample...)
+	sort.SliceStable(samples, func(i, j int) bool {
+		return abs(samples[i].Value[idx]) > abs(samples[j].Value[idx])
+	})
+
+	keep := len(samples)
+	for attempt := 0; attempt < maxDownsampleAttempts && keep > 0; attempt++ {
+		// Assume the size is proportional to the number of samples, with
+		// some margin as the heaviest samples are kept
+		keep = min(keep-1, int(float64(keep)*float64(limit)/float64(size)*0.9))
+		keep = max(keep, 0)
+		pruned, err := pruneSamples(p, samples, keep, c)
+		if err != nil {
+			return nil, err
+		}
+		if size = len(pruned); size <= limit {
+			return pruned, nil
+		}
+	}
+	return nil, errProfileTooLarge
+}
+
+// pruneSamples encodes p with the first keep samples, and the others
+// aggregated into a single sample, compressed with c.
+func pruneSamples(p *pprofile.Profile, samples []*pprofile.Sample, keep int, c uploadCompression) ([]byte, error) {
+	pruned := p.Copy()
+	pruned.Sample = append([]*pprofile.Sample(nil), samples[:keep]...)
+	if keep < len(samples) {
+		var maxFunction, maxLocation uint64
+		for _, fn := range pruned.Function {
+			maxFunction = max(maxFunction, fn.ID)
+		}
+		for _, loc := range pruned.Location {
+			maxLocation = max(maxLocation, loc.ID)
+		}
+		fn := &pprofile.Function{ID: maxFunction + 1, Name: prunedSamplesFunction}
+		loc := &pprofile.Location{ID: maxLocation + 1, Line: []pprofile.Line{{Function: fn}}}
+		pruned.Function = append(pruned.Function, fn)
+		pruned.Location = append(pruned.Location, loc)
+		aggregate := &pprofile.Sample{Location: []*pprofile.Location{loc}, Value: make([]int64, len(p.SampleType))}
+		for _, s := range samples[keep:] {
+			for i, v := range s.Value {
+				aggregate.Value[i] += v
+			}
+		}
+		pruned.Sample = append(pruned.Sample, aggregate)
+	}
+	// Remove the locations and functions of the removed samples
+	pruned = pruned.Compact()
+	var buf bytes.Buffer
+	if err := pruned.Write(&buf); err != nil {
+		return nil, fmt.Errorf("encoding profile: %w", err)
+	}
+	return c.compress(buf.Bytes())
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package profiler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/statsdtest"
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal/pprofutils"
+
+	pprofile "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// largeHeapProfile returns a gzipped heap profile with n samples of distinct
+// stacks, the i-th sample having i in-use bytes.
+func largeHeapProfile(t *testing.T, n int) []byte {
+	var text strings.Builder
+	text.WriteString("alloc_space/bytes inuse_space/bytes\n")
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&text, "main;handler%d;alloc%d 1 %d\n", i, i, i)
+	}
+	p, err := pprofutils.Text{}.Convert(strings.NewReader(text.String()))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, p.Write(&buf))
+	return buf.Bytes()
+}
+
+func sampleTotals(p *pprofile.Profile) []int64 {
+	totals := make([]int64, len(p.SampleType))
+	for _, s := range p.Sample {
+		for i, v := range s.Value {
+			totals[i] += v
+		}
+	}
+	return totals
+}
+
+func TestDownsampleProfile(t *testing.T) {
+	data := largeHeapProfile(t, 2000)
+	original, err := pprofile.ParseData(data)
+	require.NoError(t, err)
+
+	for _, c := range []uploadCompression{gzipCompression, zstdCompression} {
+		t.Run(string(c), func(t *testing.T) {
+			compressed, err := c.compress(data)
+			require.NoError(t, err)
+			limit := len(compressed) / 3
+			downsampled, err := downsampleProfile(data, len(compressed), limit, c)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(downsampled), limit)
+			if c == zstdCompression {
+				return
+			}
+
+			p, err := pprofile.ParseData(downsampled)
+			require.NoError(t, err)
+			assert.Less(t, len(p.Sample), len(original.Sample))
+			assert.Equal(t, sampleTotals(original), sampleTotals(p))
+			var kept, pruned bool
+			for _, s := range p.Sample {
+				switch s.Location[0].Line[0].Function.Name {
+				case "alloc2000":
+					kept = true
+				case prunedSamplesFunction:
+					pruned = true
+				}
+			}
+			assert.True(t, kept, "the heaviest sample must be kept")
+			assert.True(t, pruned)
+		})
+	}
+
+	t.Run("too-large", func(t *testing.T) {
+		_, err := downsampleProfile(data, len(data), 10, gzipCompression)
+		require.ErrorIs(t, err, errProfileTooLarge)
+	})
+}
+
+func TestPrepareUpload(t *testing.T) {
+	heap := largeHeapProfile(t, 2000)
+	cpu := largeHeapProfile(t, 10)
+	var stats statsdtest.TestStatsdClient
+	p, err := unstartedProfiler(
+		WithStatsd(&stats),
+		WithUploadCompression("zstd"),
+		WithMaxProfileSize(HeapProfile, len(heap)/3),
+		WithMaxProfileSize(GoroutineProfile, 10),
+	)
+	require.NoError(t, err)
+
+	bat := batch{profiles: []*profile{
+		{name: "cpu.pprof", pt: CPUProfile, data: cpu},
+		{name: "delta-heap.pprof", pt: HeapProfile, data: heap},
+		{name: "goroutines.pprof", pt: GoroutineProfile, data: heap},
+		{name: "metrics.json", pt: MetricsProfile, data: []byte("{}")},
+	}}
+	prepared := p.prepareUpload(bat)
+	require.Len(t, prepared.profiles, 3)
+	assert.Equal(t, "cpu.pprof", prepared.profiles[0].name)
+	assert.False(t, isGzip(prepared.profiles[0].data), "must be recompressed with zstd")
+	assert.Equal(t, "delta-heap.pprof", prepared.profiles[1].name)
+	assert.LessOrEqual(t, len(prepared.profiles[1].data), len(heap)/3)
+	assert.Equal(t, []byte("{}"), prepared.profiles[2].data)
+	// The batch profiles are not modified
+	assert.Equal(t, heap, bat.profiles[1].data)
+
+	counts := stats.Counts()
+	assert.Equal(t, int64(1), counts["datadog.profiling.go.downsampled_profiles"])
+	assert.NotZero(t, counts["datadog.profiling.go.dropped_profile_bytes"])
+}
@@ -0,0 +1,244 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package profiler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Exporter exports the profiles collected by the profiler in place of
+// uploading them to the Datadog Agent or intake. It can be configured with
+// WithExporter.
+type Exporter interface {
+	// Export exports a batch of profiles. It is called from a single
+	// goroutine, once per profiling period. The context is canceled when
+	// the upload timeout expires or when the profiler is stopped.
+	Export(ctx context.Context, bat *ExportBatch) error
+}
+
+// ExportBatch is a batch of profiles collected during the same profiling
+// period.
+type ExportBatch struct {
+	// Start and End delimit the profiling period.
+	Start, End time.Time
+	// Seq is the sequence number of the batch, starting at 0 for the first
+	// batch collected by the profiler.
+	Seq uint64
+	// Tags are the tags which would be attached to the profiles when
+	// uploaded to Datadog, in the "key:value" format.
+	Tags []string
+	// Profiles are the collected profiles.
+	Profiles []ExportedProfile
+	// EndpointCounts is the number of hits per endpoint during the period,
+	// when endpoint counting is enabled.
+	EndpointCounts map[string]uint64
+}
+
+// ExportedProfile is a profile in an ExportBatch.
+type ExportedProfile struct {
+	// Name is the file name of the profile, e.g. "cpu.pprof".
+	Name string
+	// Data is the profile in its serialized form, usually a gzipped pprof
+	// protobuf. It must not be modified.
+	Data []byte
+}
+
+// export exports a batch of profiles with the configured exporter.
+func (p *profiler) export(bat batch) error {
+	tags := p.batchTags(bat)
+	if bat.host != "" {
+		tags = append(tags, fmt.Sprintf("host:%s", bat.host))
+	}
+	tags = append(tags, "runtime:go")
+	eb := &ExportBatch{
+		Start:          bat.start,
+		End:            bat.end,
+		Seq:            bat.seq,
+		Tags:           tags,
+		Profiles:       make([]ExportedProfile, 0, len(bat.profiles)),
+		EndpointCounts: bat.endpointCounts,
+	}
+	for _, prof := range bat.profiles {
+		eb.Profiles = append(eb.Profiles, ExportedProfile{Name: prof.name, Data: prof.data})
+	}
+
+	ctx, cancel := p.uploadContext()
+	defer cancel()
+	err := p.cfg.exporter.Export(ctx, eb)
+	if err != nil {
+		p.cfg.statsd.Count("datadog.profiling.go.export_error", 1, nil, 1)
+	} else {
+		p.cfg.statsd.Count("datadog.profiling.go.export_success", 1, nil, 1)
+	}
+	return err
+}
+
+// exportMetadata is the metadata of an ExportBatch stored along with its
+// profiles by the built-in exporters.
+type exportMetadata struct {
+	Start          string            `json:"start"`
+	End            string            `json:"end"`
+	Seq            uint64            `json:"seq"`
+	Tags           []string          `json:"tags"`
+	Attachments    []string          `json:"attachments"`
+	EndpointCounts map[string]uint64 `json:"endpoint_counts,omitempty"`
+}
+
+// exportMetadataFile is the name of the metadata file of each exported batch.
+const exportMetadataFile = "metadata.json"
+
+func newExportMetadata(bat *ExportBatch) ([]byte, error) {
+	md := exportMetadata{
+		Start:          bat.Start.Format(time.RFC3339Nano),
+		End:            bat.End.Format(time.RFC3339Nano),
+		Seq:            bat.Seq,
+		Tags:           bat.Tags,
+		EndpointCounts: bat.EndpointCounts,
+	}
+	for _, prof := range bat.Profiles {
+		md.Attachments = append(md.Attachments, prof.Name)
+	}
+	return json.MarshalIndent(md, "", "  ")
+}
+
+// exportBatchName returns the name under which the profiles of the batch are
+// stored by the built-in exporters. Names sort in the order of the batches.
+func exportBatchName(bat *ExportBatch) string {
+	// Basic ISO 8601 Format in UTC, as used by the output directory.
+	return fmt.Sprintf("%s-%06d", bat.End.UTC().Format("20060102T150405Z"), bat.Seq)
+}
+
+// DirectoryExporter is an Exporter writing each batch of profiles to its own
+// sub-directory of a local directory, as pprof files along with a
+// metadata.json file describing the batch.
+type DirectoryExporter struct {
+	dir        string
+	maxBatches int
+}
+
+// NewDirectoryExporter returns an exporter writing profiles to dir, which is
+// created if needed. When maxBatches is greater than zero, only the
+// maxBatches most recent batches are kept and older ones are removed.
+func NewDirectoryExporter(dir string, maxBatches int) *DirectoryExporter {
+	return &DirectoryExporter{dir: dir, maxBatches: maxBatches}
+}
+
+// Export implements Exporter.
+func (e *DirectoryExporter) Export(_ context.Context, bat *ExportBatch) error {
+	md, err := newExportMetadata(bat)
+	if err != nil {
+		return err
+	}
+	dirPath := filepath.Join(e.dir, exportBatchName(bat))
+	// 0755 is what mkdir does, should be reasonable for the use cases here.
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return err
+	}
+	for _, prof := range bat.Profiles {
+		// 0644 is what touch does, should be reasonable for the use cases here.
+		if err := os.WriteFile(filepath.Join(dirPath, prof.Name), prof.Data, 0644); err != nil {
+			return err
+		}
+	}
+	// The metadata is written last so that its presence indicates a
+	// complete batch.
+	if err := os.WriteFile(filepath.Join(dirPath, exportMetadataFile), md, 0644); err != nil {
+		return err
+	}
+	return e.rotate()
+}
+
+// rotate removes the oldest batches beyond maxBatches.
+func (e *DirectoryExporter) rotate() error {
+	if e.maxBatches <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(e.dir)
+	if err != nil {
+		return err
+	}
+	var batches []string
+	for _, entry := range entries {
+		// Only consider the directories written by the exporter
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(e.dir, entry.Name(), exportMetadataFile)); err != nil {
+			continue
+		}
+		batches = append(batches, entry.Name())
+	}
+	if len(batches) <= e.maxBatches {
+		return nil
+	}
+	sort.Strings(batches)
+	var errs []error
+	for _, name := range batches[:len(batches)-e.maxBatches] {
+		if err := os.RemoveAll(filepath.Join(e.dir, name)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ObjectStoreClient is the client of an S3-compatible object store used by
+// ObjectStoreExporter. It is typically a thin adapter around the client of
+// the object store SDK.
+type ObjectStoreClient interface {
+	// PutObject stores the object of the given size read from body under
+	// key in the bucket.
+	PutObject(ctx context.Context, bucket, key string, body io.Reader, size int64) error
+}
+
+// ObjectStoreExporter is an Exporter storing each batch of profiles in an
+// S3-compatible object store, as pprof objects along with a metadata.json
+// object describing the batch, under the "<prefix>/<batch>/" key prefix.
+type ObjectStoreExporter struct {
+	client ObjectStoreClient
+	bucket string
+	prefix string
+}
+
+// NewObjectStoreExporter returns an exporter storing profiles in the bucket
+// with the given client. The keys of the objects start with prefix, which
+// may be empty.
+func NewObjectStoreExporter(client ObjectStoreClient, bucket, prefix string) *ObjectStoreExporter {
+	return &ObjectStoreExporter{client: client, bucket: bucket, prefix: prefix}
+}
+
+// Export implements Exporter.
+func (e *ObjectStoreExporter) Export(ctx context.Context, bat *ExportBatch) error {
+	md, err := newExportMetadata(bat)
+	if err != nil {
+		return err
+	}
+	base := path.Join(e.prefix, exportBatchName(bat))
+	for _, prof := range bat.Profiles {
+		if err := e.put(ctx, path.Join(base, prof.Name), prof.Data); err != nil {
+			return err
+		}
+	}
+	// The metadata is stored last so that its presence indicates a complete
+	// batch.
+	return e.put(ctx, path.Join(base, exportMetadataFile), md)
+}
+
+func (e *ObjectStoreExporter) put(ctx context.Context, key string, data []byte) error {
+	if err := e.client.PutObject(ctx, e.bucket, key, bytes.NewReader(data), int64(len(data))); err != nil {
+		return fmt.Errorf("storing %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package profiler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type exporterFunc func(ctx context.Context, bat *ExportBatch) error
+
+func (f exporterFunc) Export(ctx context.Context, bat *ExportBatch) error { return f(ctx, bat) }
+
+func TestWithExporter(t *testing.T) {
+	batches := make(chan *ExportBatch, 1)
+	exporter := exporterFunc(func(_ context.Context, bat *ExportBatch) error {
+		select {
+		case batches <- bat:
+		default:
+		}
+		return nil
+	})
+	err := Start(
+		WithExporter(exporter),
+		WithProfileTypes(HeapProfile),
+		WithPeriod(10*time.Millisecond),
+		WithService("my-service"),
+		WithEnv("my-env"),
+		WithHostname("my-host"),
+		// The exporter replaces uploads: this URL must never be used
+		WithAgentAddr("unreachable.invalid:1"),
+	)
+	require.NoError(t, err)
+	defer Stop()
+
+	bat := <-batches
+	assert.False(t, bat.Start.IsZero())
+	assert.True(t, bat.End.After(bat.Start))
+	assert.Contains(t, bat.Tags, "service:my-service")
+	assert.Contains(t, bat.Tags, "env:my-env")
+	assert.Contains(t, bat.Tags, "host:my-host")
+	assert.Contains(t, bat.Tags, "runtime:go")
+	assert.Contains(t, bat.Tags, "profile_seq:0")
+	profiles := map[string][]byte{}
+	for _, prof := range bat.Profiles {
+		profiles[prof.Name] = prof.Data
+	}
+	assert.NotEmpty(t, profiles["delta-heap.pprof"])
+}
+
+func testExportBatch(seq uint64) *ExportBatch {
+	end := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Add(time.Duration(seq) * time.Minute)
+	return &ExportBatch{
+		Start: end.Add(-time.Minute),
+		End:   end,
+		Seq:   seq,
+		Tags:  []string{"service:my-service", "runtime:go"},
+		Profiles: []ExportedProfile{
+			{Name: "cpu.pprof", Data: []byte("cpu")},
+			{Name: "delta-heap.pprof", Data: []byte("heap")},
+		},
+	}
+}
+
+func TestDirectoryExporter(t *testing.T) {
+	t.Run("export", func(t *testing.T) {
+		dir := t.TempDir()
+		e := NewDirectoryExporter(dir, 0)
+		require.NoError(t, e.Export(context.Background(), testExportBatch(1)))
+
+		batchDir := filepath.Join(dir, "20240102T030505Z-000001")
+		data, err := os.ReadFile(filepath.Join(batchDir, "cpu.pprof"))
+		require.NoError(t, err)
+		assert.Equal(t, "cpu", string(data))
+		data, err = os.ReadFile(filepath.Join(batchDir, "delta-heap.pprof"))
+		require.NoError(t, err)
+		assert.Equal(t, "heap", string(data))
+
+		data, err = os.ReadFile(filepath.Join(batchDir, "metadata.json"))
+		require.NoError(t, err)
+		var md exportMetadata
+		require.NoError(t, json.Unmarshal(data, &md))
+		assert.Equal(t, exportMetadata{
+			Start:       "2024-01-02T03:04:05Z",
+			End:         "2024-01-02T03:05:05Z",
+			Seq:         1,
+			Tags:        []string{"service:my-service", "runtime:go"},
+			Attachments: []string{"cpu.pprof", "delta-heap.pprof"},
+		}, md)
+	})
+
+	t.Run("rotation", func(t *testing.T) {
+		dir := t.TempDir()
+		// Unrelated files must be left alone
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "other"), 0755))
+
+		e := NewDirectoryExporter(dir, 2)
+		for seq := uint64(0); seq < 5; seq++ {
+			require.NoError(t, e.Export(context.Background(), testExportBatch(seq)))
+		}
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		assert.Equal(t, []string{"20240102T030705Z-000003", "20240102T030805Z-000004", "other"}, names)
+	})
+}
+
+type mockObjectStore struct {
+	mu      sync.Mutex
+	objects map[string]string
+	err     error
+}
+
+func (m *mockObjectStore) PutObject(_ context.Context, bucket, key string, body io.Reader, size int64) error {
+	if m.err != nil {
+		return m.err
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) != size {
+		return io.ErrShortWrite
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.objects == nil {
+		m.objects = make(map[string]string)
+	}
+	m.objects[bucket+"/"+key] = string(data)
+	return nil
+}
+
+func TestObjectStoreExporter(t *testing.T) {
+	t.Run("export", func(t *testing.T) {
+		var store mockObjectStore
+		e := NewObjectStoreExporter(&store, "bucket", "profiles/my-service")
+		require.NoError(t, e.Export(context.Background(), testExportBatch(1)))
+
+		require.Len(t, store.objects, 3)
+		assert.Equal(t, "cpu", store.objects["bucket/profiles/my-service/20240102T030505Z-000001/cpu.pprof"])
+		assert.Equal(t, "heap", store.objects["bucket/profiles/my-service/20240102T030505Z-000001/delta-heap.pprof"])
+		var md exportMetadata
+		require.NoError(t, json.Unmarshal([]byte(store.objects["bucket/profiles/my-service/20240102T030505Z-000001/metadata.json"]), &md))
+		assert.Equal(t, []string{"cpu.pprof", "delta-heap.pprof"}, md.Attachments)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		store := mockObjectStore{err: io.ErrUnexpectedEOF}
+		e := NewObjectStoreExporter(&store, "bucket", "")
+		err := e.Export(context.Background(), testExportBatch(1))
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Contains(t, err.Error(), "20240102T030505Z-000001/cpu.pprof")
+	})
+}
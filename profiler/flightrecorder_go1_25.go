@@ -0,0 +1,26 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+//go:build go1.25
+
+package profiler
+
+import (
+	"runtime/trace"
+	"time"
+)
+
+// flightRecorderExclusive is false as of Go 1.25, where the flight recorder
+// and other execution traces can be recorded at the same time.
+const flightRecorderExclusive = false
+
+// newFlightRecorder returns a flight recorder keeping at least the events of
+// the last minAge, within the maxBytes limit.
+func newFlightRecorder(minAge time.Duration, maxBytes int) flightRecorder {
+	return trace.NewFlightRecorder(trace.FlightRecorderConfig{
+		MinAge:   minAge,
+		MaxBytes: uint64(maxBytes),
+	})
+}
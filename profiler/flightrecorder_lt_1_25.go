@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+//go:build !go1.25
+
+package profiler
+
+import (
+	"io"
+	"time"
+
+	"golang.org/x/exp/trace"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+// flightRecorderExclusive is true as the flight recorder holds the execution
+// tracer before Go 1.25: it must be paused to record other execution traces.
+const flightRecorderExclusive = true
+
+// newFlightRecorder returns a flight recorder keeping at least the events of
+// the last minAge, within the maxBytes limit. Before Go 1.25, the flight
+// recorder of golang.org/x/exp/trace is used.
+func newFlightRecorder(minAge time.Duration, maxBytes int) flightRecorder {
+	fr := trace.NewFlightRecorder()
+	if minAge > 0 {
+		fr.SetPeriod(minAge)
+	}
+	if maxBytes > 0 {
+		fr.SetSize(maxBytes)
+	}
+	return expFlightRecorder{fr}
+}
+
+// expFlightRecorder adapts trace.FlightRecorder to the flightRecorder
+// interface.
+type expFlightRecorder struct {
+	fr *trace.FlightRecorder
+}
+
+func (r expFlightRecorder) Start() error {
+	return r.fr.Start()
+}
+
+func (r expFlightRecorder) Stop() {
+	if err := r.fr.Stop(); err != nil {
+		log.Debug("Execution trace triggers: stopping the flight recorder: %v", err)
+	}
+}
+
+func (r expFlightRecorder) WriteTo(w io.Writer) (int64, error) {
+	n, err := r.fr.WriteTo(w)
+	return int64(n), err
+}
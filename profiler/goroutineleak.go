@@ -0,0 +1,348 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package profiler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"github.com/DataDog/gostackparse"
+	pprofile "github.com/google/pprof/profile"
+)
+
+const (
+	// defaultGoroutineLeakGrowthCycles is the default number of consecutive
+	// profiling cycles during which the number of goroutines created at the
+	// same place must grow to be reported as a leak.
+	defaultGoroutineLeakGrowthCycles = 3
+	// defaultGoroutineLeakWaitThreshold is the default duration after which
+	// blocked goroutines are reported as leaked.
+	defaultGoroutineLeakWaitThreshold = 10 * time.Minute
+	// maxGoroutineLeakSites bounds the number of creation sites tracked by
+	// the goroutine leak detector. The sites with the fewest goroutines are
+	// evicted first.
+	maxGoroutineLeakSites = 1000
+)
+
+// goroutineLeakConfig configures the goroutine leak detector.
+type goroutineLeakConfig struct {
+	// growthCycles is the number of consecutive profiling cycles during
+	// which the number of goroutines created at the same place must grow to
+	// be reported as a leak.
+	growthCycles int
+	// waitThreshold is the duration after which blocked goroutines are
+	// reported as leaked.
+	waitThreshold time.Duration
+}
+
+// collectGoroutineLeakProfile implements the collection of
+// GoroutineLeakProfile: it analyzes the goroutines at the end of the
+// profiling period and returns a profile of the suspected leaks.
+func collectGoroutineLeakProfile(p *profiler) ([]byte, error) {
+	if n := runtime.NumGoroutine(); n > p.cfg.maxGoroutinesWait {
+		return nil, fmt.Errorf("skipping goroutine leak profile: %d goroutines exceeds DD_PROFILING_WAIT_PROFILE_MAX_GOROUTINES limit of %d", n, p.cfg.maxGoroutinesWait)
+	}
+	if p.interruptibleSleep(p.cfg.period) {
+		return nil, errProfilerStopped
+	}
+
+	var text bytes.Buffer
+	if err := p.lookupProfile("goroutine", &text, 2); err != nil {
+		return nil, err
+	}
+	goroutines, err := parseGoroutines(&text)
+	if err != nil {
+		return nil, err
+	}
+	leaks := p.leaks.observe(goroutines)
+	p.reportGoroutineLeaks(leaks)
+
+	var buf bytes.Buffer
+	err = goroutineLeaksToPprof(leaks, &buf, now())
+	return buf.Bytes(), err
+}
+
+// parseGoroutines parses a goroutine profile in the debug=2 format.
+func parseGoroutines(r io.Reader) (goroutines []*gostackparse.Goroutine, err error) {
+	// See goroutineDebug2ToPprof
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	goroutines, _ = gostackparse.Parse(r)
+	return goroutines, nil
+}
+
+// goroutineLeakDetector tracks the number of goroutines per creation site
+// across profiling cycles to detect leaks. It is only used by the goroutine
+// leak profile collection, which runs at most once at a time.
+type goroutineLeakDetector struct {
+	cfg   goroutineLeakConfig
+	sites map[goroutineSiteKey]*goroutineSite
+}
+
+// goroutineSiteKey identifies the place where goroutines are created.
+type goroutineSiteKey struct {
+	fn, file string
+	line     int
+}
+
+// goroutineSite is the tracking state of a goroutine creation site.
+type goroutineSite struct {
+	// counts are the numbers of goroutines of the last growthCycles+1
+	// profiling cycles, the oldest first.
+	counts []int
+	// warned is true once a leak of the site has been logged.
+	warned bool
+}
+
+// goroutineLeak is a suspected goroutine leak.
+type goroutineLeak struct {
+	site goroutineSiteKey
+	// count is the current number of goroutines created at the site.
+	count int
+	// growth is the increase of count over the tracked cycles, if it grew
+	// during all of them.
+	growth int
+	// blocked is the number of goroutines created at the site blocked for
+	// longer than the threshold, and maxWait the longest wait.
+	blocked int
+	maxWait time.Duration
+	// stack is the stack of a goroutine of the site, preferably blocked.
+	stack []*gostackparse.Frame
+}
+
+func newGoroutineLeakDetector(cfg goroutineLeakConfig) *goroutineLeakDetector {
+	return &goroutineLeakDetector{cfg: cfg, sites: make(map[goroutineSiteKey]*goroutineSite)}
+}
+
+// observe updates the tracking state with the given goroutines of the current
+// profiling cycle and returns the suspected leaks, sorted by decreasing number
+// of goroutines.
+func (d *goroutineLeakDetector) observe(goroutines []*gostackparse.Goroutine) []goroutineLeak {
+	current := make(map[goroutineSiteKey]*goroutineLeak)
+	for _, g := range goroutines {
+		if g.CreatedBy == nil {
+			// The main goroutine
+			continue
+		}
+		key := goroutineSiteKey{fn: g.CreatedBy.Func, file: g.CreatedBy.File, line: g.CreatedBy.Line}
+		l, ok := current[key]
+		if !ok {
+			l = &goroutineLeak{site: key, stack: goroutineStack(g)}
+			current[key] = l
+		}
+		l.count++
+		if g.Wait >= d.cfg.waitThreshold {
+			if l.blocked == 0 {
+				l.stack = goroutineStack(g)
+			}
+			l.blocked++
+		}
+		if g.Wait > l.maxWait {
+			l.maxWait = g.Wait
+		}
+	}
+
+	// Sites without goroutines are not leaking anymore
+	for key := range d.sites {
+		if _, ok := current[key]; !ok {
+			delete(d.sites, key)
+		}
+	}
+	for key, l := range current {
+		s, ok := d.sites[key]
+		if !ok {
+			s = &goroutineSite{counts: make([]int, 0, d.cfg.growthCycles+1)}
+			d.sites[key] = s
+		}
+		if len(s.counts) == d.cfg.growthCycles+1 {
+			s.counts = append(s.counts[:0], s.counts[1:]...)
+		}
+		s.counts = append(s.counts, l.count)
+	}
+	d.evict()
+
+	var leaks []goroutineLeak
+	for key, l := range current {
+		s, ok := d.sites[key]
+		if !ok {
+			// Evicted
+			continue
+		}
+		if growing(s.counts, d.cfg.growthCycles) {
+			l.growth = s.counts[len(s.counts)-1] - s.counts[0]
+		}
+		if l.growth == 0 && l.blocked == 0 {
+			continue
+		}
+		leaks = append(leaks, *l)
+	}
+	sort.Slice(leaks, func(i, j int) bool {
+		if leaks[i].count != leaks[j].count {
+			return leaks[i].count > leaks[j].count
+		}
+		a, b := leaks[i].site, leaks[j].site
+		if a.fn != b.fn {
+			return a.fn < b.fn
+		}
+		if a.file != b.file {
+			return a.file < b.file
+		}
+		return a.line < b.line
+	})
+	return leaks
+}
+
+// evict removes the sites with the fewest goroutines beyond
+// maxGoroutineLeakSites to bound the memory used by the tracking state.
+func (d *goroutineLeakDetector) evict() {
+	if len(d.sites) <= maxGoroutineLeakSites {
+		return
+	}
+	type entry struct {
+		key   goroutineSiteKey
+		count int
+	}
+	entries := make([]entry, 0, len(d.sites))
+	for key, s := range d.sites {
+		entries = append(entries, entry{key: key, count: s.counts[len(s.counts)-1]})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].count < entries[j].count })
+	for _, e := range entries[:len(entries)-maxGoroutineLeakSites] {
+		delete(d.sites, e.key)
+	}
+}
+
+// growing returns true if counts strictly increased during the given number
+// of cycles.
+func growing(counts []int, cycles int) bool {
+	if cycles <= 0 || len(counts) < cycles+1 {
+		return false
+	}
+	for i := 1; i < len(counts); i++ {
+		if counts[i] <= counts[i-1] {
+			return false
+		}
+	}
+	return true
+}
+
+// goroutineStack returns the stack of g, including the frame which created it
+// like in goroutineDebug2ToPprof.
+func goroutineStack(g *gostackparse.Goroutine) []*gostackparse.Frame {
+	stack := append([]*gostackparse.Frame(nil), g.Stack...)
+	if g.FramesElided {
+		stack = append(stack, &gostackparse.Frame{Func: "...additional frames elided..."})
+	}
+	return append(stack, g.CreatedBy)
+}
+
+// reportGoroutineLeaks reports the suspected goroutine leaks as metrics, and
+// logs a warning the first time a creation site is suspected of leaking.
+func (p *profiler) reportGoroutineLeaks(leaks []goroutineLeak) {
+	var leaked int64
+	for _, l := range leaks {
+		leaked += int64(l.count)
+		s := p.leaks.sites[l.site]
+		if s.warned {
+			continue
+		}
+		s.warned = true
+		if l.growth > 0 {
+			log.Warn("Possible goroutine leak: the number of goroutines created by %s at %s:%d grew by %d during the last %d profiling cycles, to %d",
+				l.site.fn, l.site.file, l.site.line, l.growth, p.cfg.goroutineLeak.growthCycles, l.count)
+		} else {
+			log.Warn("Possible goroutine leak: %d goroutines created by %s at %s:%d have been blocked for more than %s",
+				l.blocked, l.site.fn, l.site.file, l.site.line, p.cfg.goroutineLeak.waitThreshold)
+		}
+	}
+	// The metrics are the current state of the leaks, not events: they are
+	// only reported by the clients supporting gauges, such as the ones of
+	// github.com/DataDog/datadog-go.
+	g, ok := p.cfg.statsd.(gaugeClient)
+	if !ok {
+		return
+	}
+	tags := p.cfg.tags.Slice()
+	g.Gauge("datadog.profiling.go.goroutine_leak_sites", float64(len(leaks)), tags, 1)
+	g.Gauge("datadog.profiling.go.goroutine_leak_goroutines", float64(leaked), tags, 1)
+}
+
+// gaugeClient is implemented by the StatsdClient able to report gauges.
+type gaugeClient interface {
+	Gauge(name string, value float64, tags []string, rate float64) error
+}
+
+// goroutineLeaksToPprof writes the suspected goroutine leaks as a pprof
+// profile, with one sample per creation site. The reasons of the suspicion
+// are in the "leak" label: "growing" and/or "blocked".
+func goroutineLeaksToPprof(leaks []goroutineLeak, w io.Writer, t time.Time) error {
+	p := &pprofile.Profile{
+		TimeNanos: t.UnixNano(),
+		SampleType: []*pprofile.ValueType{
+			{Type: "goroutines", Unit: "count"},
+			{Type: "blocked", Unit: "count"},
+			{Type: "waitduration", Unit: "nanoseconds"},
+		},
+	}
+	m := &pprofile.Mapping{ID: 1, HasFunctions: true}
+	p.Mapping = []*pprofile.Mapping{m}
+
+	functions := make(map[goroutineSiteKey]*pprofile.Function)
+	locations := make(map[goroutineSiteKey]*pprofile.Location)
+	for _, l := range leaks {
+		sample := &pprofile.Sample{
+			Value:    []int64{int64(l.count), int64(l.blocked), l.maxWait.Nanoseconds()},
+			Label:    map[string][]string{},
+			NumLabel: map[string][]int64{"growth": {int64(l.growth)}},
+			NumUnit:  map[string][]string{"growth": {"goroutines"}},
+		}
+		if l.growth > 0 {
+			sample.Label["leak"] = append(sample.Label["leak"], "growing")
+		}
+		if l.blocked > 0 {
+			sample.Label["leak"] = append(sample.Label["leak"], "blocked")
+		}
+		for _, frame := range l.stack {
+			key := goroutineSiteKey{fn: frame.Func, file: frame.File, line: frame.Line}
+			loc, ok := locations[key]
+			if !ok {
+				fkey := goroutineSiteKey{fn: frame.Func, file: frame.File}
+				fn, ok := functions[fkey]
+				if !ok {
+					fn = &pprofile.Function{ID: uint64(len(p.Function) + 1), Name: frame.Func, Filename: frame.File}
+					functions[fkey] = fn
+					p.Function = append(p.Function, fn)
+				}
+				loc = &pprofile.Location{
+					ID:      uint64(len(p.Location) + 1),
+					Mapping: m,
+					Line:    []pprofile.Line{{Function: fn, Line: int64(frame.Line)}},
+				}
+				locations[key] = loc
+				p.Location = append(p.Location, loc)
+			}
+			sample.Location = append(sample.Location, loc)
+		}
+		p.Sample = append(p.Sample, sample)
+	}
+
+	if err := p.CheckValid(); err != nil {
+		return fmt.Errorf("goroutineLeaksToPprof: %s", err)
+	}
+	if err := p.Write(w); err != nil {
+		return fmt.Errorf("goroutineLeaksToPprof: %s", err)
+	}
+	return nil
+}
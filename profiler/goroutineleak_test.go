@@ -0,0 +1,161 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package profiler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/statsdtest"
+
+	"github.com/DataDog/gostackparse"
+	pprofile "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// goroutineDump returns a goroutine profile in the debug=2 format with the
+// given numbers of goroutines created by main.worker and main.leak, the leaked
+// goroutines being blocked for the given number of minutes.
+func goroutineDump(workers, leaked, waitMinutes int) string {
+	var b strings.Builder
+	b.WriteString("goroutine 1 [running]:\nmain.main()\n\t/example/main.go:10 +0x3d2\n\n")
+	id := 2
+	for i := 0; i < workers; i++ {
+		fmt.Fprintf(&b, "goroutine %d [select]:\nmain.work()\n\t/example/main.go:20 +0x1\ncreated by main.worker in goroutine 1\n\t/example/main.go:30 +0x2\n\n", id)
+		id++
+	}
+	for i := 0; i < leaked; i++ {
+		wait := ""
+		if waitMinutes > 0 {
+			wait = fmt.Sprintf(", %d minutes", waitMinutes)
+		}
+		fmt.Fprintf(&b, "goroutine %d [chan send%s]:\nmain.send()\n\t/example/main.go:40 +0x1\ncreated by main.leak in goroutine 1\n\t/example/main.go:50 +0x2\n\n", id, wait)
+		id++
+	}
+	return b.String()
+}
+
+func parseDump(t *testing.T, dump string) []*gostackparse.Goroutine {
+	goroutines, errs := gostackparse.Parse(strings.NewReader(dump))
+	require.Empty(t, errs)
+	return goroutines
+}
+
+func TestGoroutineLeakDetector(t *testing.T) {
+	leakSite := goroutineSiteKey{fn: "main.leak", file: "/example/main.go", line: 50}
+
+	t.Run("growing", func(t *testing.T) {
+		d := newGoroutineLeakDetector(goroutineLeakConfig{growthCycles: 3, waitThreshold: time.Hour})
+		for i, leaked := range []int{1, 2, 3} {
+			leaks := d.observe(parseDump(t, goroutineDump(5, leaked, 0)))
+			require.Empty(t, leaks, "cycle %d", i)
+		}
+		leaks := d.observe(parseDump(t, goroutineDump(5, 4, 0)))
+		require.Len(t, leaks, 1)
+		assert.Equal(t, leakSite, leaks[0].site)
+		assert.Equal(t, 4, leaks[0].count)
+		assert.Equal(t, 3, leaks[0].growth)
+		assert.Zero(t, leaks[0].blocked)
+		assert.Equal(t, "main.send", leaks[0].stack[0].Func)
+		assert.Equal(t, "main.leak", leaks[0].stack[1].Func)
+
+		// The growth must be strict
+		leaks = d.observe(parseDump(t, goroutineDump(5, 4, 0)))
+		require.Empty(t, leaks)
+
+		// The tracking state is reset when the goroutines are gone
+		d.observe(parseDump(t, goroutineDump(5, 0, 0)))
+		assert.NotContains(t, d.sites, leakSite)
+	})
+
+	t.Run("blocked", func(t *testing.T) {
+		d := newGoroutineLeakDetector(goroutineLeakConfig{growthCycles: 3, waitThreshold: 10 * time.Minute})
+		require.Empty(t, d.observe(parseDump(t, goroutineDump(5, 2, 9))))
+		leaks := d.observe(parseDump(t, goroutineDump(5, 2, 10)))
+		require.Len(t, leaks, 1)
+		assert.Equal(t, leakSite, leaks[0].site)
+		assert.Equal(t, 2, leaks[0].blocked)
+		assert.Equal(t, 10*time.Minute, leaks[0].maxWait)
+		assert.Zero(t, leaks[0].growth)
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		d := newGoroutineLeakDetector(goroutineLeakConfig{growthCycles: 3, waitThreshold: time.Hour})
+		var goroutines []*gostackparse.Goroutine
+		for i := 0; i < maxGoroutineLeakSites+10; i++ {
+			goroutines = append(goroutines, &gostackparse.Goroutine{
+				ID:        i,
+				Stack:     []*gostackparse.Frame{{Func: "main.work"}},
+				CreatedBy: &gostackparse.Frame{Func: "main.spawn", File: "/example/main.go", Line: i},
+			})
+		}
+		// The leaking site has the most goroutines and must not be evicted
+		for i := 0; i < 3; i++ {
+			goroutines = append(goroutines, &gostackparse.Goroutine{
+				Stack:     []*gostackparse.Frame{{Func: "main.send"}},
+				CreatedBy: &gostackparse.Frame{Func: leakSite.fn, File: leakSite.file, Line: leakSite.line},
+			})
+		}
+		d.observe(goroutines)
+		assert.Len(t, d.sites, maxGoroutineLeakSites)
+		assert.Contains(t, d.sites, leakSite)
+	})
+}
+
+func TestGoroutineLeakProfile(t *testing.T) {
+	t.Setenv("DD_PROFILING_GOROUTINE_LEAK_GROWTH_CYCLES", "2")
+	t.Setenv("DD_PROFILING_GOROUTINE_LEAK_WAIT_THRESHOLD", "1h")
+	var stats statsdtest.TestStatsdClient
+	p, err := unstartedProfiler(WithPeriod(time.Millisecond), WithProfileTypes(GoroutineLeakProfile), WithStatsd(&stats))
+	require.NoError(t, err)
+	var cycle int
+	p.testHooks.lookupProfile = func(_ string, w io.Writer, _ int) error {
+		cycle++
+		_, err := io.WriteString(w, goroutineDump(3, cycle, 0))
+		return err
+	}
+
+	var profs []*profile
+	for i := 0; i < 3; i++ {
+		profs, err = p.runProfile(GoroutineLeakProfile)
+		require.NoError(t, err)
+		require.Equal(t, "goroutineleaks.pprof", profs[0].name)
+	}
+	assert.True(t, p.leaks.sites[goroutineSiteKey{fn: "main.leak", file: "/example/main.go", line: 50}].warned)
+	// The leak metrics are gauges reported on every cycle
+	assert.Empty(t, stats.CountCalls())
+	assert.Len(t, stats.GaugeCalls(), 6)
+	assert.Equal(t, 3, stats.CallsByName()["datadog.profiling.go.goroutine_leak_sites"])
+	assert.Equal(t, 3, stats.CallsByName()["datadog.profiling.go.goroutine_leak_goroutines"])
+
+	pp, err := pprofile.Parse(bytes.NewReader(profs[0].data))
+	require.NoError(t, err)
+	require.Len(t, pp.SampleType, 3)
+	require.Len(t, pp.Sample, 1)
+	s := pp.Sample[0]
+	assert.Equal(t, []int64{3, 0, 0}, s.Value)
+	assert.Equal(t, []string{"growing"}, s.Label["leak"])
+	assert.Equal(t, []int64{2}, s.NumLabel["growth"])
+	var functions []string
+	for _, loc := range s.Location {
+		functions = append(functions, loc.Line[0].Function.Name)
+	}
+	assert.Equal(t, []string{"main.send", "main.leak"}, functions)
+}
+
+func TestGoroutineLeakInvalidConfig(t *testing.T) {
+	t.Setenv("DD_PROFILING_GOROUTINE_LEAK_GROWTH_CYCLES", "-1")
+	t.Setenv("DD_PROFILING_GOROUTINE_LEAK_WAIT_THRESHOLD", "0s")
+	p, err := unstartedProfiler(WithProfileTypes(GoroutineLeakProfile))
+	require.NoError(t, err)
+	assert.Equal(t, defaultGoroutineLeakGrowthCycles, p.cfg.goroutineLeak.growthCycles)
+	assert.Equal(t, defaultGoroutineLeakWaitThreshold, p.cfg.goroutineLeak.waitThreshold)
+}
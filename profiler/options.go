@@ -15,6 +15,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -61,6 +62,10 @@ const (
 	defaultAPIURL    = "https://intake.profile.datadoghq.com/v1/input"
 	defaultAgentHost = "localhost"
 	defaultAgentPort = "8126"
+
+	// defaultSpanTagLabelsMaxValues is the default number of distinct values
+	// of each span tag applied as pprof labels, see WithSpanTagLabels.
+	defaultSpanTagLabelsMaxValues = 100
 )
 
 var defaultClient = &http.Client{
@@ -98,18 +103,26 @@ type config struct {
 	httpClient           *http.Client
 	tags                 immutable.StringSlice
 	customProfilerLabels []string
+	spanTagLabels        []string
+	spanTagLabelsMax     int
 	types                map[ProfileType]struct{}
 	period               time.Duration
 	cpuDuration          time.Duration
 	cpuProfileRate       int
 	uploadTimeout        time.Duration
+	uploadCompression    uploadCompression
+	maxProfileSize       int                 // maxProfileSize is the size limit of the uploaded profiles, 0 if unlimited
+	maxProfileSizes      map[ProfileType]int // maxProfileSizes overrides maxProfileSize per profile type
 	maxGoroutinesWait    int
+	goroutineLeak        goroutineLeakConfig
 	mutexFraction        int
 	blockRate            int
 	outputDir            string
+	exporter             Exporter
 	deltaProfiles        bool
 	logStartup           bool
 	traceConfig          executionTraceConfig
+	traceTriggers        *ExecutionTraceTriggers
 	endpointCountEnabled bool
 }
 
@@ -140,12 +153,16 @@ func logStartup(c *config) {
 		"block_profile_rate":         c.blockRate,
 		"mutex_profile_fraction":     c.mutexFraction,
 		"max_goroutines_wait":        c.maxGoroutinesWait,
+		"upload_compression":         c.uploadCompression,
+		"max_profile_size_bytes":     c.maxProfileSize,
 		"upload_timeout":             c.uploadTimeout.String(),
 		"execution_trace_enabled":    c.traceConfig.Enabled,
 		"execution_trace_period":     c.traceConfig.Period.String(),
 		"execution_trace_size_limit": c.traceConfig.Limit,
+		"execution_trace_triggers":   c.traceTriggers != nil,
 		"endpoint_count_enabled":     c.endpointCountEnabled,
 		"custom_profiler_label_keys": c.customProfilerLabels,
+		"span_tag_labels":            c.spanTagLabels,
 	}
 	b, err := json.Marshal(info)
 	if err != nil {
@@ -192,6 +209,8 @@ func defaultConfig() (*config, error) {
 		blockRate:            DefaultBlockRate,
 		mutexFraction:        DefaultMutexFraction,
 		uploadTimeout:        DefaultUploadTimeout,
+		uploadCompression:    gzipCompression,
+		maxProfileSizes:      make(map[ProfileType]int),
 		maxGoroutinesWait:    1000, // arbitrary value, should limit STW to ~30ms
 		deltaProfiles:        internal.BoolEnv("DD_PROFILING_DELTA", true),
 		logStartup:           internal.BoolEnv("DD_TRACE_STARTUP_LOGS", true),
@@ -215,6 +234,10 @@ func defaultConfig() (*config, error) {
 		}
 		WithUploadTimeout(d)(&c)
 	}
+	if v := os.Getenv("DD_PROFILING_UPLOAD_COMPRESSION"); v != "" {
+		WithUploadCompression(v)(&c)
+	}
+	c.maxProfileSize = internal.IntEnv("DD_PROFILING_MAX_PROFILE_SIZE_BYTES", 0)
 	if v := os.Getenv("DD_API_KEY"); v != "" {
 		WithAPIKey(v)(&c)
 	}
@@ -266,6 +289,28 @@ func defaultConfig() (*config, error) {
 	if v := os.Getenv("DD_PROFILING_OUTPUT_DIR"); v != "" {
 		withOutputDir(v)(&c)
 	}
+	c.goroutineLeak.growthCycles = internal.IntEnv("DD_PROFILING_GOROUTINE_LEAK_GROWTH_CYCLES", defaultGoroutineLeakGrowthCycles)
+	if c.goroutineLeak.growthCycles < 1 {
+		log.Warn("Invalid DD_PROFILING_GOROUTINE_LEAK_GROWTH_CYCLES %d, must be at least 1, using the default of %d",
+			c.goroutineLeak.growthCycles, defaultGoroutineLeakGrowthCycles)
+		c.goroutineLeak.growthCycles = defaultGoroutineLeakGrowthCycles
+	}
+	c.goroutineLeak.waitThreshold = internal.DurationEnv("DD_PROFILING_GOROUTINE_LEAK_WAIT_THRESHOLD", defaultGoroutineLeakWaitThreshold)
+	if c.goroutineLeak.waitThreshold <= 0 {
+		log.Warn("Invalid DD_PROFILING_GOROUTINE_LEAK_WAIT_THRESHOLD %s, must be positive, using the default of %s",
+			c.goroutineLeak.waitThreshold, defaultGoroutineLeakWaitThreshold)
+		c.goroutineLeak.waitThreshold = defaultGoroutineLeakWaitThreshold
+	}
+	if v := os.Getenv("DD_PROFILING_SPAN_TAG_LABELS"); v != "" {
+		var keys []string
+		for _, k := range strings.Split(v, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				keys = append(keys, k)
+			}
+		}
+		WithSpanTagLabels(keys...)(&c)
+	}
+	c.spanTagLabelsMax = internal.IntEnv("DD_PROFILING_SPAN_TAG_LABELS_MAX_VALUES", defaultSpanTagLabelsMaxValues)
 	if v := os.Getenv("DD_PROFILING_WAIT_PROFILE_MAX_GOROUTINES"); v != "" {
 		n, err := strconv.Atoi(v)
 		if err != nil {
@@ -494,6 +539,17 @@ func withOutputDir(dir string) Option {
 	}
 }
 
+// WithExporter exports the profiles with the given exporter instead of
+// uploading them to the Datadog Agent or intake, for example to keep them in a
+// local directory with NewDirectoryExporter or in an object store with
+// NewObjectStoreExporter. This is useful when the Datadog Agent can't be
+// reached, such as in air-gapped environments.
+func WithExporter(e Exporter) Option {
+	return func(cfg *config) {
+		cfg.exporter = e
+	}
+}
+
 // WithLogStartup toggles logging the configuration of the profiler to standard
 // error when profiling is started. The configuration is logged in a JSON
 // format. This option is enabled by default.
@@ -561,6 +617,70 @@ func (e *executionTraceConfig) Refresh() {
 	e.warned = false
 }
 
+// WithUploadCompression sets the compression of the pprof profiles uploaded
+// to Datadog: "gzip", the default, uploads them as compressed by the Go
+// runtime, while "zstd" recompresses them with zstd, which usually makes the
+// uploads smaller at the cost of some CPU time. It can also be set with the
+// DD_PROFILING_UPLOAD_COMPRESSION environment variable.
+func WithUploadCompression(compression string) Option {
+	return func(cfg *config) {
+		cfg.uploadCompression = uploadCompression(strings.ToLower(compression))
+	}
+}
+
+// WithMaxProfileSize limits the size, in bytes and once compressed, of the
+// uploaded pprof profiles of type t. Larger profiles are downsampled by
+// aggregating their lowest-weight samples, e.g. the smallest heap
+// allocations or the least common goroutine stacks, into a single sample, so
+// that their totals are preserved. Profiles which still exceed the limit are
+// dropped. A limit of 0 removes the limit of the type.
+//
+// The default limit of all the types can be set with the
+// DD_PROFILING_MAX_PROFILE_SIZE_BYTES environment variable. Execution traces
+// are limited by DD_PROFILING_EXECUTION_TRACE_LIMIT_BYTES instead.
+func WithMaxProfileSize(t ProfileType, bytes int) Option {
+	return func(cfg *config) {
+		if cfg.maxProfileSizes == nil {
+			cfg.maxProfileSizes = make(map[ProfileType]int)
+		}
+		cfg.maxProfileSizes[t] = bytes
+	}
+}
+
+// maxProfileSizeOf returns the size limit of the uploaded profiles of type t,
+// 0 if unlimited.
+func (c *config) maxProfileSizeOf(t ProfileType) int {
+	if n, ok := c.maxProfileSizes[t]; ok {
+		return n
+	}
+	return c.maxProfileSize
+}
+
+// WithExecutionTraceTriggers records execution traces when spans matching
+// the given rules are slow or fail, or when runtime metrics cross the given
+// thresholds, in addition to the periodic execution traces. The traces are
+// tagged with the reason of the trigger and the IDs of the triggering span.
+// They are rate limited by ExecutionTraceTriggers.MinInterval, and their size
+// is limited like the periodic traces.
+//
+// A flight recorder keeps the most recent execution trace events in memory,
+// so the recorded trace covers the moments preceding the trigger, including
+// the triggering span. It is the one of the runtime as of Go 1.25, and the one
+// of golang.org/x/exp/trace with earlier Go versions. Before Go 1.25, the
+// flight recorder is paused while the periodic and on-demand execution traces
+// are recorded, and the triggers are skipped meanwhile. If the flight recorder
+// can't be started, e.g. because the application records an execution trace,
+// the trace is recorded after the trigger, for
+// ExecutionTraceTriggers.Duration: slow spans trigger it as soon as they
+// exceed their threshold, while still in flight.
+//
+// Span triggers require the tracer to be started.
+func WithExecutionTraceTriggers(triggers ExecutionTraceTriggers) Option {
+	return func(cfg *config) {
+		cfg.traceTriggers = &triggers
+	}
+}
+
 // WithCustomProfilerLabelKeys specifies [profiler label] keys which should be
 // available as attributes for filtering frames for CPU and goroutine profile
 // flame graphs in the Datadog profiler UI.
@@ -575,3 +695,31 @@ func WithCustomProfilerLabelKeys(keys ...string) Option {
 		cfg.customProfilerLabels = append(cfg.customProfilerLabels, keys...)
 	}
 }
+
+// WithSpanTagLabels specifies span tags, such as a customer tier, a tenant ID
+// or http.route, which the tracer applies as [profiler label]s to the
+// goroutines of the spans having them, so that CPU and goroutine profiles can
+// be broken down by them without calling pprof.Do. This requires the tracer
+// to be started. The keys are also added as with WithCustomProfilerLabelKeys.
+//
+// To bound the cardinality of the labels, the values of each tag beyond the
+// first 100 distinct ones are replaced by "_other". The limit can be changed
+// with the DD_PROFILING_SPAN_TAG_LABELS_MAX_VALUES environment variable.
+//
+// The tags can also be set with the DD_PROFILING_SPAN_TAG_LABELS environment
+// variable, as a comma-separated list.
+//
+// [profiler label]: https://rakyll.org/profiler-labels/
+func WithSpanTagLabels(keys ...string) Option {
+	return func(cfg *config) {
+		for _, k := range keys {
+			if slices.Contains(cfg.spanTagLabels, k) {
+				continue
+			}
+			cfg.spanTagLabels = append(cfg.spanTagLabels, k)
+			if !slices.Contains(cfg.customProfilerLabels, k) {
+				cfg.customProfilerLabels = append(cfg.customProfilerLabels, k)
+			}
+		}
+	}
+}
@@ -247,6 +247,32 @@ func TestOptions(t *testing.T) {
 		WithHostname("example")(&cfg)
 		assert.Equal(t, "example", cfg.hostname)
 	})
+
+	t.Run("WithUploadCompression", func(t *testing.T) {
+		p, err := unstartedProfiler(WithUploadCompression("ZSTD"))
+		require.NoError(t, err)
+		assert.Equal(t, zstdCompression, p.cfg.uploadCompression)
+		_, err = unstartedProfiler(WithUploadCompression("lz4"))
+		require.Error(t, err)
+	})
+
+	t.Run("WithMaxProfileSize", func(t *testing.T) {
+		var cfg config
+		cfg.maxProfileSize = 1000
+		WithMaxProfileSize(HeapProfile, 10)(&cfg)
+		WithMaxProfileSize(CPUProfile, 0)(&cfg)
+		assert.Equal(t, 10, cfg.maxProfileSizeOf(HeapProfile))
+		assert.Equal(t, 0, cfg.maxProfileSizeOf(CPUProfile))
+		assert.Equal(t, 1000, cfg.maxProfileSizeOf(GoroutineProfile))
+	})
+
+	t.Run("WithSpanTagLabels", func(t *testing.T) {
+		var cfg config
+		WithCustomProfilerLabelKeys("tenant")(&cfg)
+		WithSpanTagLabels("tenant", "tier", "tier")(&cfg)
+		assert.Equal(t, []string{"tenant", "tier"}, cfg.spanTagLabels)
+		assert.Equal(t, []string{"tenant", "tier"}, cfg.customProfilerLabels)
+	})
 }
 
 func TestEnvVars(t *testing.T) {
@@ -340,6 +366,24 @@ func TestEnvVars(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, cfg.deltaProfiles, false)
 	})
+
+	t.Run("DD_PROFILING_UPLOAD_COMPRESSION", func(t *testing.T) {
+		t.Setenv("DD_PROFILING_UPLOAD_COMPRESSION", "zstd")
+		t.Setenv("DD_PROFILING_MAX_PROFILE_SIZE_BYTES", "1048576")
+		cfg, err := defaultConfig()
+		require.NoError(t, err)
+		assert.Equal(t, zstdCompression, cfg.uploadCompression)
+		assert.Equal(t, 1048576, cfg.maxProfileSizeOf(HeapProfile))
+	})
+
+	t.Run("DD_PROFILING_SPAN_TAG_LABELS", func(t *testing.T) {
+		t.Setenv("DD_PROFILING_SPAN_TAG_LABELS", "tenant, http.route,")
+		t.Setenv("DD_PROFILING_SPAN_TAG_LABELS_MAX_VALUES", "10")
+		cfg, err := defaultConfig()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"tenant", "http.route"}, cfg.spanTagLabels)
+		assert.Equal(t, 10, cfg.spanTagLabelsMax)
+	})
 }
 
 func TestDefaultConfig(t *testing.T) {
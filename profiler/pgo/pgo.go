@@ -0,0 +1,287 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+// Package pgo generates profiles for profile-guided optimization (PGO) from
+// the CPU profiles collected by the profiler and exported locally with
+// profiler.NewDirectoryExporter. The generated profile is meant to be
+// committed as default.pgo in the main package directory, where
+// `go build -pgo=auto` picks it up.
+package pgo // import "gopkg.in/DataDog/dd-trace-go.v1/profiler/pgo"
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal/pprofutils"
+
+	"github.com/google/pprof/profile"
+)
+
+// cpuSampleType is the sample type of the CPU profiles collected by the
+// profiler, used by the compiler to weight the call edges.
+var cpuSampleType = pprofutils.ValueType{Type: "cpu", Unit: "nanoseconds"}
+
+// DefaultPrunedRoots are the root functions of the goroutines whose samples
+// are pruned by default: runtime background workers, and samples without a Go
+// stack attributed to pseudo-functions like runtime._GC. They are not
+// representative of the program and would steer the optimizations of the
+// compiler towards code it doesn't own.
+var DefaultPrunedRoots = []string{
+	"runtime._",
+	"runtime.bgscavenge",
+	"runtime.bgsweep",
+	"runtime.forcegchelper",
+	"runtime.gcBgMarkWorker",
+}
+
+// TracerRoot is the root function prefix of the goroutines of dd-trace-go,
+// pruned when Config.PruneTracer is set. They are kept by default, as
+// dd-trace-go is compiled into the program and benefits from PGO too.
+const TracerRoot = "gopkg.in/DataDog/dd-trace-go.v1/"
+
+// Config configures the generation of a PGO profile.
+type Config struct {
+	// Start and End delimit the time window of the profiles to merge. The
+	// profiles of the batches overlapping the window are merged. A zero
+	// Start or End leaves the window open on that side.
+	Start, End time.Time
+	// PrunedRoots are the root functions of the goroutines whose samples are
+	// pruned, matched by prefix. The root function is the outermost
+	// function of the stack, runtime.goexit aside. Defaults to
+	// DefaultPrunedRoots when nil.
+	PrunedRoots []string
+	// PruneTracer prunes the samples of the goroutines of dd-trace-go, in
+	// addition to PrunedRoots.
+	PruneTracer bool
+}
+
+// batchMetadata is the subset of the metadata.json file written with each
+// batch of profiles by profiler.NewDirectoryExporter used to select them.
+type batchMetadata struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Attachments []string  `json:"attachments"`
+}
+
+// cpuProfileName is the name of the CPU profile in the exported batches.
+const cpuProfileName = "cpu.pprof"
+
+// LoadDir loads the CPU profiles exported to dir by
+// profiler.NewDirectoryExporter within the time window of cfg. The batches
+// without a CPU profile are skipped.
+func LoadDir(dir string, cfg Config) ([]*profile.Profile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var profiles []*profile.Profile
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		batchDir := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(batchDir, "metadata.json"))
+		if errors.Is(err, os.ErrNotExist) {
+			// Not a batch, or a batch still being written
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		var md batchMetadata
+		if err := json.Unmarshal(data, &md); err != nil {
+			return nil, fmt.Errorf("%s: %w", batchDir, err)
+		}
+		if !cfg.overlaps(md.Start, md.End) || !hasAttachment(md, cpuProfileName) {
+			continue
+		}
+		prof, err := readProfile(filepath.Join(batchDir, cpuProfileName))
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, prof)
+	}
+	return profiles, nil
+}
+
+func (cfg *Config) overlaps(start, end time.Time) bool {
+	if !cfg.Start.IsZero() && end.Before(cfg.Start) {
+		return false
+	}
+	if !cfg.End.IsZero() && start.After(cfg.End) {
+		return false
+	}
+	return true
+}
+
+func hasAttachment(md batchMetadata, name string) bool {
+	for _, a := range md.Attachments {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+func readProfile(path string) (*profile.Profile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	prof, err := profile.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return prof, nil
+}
+
+// Merge normalizes the given CPU profiles and merges them into a single
+// profile suitable for PGO. Normalizing a profile strips its labels and
+// addresses, and prunes the samples of the goroutines configured with
+// cfg.PrunedRoots and cfg.PruneTracer. The samples are then merged by
+// function: the samples of the same call stacks merge across processes and
+// builds, even when a function moved between builds, the latest profile
+// defining the location of the functions.
+func Merge(profiles []*profile.Profile, cfg Config) (*profile.Profile, error) {
+	if len(profiles) == 0 {
+		return nil, errors.New("no CPU profile to merge")
+	}
+	pruned := cfg.PrunedRoots
+	if pruned == nil {
+		pruned = DefaultPrunedRoots
+	}
+	if cfg.PruneTracer {
+		pruned = append(slices.Clip(pruned), TracerRoot)
+	}
+	normalized := make([]*profile.Profile, 0, len(profiles))
+	for _, prof := range profiles {
+		if !hasSampleType(prof, cpuSampleType) {
+			return nil, fmt.Errorf("not a CPU profile: missing %s/%s sample type", cpuSampleType.Type, cpuSampleType.Unit)
+		}
+		normalized = append(normalized, normalize(prof.Copy(), pruned))
+	}
+	mergeFunctions(normalized)
+	merged, err := profile.Merge(normalized)
+	if err != nil {
+		return nil, err
+	}
+	return merged.Compact(), nil
+}
+
+func hasSampleType(prof *profile.Profile, vt pprofutils.ValueType) bool {
+	for _, st := range prof.SampleType {
+		if st.Type == vt.Type && st.Unit == vt.Unit {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize strips the labels and addresses of prof, and prunes the samples
+// of the goroutines rooted at the pruned functions. It modifies prof.
+func normalize(prof *profile.Profile, pruned []string) *profile.Profile {
+	samples := prof.Sample[:0]
+	for _, s := range prof.Sample {
+		if isPruned(s, pruned) {
+			continue
+		}
+		s.Label = nil
+		s.NumLabel = nil
+		s.NumUnit = nil
+		samples = append(samples, s)
+	}
+	prof.Sample = samples
+	// Locations are identified by their address when it is set, which
+	// differs between processes because of ASLR and between builds.
+	for _, loc := range prof.Location {
+		loc.Address = 0
+		loc.Mapping = nil
+	}
+	prof.Mapping = nil
+	prof.DropFrames = ""
+	prof.KeepFrames = ""
+	return prof
+}
+
+// mergeFunctions makes the functions with the same name identical across
+// profiles, so that their samples merge even when the functions moved between
+// builds. The last profile defines the functions. The lines are shifted to
+// keep their offset from the start of their function, which the compiler uses
+// to identify the call sites. It modifies profiles.
+func mergeFunctions(profiles []*profile.Profile) {
+	latest := make(map[string]*profile.Function)
+	for _, prof := range profiles {
+		for _, fn := range prof.Function {
+			latest[fn.Name] = fn
+		}
+	}
+	for _, prof := range profiles {
+		for _, loc := range prof.Location {
+			for i := range loc.Line {
+				fn := loc.Line[i].Function
+				if fn == nil {
+					continue
+				}
+				if l := latest[fn.Name]; fn.StartLine != 0 && l.StartLine != 0 {
+					loc.Line[i].Line += l.StartLine - fn.StartLine
+				}
+			}
+		}
+		// Update the functions once all their lines are shifted
+		for _, fn := range prof.Function {
+			l := latest[fn.Name]
+			fn.SystemName, fn.Filename, fn.StartLine = l.SystemName, l.Filename, l.StartLine
+		}
+	}
+}
+
+// isPruned returns true if the root function of the stack of s starts with
+// one of the pruned prefixes.
+func isPruned(s *profile.Sample, pruned []string) bool {
+	root := rootFunction(s)
+	for _, prefix := range pruned {
+		if strings.HasPrefix(root, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// rootFunction returns the name of the outermost function of the stack of s,
+// ignoring runtime.goexit which is at the root of every goroutine.
+func rootFunction(s *profile.Sample) string {
+	for i := len(s.Location) - 1; i >= 0; i-- {
+		lines := s.Location[i].Line
+		// The outermost function of an inlined location comes last
+		for j := len(lines) - 1; j >= 0; j-- {
+			if lines[j].Function == nil || lines[j].Function.Name == "runtime.goexit" {
+				continue
+			}
+			return lines[j].Function.Name
+		}
+	}
+	return ""
+}
+
+// Generate merges the CPU profiles exported to dir within the time window of
+// cfg and writes the resulting PGO profile to w.
+func Generate(w io.Writer, dir string, cfg Config) error {
+	profiles, err := LoadDir(dir, cfg)
+	if err != nil {
+		return err
+	}
+	merged, err := Merge(profiles, cfg)
+	if err != nil {
+		return err
+	}
+	return merged.Write(w)
+}
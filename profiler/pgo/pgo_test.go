@@ -0,0 +1,192 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package pgo
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler"
+
+	"github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCPUProfile returns a CPU profile with one sample of the given value per
+// stack, the stacks being listed from the leaf to the root function. The
+// addresses of the locations start at base, and each sample is labeled.
+func newCPUProfile(base uint64, value int64, stacks ...[]string) *profile.Profile {
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "samples", Unit: "count"},
+			{Type: "cpu", Unit: "nanoseconds"},
+		},
+		PeriodType: &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Period:     10000000,
+		Mapping:    []*profile.Mapping{{ID: 1, Start: base, Limit: base + 0x100000, File: "/app/server", HasFunctions: true}},
+	}
+	functions := map[string]*profile.Function{}
+	for _, stack := range stacks {
+		s := &profile.Sample{
+			Value: []int64{1, value},
+			Label: map[string][]string{"span id": {"123"}},
+		}
+		for i, name := range stack {
+			fn, ok := functions[name]
+			if !ok {
+				fn = &profile.Function{ID: uint64(len(functions) + 1), Name: name, SystemName: name, Filename: "main.go", StartLine: 10}
+				functions[name] = fn
+				prof.Function = append(prof.Function, fn)
+			}
+			loc := &profile.Location{
+				ID:      uint64(len(prof.Location) + 1),
+				Mapping: prof.Mapping[0],
+				Address: base + uint64(len(prof.Location)*0x10),
+				Line:    []profile.Line{{Function: fn, Line: int64(20 + i)}},
+			}
+			prof.Location = append(prof.Location, loc)
+			s.Location = append(s.Location, loc)
+		}
+		prof.Sample = append(prof.Sample, s)
+	}
+	return prof
+}
+
+var (
+	userStack    = []string{"main.compute", "main.handle", "main.main", "runtime.main", "runtime.goexit"}
+	gcStack      = []string{"runtime.scanobject", "runtime.gcDrain", "runtime.gcBgMarkWorker", "runtime.goexit"}
+	tracerStack  = []string{"encoding/json.Marshal", "gopkg.in/DataDog/dd-trace-go.v1/profiler.(*profiler).send", "runtime.goexit"}
+	pseudoStack  = []string{"runtime._GC"}
+	allTheStacks = [][]string{userStack, gcStack, tracerStack, pseudoStack}
+)
+
+func TestMerge(t *testing.T) {
+	// Two processes with different address layouts
+	profiles := []*profile.Profile{
+		newCPUProfile(0x400000, 10, allTheStacks...),
+		newCPUProfile(0x800000, 20, allTheStacks...),
+	}
+
+	merged, err := Merge(profiles, Config{PruneTracer: true})
+	require.NoError(t, err)
+	require.NoError(t, merged.CheckValid())
+	require.Len(t, merged.Sample, 1)
+	s := merged.Sample[0]
+	assert.Equal(t, []int64{2, 30}, s.Value)
+	assert.Empty(t, s.Label)
+	assert.Equal(t, "main.compute", s.Location[0].Line[0].Function.Name)
+	assert.Equal(t, int64(20), s.Location[0].Line[0].Line)
+
+	// The input profiles are left untouched
+	assert.Len(t, profiles[0].Sample, 4)
+	assert.NotEmpty(t, profiles[0].Sample[0].Label)
+
+	t.Run("moved-functions", func(t *testing.T) {
+		// main.compute moved down by 5 lines in the latest build
+		moved := newCPUProfile(0x800000, 20, userStack)
+		for _, fn := range moved.Function {
+			if fn.Name == "main.compute" {
+				fn.StartLine += 5
+			}
+		}
+		moved.Sample[0].Location[0].Line[0].Line += 5
+		merged, err := Merge([]*profile.Profile{newCPUProfile(0x400000, 10, userStack), moved}, Config{})
+		require.NoError(t, err)
+		require.NoError(t, merged.CheckValid())
+		require.Len(t, merged.Sample, 1)
+		assert.Equal(t, []int64{2, 30}, merged.Sample[0].Value)
+		line := merged.Sample[0].Location[0].Line[0]
+		assert.Equal(t, int64(15), line.Function.StartLine)
+		assert.Equal(t, int64(25), line.Line)
+	})
+
+	t.Run("pruned-roots", func(t *testing.T) {
+		merged, err := Merge(profiles, Config{PrunedRoots: []string{"runtime.gcBgMarkWorker"}})
+		require.NoError(t, err)
+		assert.Len(t, merged.Sample, 3)
+	})
+
+	t.Run("tracer-kept", func(t *testing.T) {
+		// dd-trace-go is compiled into the program: its goroutines are kept by default
+		merged, err := Merge(profiles, Config{})
+		require.NoError(t, err)
+		require.Len(t, merged.Sample, 2)
+		var roots []string
+		for _, s := range merged.Sample {
+			roots = append(roots, rootFunction(s))
+		}
+		assert.ElementsMatch(t, []string{"runtime.main", "gopkg.in/DataDog/dd-trace-go.v1/profiler.(*profiler).send"}, roots)
+	})
+
+	t.Run("not-cpu", func(t *testing.T) {
+		prof := newCPUProfile(0x400000, 10, userStack)
+		prof.SampleType = []*profile.ValueType{{Type: "alloc_space", Unit: "bytes"}}
+		prof.Sample[0].Value = prof.Sample[0].Value[:1]
+		_, err := Merge([]*profile.Profile{prof}, Config{})
+		require.Error(t, err)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := Merge(nil, Config{})
+		require.Error(t, err)
+	})
+}
+
+// exportCPUProfile exports prof as the CPU profile of a batch ending at end.
+func exportCPUProfile(t *testing.T, e profiler.Exporter, seq uint64, end time.Time, prof *profile.Profile) {
+	var buf bytes.Buffer
+	require.NoError(t, prof.Write(&buf))
+	require.NoError(t, e.Export(context.Background(), &profiler.ExportBatch{
+		Start:    end.Add(-time.Minute),
+		End:      end,
+		Seq:      seq,
+		Profiles: []profiler.ExportedProfile{{Name: "cpu.pprof", Data: buf.Bytes()}},
+	}))
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	e := profiler.NewDirectoryExporter(dir, 0)
+	end := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		exportCPUProfile(t, e, uint64(i), end.Add(time.Duration(i)*time.Hour), newCPUProfile(0x400000, 10, userStack, gcStack, pseudoStack))
+	}
+	// Batches without a CPU profile are skipped
+	require.NoError(t, e.Export(context.Background(), &profiler.ExportBatch{
+		Start:    end.Add(-time.Minute),
+		End:      end,
+		Seq:      3,
+		Profiles: []profiler.ExportedProfile{{Name: "delta-heap.pprof", Data: []byte("heap")}},
+	}))
+
+	for _, tc := range []struct {
+		name  string
+		cfg   Config
+		value int64
+	}{
+		{name: "all", value: 30},
+		{name: "start", cfg: Config{Start: end.Add(30 * time.Minute)}, value: 20},
+		{name: "end", cfg: Config{End: end.Add(30 * time.Minute)}, value: 10},
+		{name: "window", cfg: Config{Start: end.Add(30 * time.Minute), End: end.Add(90 * time.Minute)}, value: 10},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Generate(&buf, dir, tc.cfg))
+			prof, err := profile.Parse(&buf)
+			require.NoError(t, err)
+			require.Len(t, prof.Sample, 1)
+			assert.Equal(t, tc.value, prof.Sample[0].Value[1])
+		})
+	}
+
+	t.Run("no-profile", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.Error(t, Generate(&buf, dir, Config{Start: end.Add(24 * time.Hour)}))
+	})
+}
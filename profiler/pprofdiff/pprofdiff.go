@@ -0,0 +1,357 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+// Package pprofdiff compares two pprof profiles of the same kind, typically
+// collected from two versions of a service, and reports the functions whose
+// allocations, live heap or CPU usage changed.
+package pprofdiff // import "gopkg.in/DataDog/dd-trace-go.v1/profiler/pprofdiff"
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal/pprofutils"
+
+	"github.com/google/pprof/profile"
+)
+
+// DefaultSampleTypes are the sample types compared by default, when present
+// in both profiles.
+var DefaultSampleTypes = []string{"alloc_space", "inuse_space", "cpu"}
+
+// Options configures the comparison of two profiles.
+type Options struct {
+	// SampleTypes are the sample types to compare. Defaults to the
+	// DefaultSampleTypes present in both profiles, or to all the sample
+	// types present in both profiles if there are none.
+	SampleTypes []string
+	// Threshold is the relative increase of the flat value of a function
+	// above which it is reported as a regression, e.g. 0.1 for +10%.
+	Threshold float64
+	// MinDelta is the absolute increase of the flat value of a function
+	// below which it is never reported as a regression, to ignore noise.
+	MinDelta int64
+	// Normalize scales the values of the target profile so that its totals
+	// match the base profile's, to compare the share of each function
+	// rather than absolute values, e.g. when the profiles cover different
+	// durations or loads.
+	Normalize bool
+}
+
+// Report is the result of the comparison of two profiles.
+type Report struct {
+	SampleTypes []SampleTypeDiff `json:"sample_types"`
+}
+
+// SampleTypeDiff is the comparison of the values of a sample type.
+type SampleTypeDiff struct {
+	Type string `json:"type"`
+	Unit string `json:"unit"`
+	// Base and Target are the totals of the sample type in each profile.
+	Base   int64 `json:"base"`
+	Target int64 `json:"target"`
+	// Functions are the functions whose values differ, sorted by
+	// decreasing absolute flat delta.
+	Functions []FunctionDiff `json:"functions"`
+}
+
+// FunctionDiff is the comparison of the values of a function.
+type FunctionDiff struct {
+	Function string `json:"function"`
+	// Type is the sample type of the values, e.g. alloc_space.
+	Type string `json:"type"`
+	// BaseFlat and TargetFlat are the values of the samples whose leaf is
+	// the function.
+	BaseFlat   int64 `json:"base_flat"`
+	TargetFlat int64 `json:"target_flat"`
+	// BaseCum and TargetCum are the values of the samples whose stack
+	// includes the function.
+	BaseCum   int64 `json:"base_cum"`
+	TargetCum int64 `json:"target_cum"`
+	// Regression is true when the flat value increased beyond the
+	// configured thresholds.
+	Regression bool `json:"regression"`
+}
+
+// FlatDelta returns the difference of the flat values.
+func (f FunctionDiff) FlatDelta() int64 { return f.TargetFlat - f.BaseFlat }
+
+// CumDelta returns the difference of the cumulative values.
+func (f FunctionDiff) CumDelta() int64 { return f.TargetCum - f.BaseCum }
+
+// Regressions returns the regressed functions of all the sample types. The
+// same function is returned once per sample type that regressed.
+func (r *Report) Regressions() []FunctionDiff {
+	var regressions []FunctionDiff
+	for _, st := range r.SampleTypes {
+		for _, f := range st.Functions {
+			if f.Regression {
+				regressions = append(regressions, f)
+			}
+		}
+	}
+	return regressions
+}
+
+// Diff compares the base and target profiles.
+func Diff(base, target *profile.Profile, opts Options) (*Report, error) {
+	sampleTypes := opts.SampleTypes
+	if len(sampleTypes) == 0 {
+		sampleTypes = commonSampleTypes(base, target, DefaultSampleTypes)
+		if len(sampleTypes) == 0 {
+			sampleTypes = commonSampleTypes(base, target, nil)
+		}
+	}
+	if len(sampleTypes) == 0 {
+		return nil, errors.New("the profiles have no sample type in common")
+	}
+
+	r := &Report{}
+	for _, name := range sampleTypes {
+		bi, bvt, err := sampleTypeIndex(base, name)
+		if err != nil {
+			return nil, fmt.Errorf("base profile: %w", err)
+		}
+		ti, tvt, err := sampleTypeIndex(target, name)
+		if err != nil {
+			return nil, fmt.Errorf("target profile: %w", err)
+		}
+		if bvt.Unit != tvt.Unit {
+			return nil, fmt.Errorf("sample type %s: unit %s differs from %s", name, tvt.Unit, bvt.Unit)
+		}
+		r.SampleTypes = append(r.SampleTypes, diffSampleType(base, target, bi, ti, bvt, opts))
+	}
+	return r, nil
+}
+
+// commonSampleTypes returns the sample types present in both profiles, in the
+// order of candidates, or of the base profile if candidates is nil.
+func commonSampleTypes(base, target *profile.Profile, candidates []string) []string {
+	if candidates == nil {
+		for _, st := range base.SampleType {
+			candidates = append(candidates, st.Type)
+		}
+	}
+	var common []string
+	for _, name := range candidates {
+		_, _, berr := sampleTypeIndex(base, name)
+		_, _, terr := sampleTypeIndex(target, name)
+		if berr == nil && terr == nil {
+			common = append(common, name)
+		}
+	}
+	return common
+}
+
+func sampleTypeIndex(p *profile.Profile, name string) (int, pprofutils.ValueType, error) {
+	for i, st := range p.SampleType {
+		if st.Type == name {
+			return i, pprofutils.ValueType{Type: st.Type, Unit: st.Unit}, nil
+		}
+	}
+	return 0, pprofutils.ValueType{}, fmt.Errorf("sample type %s not found", name)
+}
+
+// functionValues are the flat and cumulative values of a function.
+type functionValues struct {
+	flat, cum int64
+}
+
+// aggregate returns the total of the values at index i of the samples of p,
+// and their breakdown per function. The values are keyed by function name
+// rather than by location, unlike fastdelta which hashes the addresses of the
+// locations: the addresses of the same code differ between the builds of the
+// two versions being compared.
+func aggregate(p *profile.Profile, i int) (int64, map[string]*functionValues) {
+	var (
+		total int64
+		funcs = make(map[string]*functionValues)
+		seen  = make(map[string]struct{})
+	)
+	get := func(name string) *functionValues {
+		v, ok := funcs[name]
+		if !ok {
+			v = &functionValues{}
+			funcs[name] = v
+		}
+		return v
+	}
+	for _, s := range p.Sample {
+		v := s.Value[i]
+		if v == 0 {
+			continue
+		}
+		total += v
+		clear(seen)
+		leaf := true
+		for _, loc := range s.Location {
+			// Inlined functions come first in the lines of a location
+			for _, line := range loc.Line {
+				name := functionName(line, loc)
+				if leaf {
+					get(name).flat += v
+					leaf = false
+				}
+				// Count recursive functions once
+				if _, ok := seen[name]; !ok {
+					seen[name] = struct{}{}
+					get(name).cum += v
+				}
+			}
+		}
+	}
+	return total, funcs
+}
+
+func functionName(line profile.Line, loc *profile.Location) string {
+	if line.Function != nil && line.Function.Name != "" {
+		return line.Function.Name
+	}
+	return fmt.Sprintf("0x%x", loc.Address)
+}
+
+func diffSampleType(base, target *profile.Profile, bi, ti int, vt pprofutils.ValueType, opts Options) SampleTypeDiff {
+	baseTotal, baseFuncs := aggregate(base, bi)
+	targetTotal, targetFuncs := aggregate(target, ti)
+	scale := func(v int64) int64 { return v }
+	if opts.Normalize && targetTotal != 0 {
+		factor := float64(baseTotal) / float64(targetTotal)
+		scale = func(v int64) int64 { return int64(float64(v) * factor) }
+		targetTotal = baseTotal
+	}
+
+	std := SampleTypeDiff{
+		Type:      vt.Type,
+		Unit:      vt.Unit,
+		Base:      baseTotal,
+		Target:    targetTotal,
+		Functions: []FunctionDiff{},
+	}
+	names := make(map[string]struct{}, len(baseFuncs)+len(targetFuncs))
+	for name := range baseFuncs {
+		names[name] = struct{}{}
+	}
+	for name := range targetFuncs {
+		names[name] = struct{}{}
+	}
+	for name := range names {
+		f := FunctionDiff{Function: name, Type: vt.Type}
+		if v, ok := baseFuncs[name]; ok {
+			f.BaseFlat, f.BaseCum = v.flat, v.cum
+		}
+		if v, ok := targetFuncs[name]; ok {
+			f.TargetFlat, f.TargetCum = scale(v.flat), scale(v.cum)
+		}
+		if f.FlatDelta() == 0 && f.CumDelta() == 0 {
+			continue
+		}
+		f.Regression = isRegression(f, opts)
+		std.Functions = append(std.Functions, f)
+	}
+	sort.Slice(std.Functions, func(i, j int) bool {
+		a, b := abs(std.Functions[i].FlatDelta()), abs(std.Functions[j].FlatDelta())
+		if a != b {
+			return a > b
+		}
+		a, b = abs(std.Functions[i].CumDelta()), abs(std.Functions[j].CumDelta())
+		if a != b {
+			return a > b
+		}
+		return std.Functions[i].Function < std.Functions[j].Function
+	})
+	return std
+}
+
+func isRegression(f FunctionDiff, opts Options) bool {
+	delta := f.FlatDelta()
+	if delta <= 0 || delta < opts.MinDelta {
+		return false
+	}
+	if f.BaseFlat == 0 {
+		// New function
+		return true
+	}
+	return float64(delta)/float64(f.BaseFlat) > opts.Threshold
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// WriteText writes a human-readable report, listing at most limit functions
+// per sample type, or all of them if limit is zero or negative.
+func (r *Report) WriteText(w io.Writer, limit int) error {
+	for i, st := range r.SampleTypes {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s/%s: %d -> %d (%s)\n", st.Type, st.Unit, st.Base, st.Target, percent(st.Target-st.Base, st.Base))
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "flat delta\tflat%\tcum delta\tcum%\t\t function")
+		for j, f := range st.Functions {
+			if limit > 0 && j >= limit {
+				fmt.Fprintf(tw, "... %d more\t\t\t\t\t\n", len(st.Functions)-limit)
+				break
+			}
+			mark := ""
+			if f.Regression {
+				mark = "REGRESSION"
+			}
+			fmt.Fprintf(tw, "%+d\t%s\t%+d\t%s\t%s\t %s\n",
+				f.FlatDelta(), percent(f.FlatDelta(), f.BaseFlat),
+				f.CumDelta(), percent(f.CumDelta(), f.BaseCum),
+				mark, f.Function)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func percent(delta, base int64) string {
+	if base == 0 {
+		if delta == 0 {
+			return "0%"
+		}
+		return "new"
+	}
+	return fmt.Sprintf("%+.1f%%", float64(delta)/float64(base)*100)
+}
+
+// WriteJSON writes the report in JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// ReadProfile reads a profile from the given file, in the pprof format or in
+// the folded text format of pprofutils.Text, i.e. one "a;b;c <values>" stack
+// per line with an optional "type/unit ..." header.
+func ReadProfile(path string) (*profile.Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := profile.ParseData(data)
+	if err == nil {
+		return p, nil
+	}
+	p, terr := pprofutils.Text{}.Convert(bytes.NewReader(data))
+	if terr != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
@@ -0,0 +1,199 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package pprofdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal/pprofutils"
+
+	"github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func textProfile(t *testing.T, text string) *profile.Profile {
+	p, err := pprofutils.Text{}.Convert(strings.NewReader(strings.TrimSpace(text)))
+	require.NoError(t, err)
+	return p
+}
+
+const baseHeap = `
+alloc_objects/count alloc_space/bytes inuse_objects/count inuse_space/bytes
+main;handle;parse 10 1000 1 100
+main;handle;render 5 500 0 0
+main;cache;cache 1 100 1 100
+`
+
+const targetHeap = `
+alloc_objects/count alloc_space/bytes inuse_objects/count inuse_space/bytes
+main;handle;parse 10 1050 1 100
+main;handle;render 1 100 0 0
+main;handle;compress 2 300 0 0
+main;cache;cache 1 100 3 300
+`
+
+func findFunction(t *testing.T, st SampleTypeDiff, name string) FunctionDiff {
+	for _, f := range st.Functions {
+		if f.Function == name {
+			return f
+		}
+	}
+	t.Fatalf("function %s not found in %s diff", name, st.Type)
+	return FunctionDiff{}
+}
+
+func TestDiff(t *testing.T) {
+	base, target := textProfile(t, baseHeap), textProfile(t, targetHeap)
+
+	r, err := Diff(base, target, Options{Threshold: 0.1})
+	require.NoError(t, err)
+	require.Len(t, r.SampleTypes, 2)
+
+	alloc := r.SampleTypes[0]
+	assert.Equal(t, "alloc_space", alloc.Type)
+	assert.Equal(t, "bytes", alloc.Unit)
+	assert.Equal(t, int64(1600), alloc.Base)
+	assert.Equal(t, int64(1550), alloc.Target)
+	// Sorted by decreasing absolute flat delta
+	var names []string
+	for _, f := range alloc.Functions {
+		names = append(names, f.Function)
+	}
+	assert.Equal(t, []string{"render", "compress", "parse", "handle", "main"}, names)
+
+	// Below the threshold
+	parse := findFunction(t, alloc, "parse")
+	assert.Equal(t, int64(50), parse.FlatDelta())
+	assert.False(t, parse.Regression)
+	// New function
+	compress := findFunction(t, alloc, "compress")
+	assert.Equal(t, FunctionDiff{Function: "compress", Type: "alloc_space", TargetFlat: 300, TargetCum: 300, Regression: true}, compress)
+	// Cumulative values only
+	handle := findFunction(t, alloc, "handle")
+	assert.Equal(t, int64(0), handle.FlatDelta())
+	assert.Equal(t, int64(-50), handle.CumDelta())
+	assert.False(t, handle.Regression)
+
+	// Recursive functions are counted once in the cumulative values
+	inuse := r.SampleTypes[1]
+	assert.Equal(t, "inuse_space", inuse.Type)
+	cache := findFunction(t, inuse, "cache")
+	assert.Equal(t, FunctionDiff{Function: "cache", Type: "inuse_space", BaseFlat: 100, TargetFlat: 300, BaseCum: 100, TargetCum: 300, Regression: true}, cache)
+
+	regressions := r.Regressions()
+	require.Len(t, regressions, 2)
+	assert.Equal(t, "compress", regressions[0].Function)
+	assert.Equal(t, "alloc_space", regressions[0].Type)
+	assert.Equal(t, "cache", regressions[1].Function)
+	assert.Equal(t, "inuse_space", regressions[1].Type)
+
+	t.Run("min-delta", func(t *testing.T) {
+		r, err := Diff(base, target, Options{Threshold: 0.1, MinDelta: 250})
+		require.NoError(t, err)
+		require.Len(t, r.Regressions(), 1)
+		assert.Equal(t, "compress", r.Regressions()[0].Function)
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		r, err := Diff(base, target, Options{Threshold: 0.01, SampleTypes: []string{"alloc_space"}})
+		require.NoError(t, err)
+		require.Len(t, r.SampleTypes, 1)
+		assert.True(t, findFunction(t, r.SampleTypes[0], "parse").Regression)
+	})
+
+	t.Run("normalize", func(t *testing.T) {
+		double := textProfile(t, `
+alloc_space/bytes
+main;handle;parse 2000
+main;handle;render 1000
+`)
+		single := textProfile(t, `
+alloc_space/bytes
+main;handle;parse 1000
+main;handle;render 500
+`)
+		r, err := Diff(single, double, Options{Normalize: true})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1500), r.SampleTypes[0].Target)
+		assert.Empty(t, r.SampleTypes[0].Functions)
+	})
+
+	t.Run("no-common-sample-type", func(t *testing.T) {
+		cpu := textProfile(t, `
+samples/count cpu/nanoseconds
+main;handle 1 10000000
+`)
+		_, err := Diff(base, cpu, Options{})
+		require.Error(t, err)
+		_, err = Diff(base, target, Options{SampleTypes: []string{"cpu"}})
+		require.Error(t, err)
+	})
+
+	t.Run("fallback-sample-types", func(t *testing.T) {
+		samples := textProfile(t, `
+samples/count
+main;handle 1
+`)
+		r, err := Diff(samples, samples, Options{})
+		require.NoError(t, err)
+		require.Len(t, r.SampleTypes, 1)
+		assert.Equal(t, "samples", r.SampleTypes[0].Type)
+	})
+}
+
+func TestReport(t *testing.T) {
+	r, err := Diff(textProfile(t, baseHeap), textProfile(t, targetHeap), Options{Threshold: 0.1, SampleTypes: []string{"alloc_space"}})
+	require.NoError(t, err)
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, r.WriteText(&buf, 2))
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 5)
+		assert.Contains(t, lines[0], "alloc_space/bytes: 1600 -> 1550 (-3.1%)")
+		assert.Regexp(t, `-400\s+-80.0%\s+-400\s+-80.0%\s+render$`, lines[2])
+		assert.Regexp(t, `\+300\s+new\s+\+300\s+new\s+REGRESSION\s+compress$`, lines[3])
+		assert.Contains(t, lines[4], "... 3 more")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, r.WriteJSON(&buf))
+		var decoded Report
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, r, &decoded)
+	})
+}
+
+func TestReadProfile(t *testing.T) {
+	dir := t.TempDir()
+	p := textProfile(t, baseHeap)
+
+	pprofPath := filepath.Join(dir, "heap.pprof")
+	var buf bytes.Buffer
+	require.NoError(t, p.Write(&buf))
+	require.NoError(t, os.WriteFile(pprofPath, buf.Bytes(), 0644))
+	read, err := ReadProfile(pprofPath)
+	require.NoError(t, err)
+	assert.Len(t, read.Sample, 3)
+
+	textPath := filepath.Join(dir, "heap.txt")
+	require.NoError(t, os.WriteFile(textPath, []byte(strings.TrimSpace(baseHeap)), 0644))
+	read, err = ReadProfile(textPath)
+	require.NoError(t, err)
+	assert.Len(t, read.Sample, 3)
+
+	invalidPath := filepath.Join(dir, "invalid")
+	require.NoError(t, os.WriteFile(invalidPath, []byte("invalid"), 0644))
+	_, err = ReadProfile(invalidPath)
+	require.Error(t, err)
+}
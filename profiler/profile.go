@@ -50,6 +50,12 @@ const (
 	expGoroutineWaitProfile
 	// MetricsProfile reports top-line metrics associated with user-specified profiles
 	MetricsProfile
+	// GoroutineLeakProfile reports the places creating goroutines suspected
+	// of leaking: the number of goroutines they created grew during several
+	// profiling cycles, or some of them have been blocked for a long time.
+	// It is not enabled by default. Like the goroutine profile, it briefly
+	// stops the world, and it is skipped when there are too many goroutines.
+	GoroutineLeakProfile
 
 	// executionTrace is the runtime/trace execution tracer.
 	// This is private, as this trace requires special explicit configuration and
@@ -92,6 +98,8 @@ var profileTypes = map[ProfileType]profileType{
 			// period so that we're sure to capture the CPU usage of
 			// this library, which mostly happens at the end
 			p.interruptibleSleep(p.cfg.period - p.cfg.cpuDuration)
+			p.cpuMu.Lock()
+			defer p.cpuMu.Unlock()
 			if p.cfg.cpuProfileRate != 0 {
 				// The profile has to be set each time before
 				// profiling is started. Otherwise,
@@ -103,7 +111,16 @@ var profileTypes = map[ProfileType]profileType{
 			if err := p.startCPUProfile(&buf); err != nil {
 				return nil, err
 			}
-			p.interruptibleSleep(p.cfg.cpuDuration)
+			select {
+			case <-p.exit:
+			case <-time.After(p.cfg.cpuDuration):
+			case <-p.cpuPreempt:
+				// An on-demand capture needs the CPU profiler: cut
+				// this profile short rather than making it wait
+				// until the end of the profiling period.
+				p.stopCPUProfile()
+				return buf.Bytes(), nil
+			}
 
 			// We want the CPU profiler to finish last so that it can
 			// properly record all of our profile processing work for
@@ -185,6 +202,11 @@ var profileTypes = map[ProfileType]profileType{
 			return buf.Bytes(), err
 		},
 	},
+	GoroutineLeakProfile: {
+		Name:     "goroutineleak",
+		Filename: "goroutineleaks.pprof",
+		Collect:  collectGoroutineLeakProfile,
+	},
 	executionTrace: {
 		Name:     "execution-trace",
 		Filename: "go.trace",
@@ -192,7 +214,8 @@ var profileTypes = map[ProfileType]profileType{
 			p.lastTrace = time.Now()
 			buf := new(bytes.Buffer)
 			lt := newLimitedTraceCollector(buf, int64(p.cfg.traceConfig.Limit))
-			if err := trace.Start(lt); err != nil {
+			stop, err := p.startExecutionTrace(lt)
+			if err != nil {
 				return nil, err
 			}
 			traceLogCPUProfileRate(p.cfg.cpuProfileRate)
@@ -201,12 +224,30 @@ var profileTypes = map[ProfileType]profileType{
 			case <-time.After(p.cfg.period): // The profiling cycle has ended
 			case <-lt.done: // The trace size limit was exceeded
 			}
-			trace.Stop()
+			stop()
 			return buf.Bytes(), nil
 		},
 	},
 }
 
+// startExecutionTrace starts recording an execution trace to w and returns
+// the function stopping it. The flight recorder of the execution trace
+// triggers is paused meanwhile if both can't be recorded at the same time.
+func (p *profiler) startExecutionTrace(w io.Writer) (stop func(), err error) {
+	resume := func() {}
+	if p.triggers != nil {
+		resume = p.triggers.pauseFlightRecorder()
+	}
+	if err := trace.Start(w); err != nil {
+		resume()
+		return nil, err
+	}
+	return func() {
+		trace.Stop()
+		resume()
+	}, nil
+}
+
 // traceLogCPUProfileRate logs the cpuProfileRate to the execution tracer if
 // its not 0. This gives us a better chance to correctly guess the CPU duration
 // of traceEvCPUSample events. It will not work correctly if the user is
@@ -16,6 +16,7 @@ import (
 	"runtime/pprof"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
@@ -61,6 +62,12 @@ func Start(opts ...Option) error {
 	activeProfiler = p
 	activeProfiler.run()
 	traceprof.SetProfilerEnabled(true)
+	traceprof.SetSpanTagLabels(p.cfg.spanTagLabels, p.cfg.spanTagLabelsMax)
+	if p.triggers != nil {
+		traceprof.SetSpanObserver(p.triggers)
+	} else {
+		traceprof.SetSpanObserver(nil)
+	}
 	return nil
 }
 
@@ -69,9 +76,11 @@ func Start(opts ...Option) error {
 func Stop() {
 	mu.Lock()
 	if activeProfiler != nil {
+		traceprof.SetSpanObserver(nil)
 		activeProfiler.stop()
 		activeProfiler = nil
 		traceprof.SetProfilerEnabled(false)
+		traceprof.SetSpanTagLabels(nil, 0)
 	}
 	mu.Unlock()
 }
@@ -79,16 +88,21 @@ func Stop() {
 // profiler collects and sends preset profiles to the Datadog API at a given frequency
 // using a given configuration.
 type profiler struct {
-	cfg             *config           // profile configuration
-	out             chan batch        // upload queue
-	uploadFunc      func(batch) error // defaults to (*profiler).upload; replaced in tests
-	exit            chan struct{}     // exit signals the profiler to stop; it is closed after stopping
-	stopOnce        sync.Once         // stopOnce ensures the profiler is stopped exactly once.
-	wg              sync.WaitGroup    // wg waits for all goroutines to exit when stopping.
-	met             *metrics          // metric collector state
+	cfg             *config                // profile configuration
+	out             chan batch             // upload queue
+	uploadFunc      func(batch) error      // defaults to (*profiler).upload, or (*profiler).export with an exporter; replaced in tests
+	exit            chan struct{}          // exit signals the profiler to stop; it is closed after stopping
+	stopOnce        sync.Once              // stopOnce ensures the profiler is stopped exactly once.
+	wg              sync.WaitGroup         // wg waits for all goroutines to exit when stopping.
+	met             *metrics               // metric collector state
+	leaks           *goroutineLeakDetector // goroutine leak detector state
 	deltas          map[ProfileType]*fastDeltaProfiler
-	seq             uint64         // seq is the value of the profile_seq tag
-	pendingProfiles sync.WaitGroup // signal that profile collection is done, for stopping CPU profiling
+	seq             atomic.Uint64   // seq is the value of the profile_seq tag of the next batch
+	pendingProfiles sync.WaitGroup  // signal that profile collection is done, for stopping CPU profiling
+	cpuMu           sync.Mutex      // cpuMu is held while the CPU profiler is running
+	cpuPreempt      chan struct{}   // cpuPreempt asks the profiling cycle to stop its CPU profile early
+	capturing       atomic.Bool     // capturing is true while an on-demand capture is running
+	triggers        *traceTriggerer // triggers records execution traces on triggers, nil if not configured
 
 	testHooks testHooks
 
@@ -191,6 +205,9 @@ func newProfiler(opts ...Option) (*profiler, error) {
 	if cfg.uploadTimeout <= 0 {
 		return nil, fmt.Errorf("invalid upload timeout, must be > 0: %s", cfg.uploadTimeout)
 	}
+	if cfg.uploadCompression != gzipCompression && cfg.uploadCompression != zstdCompression {
+		return nil, fmt.Errorf("unsupported upload compression %q, must be gzip or zstd", cfg.uploadCompression)
+	}
 	for pt := range cfg.types {
 		if _, ok := profileTypes[pt]; !ok {
 			return nil, fmt.Errorf("unknown profile type: %d", pt)
@@ -224,18 +241,26 @@ func newProfiler(opts ...Option) (*profiler, error) {
 	cfg.tags = immutable.NewStringSlice(tags)
 
 	p := profiler{
-		cfg:    cfg,
-		out:    make(chan batch, outChannelSize),
-		exit:   make(chan struct{}),
-		met:    newMetrics(),
-		deltas: make(map[ProfileType]*fastDeltaProfiler),
+		cfg:        cfg,
+		out:        make(chan batch, outChannelSize),
+		exit:       make(chan struct{}),
+		met:        newMetrics(),
+		deltas:     make(map[ProfileType]*fastDeltaProfiler),
+		cpuPreempt: make(chan struct{}, 1),
+		leaks:      newGoroutineLeakDetector(cfg.goroutineLeak),
 	}
 	for pt := range cfg.types {
 		if d := profileTypes[pt].DeltaValues; len(d) > 0 {
 			p.deltas[pt] = newFastDeltaProfiler(d...)
 		}
 	}
+	if cfg.traceTriggers != nil {
+		p.triggers = newTraceTriggerer(&p, *cfg.traceTriggers)
+	}
 	p.uploadFunc = p.upload
+	if cfg.exporter != nil {
+		p.uploadFunc = p.export
+	}
 	return &p, nil
 }
 
@@ -252,6 +277,10 @@ func (p *profiler) run() {
 		runtime.SetBlockProfileRate(p.cfg.blockRate)
 	}
 	startTelemetry(p.cfg)
+	if p.triggers != nil {
+		// Start the flight recorder before the periodic execution traces
+		p.triggers.start()
+	}
 	p.wg.Add(1)
 	go func() {
 		defer p.wg.Done()
@@ -265,12 +294,18 @@ func (p *profiler) run() {
 		defer p.wg.Done()
 		p.send()
 	}()
+	if p.triggers != nil {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.triggers.run()
+		}()
+	}
 }
 
 // collect runs the profile types found in the configuration whenever the ticker receives
 // an item.
 func (p *profiler) collect(ticker <-chan time.Time) {
-	defer close(p.out)
 	var (
 		// mu guards completed
 		mu        sync.Mutex
@@ -291,7 +326,7 @@ func (p *profiler) collect(ticker <-chan time.Time) {
 
 	for {
 		bat := batch{
-			seq:   p.seq,
+			seq:   p.seq.Add(1) - 1,
 			host:  p.cfg.hostname,
 			start: now(),
 			extraTags: []string{
@@ -304,7 +339,6 @@ func (p *profiler) collect(ticker <-chan time.Time) {
 			},
 			customAttributes: p.cfg.customProfilerLabels,
 		}
-		p.seq++
 
 		completed = completed[:0]
 		// We need to increment pendingProfiles for every non-CPU
@@ -413,6 +447,7 @@ func (p *profiler) enabledProfileTypes() []ProfileType {
 		GoroutineProfile,
 		expGoroutineWaitProfile,
 		MetricsProfile,
+		GoroutineLeakProfile,
 		executionTrace,
 	}
 	enabled := []ProfileType{}
@@ -0,0 +1,462 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package profiler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	rtmetrics "runtime/metrics"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/traceprof"
+)
+
+const (
+	// DefaultTraceTriggerInterval is the default minimum duration between
+	// two execution traces recorded because of ExecutionTraceTriggers.
+	DefaultTraceTriggerInterval = 5 * time.Minute
+
+	// DefaultTraceTriggerDuration is the default duration of the execution
+	// traces recorded after a trigger when the flight recorder isn't
+	// available.
+	DefaultTraceTriggerDuration = 10 * time.Second
+
+	// maxTraceTriggerSpans bounds the number of in-flight spans tracked to
+	// detect slow spans.
+	maxTraceTriggerSpans = 10000
+
+	// runtimeTriggerInterval is the interval at which the runtime metrics
+	// are checked against their trigger thresholds.
+	runtimeTriggerInterval = time.Second
+
+	// gcPausesMetric is the runtime metric of the GC pauses.
+	gcPausesMetric = "/sched/pauses/total/gc:seconds"
+)
+
+// ExecutionTraceTriggers configures the recording of execution traces when a
+// matching span is slow or fails, or when runtime metrics cross thresholds,
+// in addition to the periodic execution traces. See WithExecutionTraceTriggers.
+type ExecutionTraceTriggers struct {
+	// Spans are the rules of the spans triggering an execution trace.
+	Spans []SpanTraceTrigger
+	// Goroutines triggers an execution trace when the number of goroutines
+	// exceeds it, if positive.
+	Goroutines int
+	// GCPause triggers an execution trace when a garbage collection pause
+	// exceeds it, if positive.
+	GCPause time.Duration
+	// MinInterval is the minimum duration between two triggered execution
+	// traces. Defaults to DefaultTraceTriggerInterval.
+	MinInterval time.Duration
+	// Duration is how long an execution trace is recorded after a trigger
+	// when the flight recorder isn't available. Defaults to
+	// DefaultTraceTriggerDuration.
+	Duration time.Duration
+}
+
+// SpanTraceTrigger is a rule matching the spans triggering an execution
+// trace. A span matches when its service and resource match, and it lasts at
+// least MinDuration or it failed with Error set.
+type SpanTraceTrigger struct {
+	// Service is the service of the span, any if empty.
+	Service string
+	// Resource is the resource of the span, any if empty.
+	Resource string
+	// MinDuration is the duration of the span above which a trace is
+	// recorded, if positive.
+	MinDuration time.Duration
+	// Error triggers a trace when the span finishes with an error.
+	Error bool
+}
+
+// matches returns true if the rule applies to the span, regardless of its
+// duration and error.
+func (r *SpanTraceTrigger) matches(s *traceprof.SpanInfo) bool {
+	return (r.Service == "" || r.Service == s.Service) && (r.Resource == "" || r.Resource == s.Resource)
+}
+
+// flightRecorder continuously records an execution trace in memory, keeping
+// only the most recent events. It is implemented by trace.FlightRecorder as
+// of Go 1.25, and by the flight recorder of golang.org/x/exp/trace before.
+type flightRecorder interface {
+	Start() error
+	Stop()
+	WriteTo(w io.Writer) (int64, error)
+}
+
+// traceTriggerer records execution traces on the triggers of the
+// configuration. With the flight recorder, the recorded trace covers the
+// moments preceding the trigger, and spans are checked when they finish.
+// Otherwise, the trace is recorded after the trigger, and spans are checked
+// while in flight to catch slow spans before they finish.
+type traceTriggerer struct {
+	p   *profiler
+	cfg ExecutionTraceTriggers
+	fr  flightRecorder // nil if unavailable
+
+	frMu     sync.Mutex // frMu guards the state of the flight recorder below
+	frPauses int        // frPauses is the number of execution traces the flight recorder is paused for
+	frClosed bool       // frClosed is true once the flight recorder is stopped for good
+
+	wg        sync.WaitGroup // wg waits for the triggered traces to be recorded
+	mu        sync.Mutex
+	stopped   bool                        // stopped is true once the profiler is stopped
+	last      time.Time                   // last is the time of the last trigger
+	recording bool                        // recording is true while a triggered trace is being recorded
+	inflight  map[uint64]inflightSpan     // inflight are the spans checked while in flight, by span ID
+	gcPauses  *rtmetrics.Float64Histogram // gcPauses is the last read GC pause histogram
+	samples   []rtmetrics.Sample          // samples are the runtime metrics read for the GC pause trigger
+}
+
+// inflightSpan is a span checked while in flight.
+type inflightSpan struct {
+	info      traceprof.SpanInfo
+	threshold time.Duration
+}
+
+func newTraceTriggerer(p *profiler, cfg ExecutionTraceTriggers) *traceTriggerer {
+	if cfg.MinInterval <= 0 {
+		cfg.MinInterval = DefaultTraceTriggerInterval
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = DefaultTraceTriggerDuration
+	}
+	t := &traceTriggerer{
+		p:        p,
+		cfg:      cfg,
+		inflight: make(map[uint64]inflightSpan),
+		samples:  []rtmetrics.Sample{{Name: gcPausesMetric}},
+	}
+	// Keep the slowest matching spans in the flight recorder window
+	var minAge time.Duration
+	for _, r := range cfg.Spans {
+		minAge = max(minAge, r.MinDuration)
+	}
+	t.fr = newFlightRecorder(minAge, p.cfg.traceConfig.Limit)
+	return t
+}
+
+// start starts the flight recorder, if available. It must be called before
+// the triggerer is registered as a span observer.
+func (t *traceTriggerer) start() {
+	if t.fr == nil {
+		return
+	}
+	if err := t.fr.Start(); err != nil {
+		log.Warn("Execution trace triggers: unable to start the flight recorder, recording traces after the triggers instead: %v", err)
+		t.fr = nil
+	}
+}
+
+// run checks the in-flight spans and the runtime metrics against the
+// triggers until the profiler is stopped.
+func (t *traceTriggerer) run() {
+	defer func() {
+		t.mu.Lock()
+		t.stopped = true
+		t.mu.Unlock()
+		t.wg.Wait()
+		if t.fr != nil {
+			t.frMu.Lock()
+			if t.frPauses == 0 {
+				t.fr.Stop()
+			}
+			t.frClosed = true
+			t.frMu.Unlock()
+		}
+	}()
+	interval := runtimeTriggerInterval
+	if t.fr == nil {
+		for _, r := range t.cfg.Spans {
+			if r.MinDuration > 0 {
+				interval = min(interval, max(r.MinDuration/4, 10*time.Millisecond))
+			}
+		}
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	lastRuntimeCheck := now()
+	for {
+		select {
+		case <-t.p.exit:
+			return
+		case <-tick.C:
+		}
+		t.checkInflight()
+		if now().Sub(lastRuntimeCheck) >= runtimeTriggerInterval {
+			lastRuntimeCheck = now()
+			t.checkRuntime()
+		}
+	}
+}
+
+// SpanStarted implements traceprof.SpanObserver.
+func (t *traceTriggerer) SpanStarted(s traceprof.SpanInfo) {
+	t.trackInflight(s)
+}
+
+// SpanResourceChanged implements traceprof.SpanObserver. The rules matching
+// on the resource are evaluated again, as the resource is often only known
+// after the span started, e.g. once the HTTP route is resolved.
+func (t *traceTriggerer) SpanResourceChanged(s traceprof.SpanInfo) {
+	t.trackInflight(s)
+}
+
+// trackInflight starts or stops checking the span while in flight, depending
+// on whether it matches a slow span rule.
+func (t *traceTriggerer) trackInflight(s traceprof.SpanInfo) {
+	if t.fr != nil {
+		// Checked when finished
+		return
+	}
+	var threshold time.Duration
+	for i := range t.cfg.Spans {
+		r := &t.cfg.Spans[i]
+		if r.MinDuration > 0 && r.matches(&s) && (threshold == 0 || r.MinDuration < threshold) {
+			threshold = r.MinDuration
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if threshold == 0 {
+		delete(t.inflight, s.SpanID)
+		return
+	}
+	if _, ok := t.inflight[s.SpanID]; ok || len(t.inflight) < maxTraceTriggerSpans {
+		t.inflight[s.SpanID] = inflightSpan{info: s, threshold: threshold}
+	}
+}
+
+// SpanFinished implements traceprof.SpanObserver.
+func (t *traceTriggerer) SpanFinished(s traceprof.SpanInfo) {
+	if t.fr == nil {
+		t.mu.Lock()
+		delete(t.inflight, s.SpanID)
+		t.mu.Unlock()
+	}
+	for i := range t.cfg.Spans {
+		r := &t.cfg.Spans[i]
+		if !r.matches(&s) {
+			continue
+		}
+		if s.Error && r.Error {
+			t.trigger("span_error", &s)
+			return
+		}
+		// Without the flight recorder, slow spans are caught in flight
+		if t.fr != nil && r.MinDuration > 0 && s.Duration >= r.MinDuration {
+			t.trigger("slow_span", &s)
+			return
+		}
+	}
+}
+
+// checkInflight triggers a trace if an in-flight span exceeded its
+// threshold.
+func (t *traceTriggerer) checkInflight() {
+	var slow *traceprof.SpanInfo
+	n := now()
+	t.mu.Lock()
+	for id, s := range t.inflight {
+		if n.Sub(s.info.Start) >= s.threshold {
+			delete(t.inflight, id)
+			slow = &s.info
+			break
+		}
+	}
+	t.mu.Unlock()
+	if slow != nil {
+		t.trigger("slow_span", slow)
+	}
+}
+
+// checkRuntime triggers a trace if a runtime metric crossed its threshold.
+func (t *traceTriggerer) checkRuntime() {
+	if t.cfg.Goroutines > 0 && runtime.NumGoroutine() > t.cfg.Goroutines {
+		t.trigger("goroutines", nil)
+		return
+	}
+	if t.cfg.GCPause > 0 && t.maxGCPause() >= t.cfg.GCPause {
+		t.trigger("gc_pause", nil)
+	}
+}
+
+// maxGCPause returns a lower bound of the longest GC pause since the
+// previous call.
+func (t *traceTriggerer) maxGCPause() time.Duration {
+	rtmetrics.Read(t.samples)
+	if t.samples[0].Value.Kind() != rtmetrics.KindFloat64Histogram {
+		return 0
+	}
+	h := t.samples[0].Value.Float64Histogram()
+	prev := t.gcPauses
+	t.gcPauses = &rtmetrics.Float64Histogram{
+		Counts:  append([]uint64(nil), h.Counts...),
+		Buckets: h.Buckets,
+	}
+	if prev == nil || len(prev.Counts) != len(h.Counts) {
+		return 0
+	}
+	for i := len(h.Counts) - 1; i >= 0; i-- {
+		if h.Counts[i] > prev.Counts[i] {
+			// The lower bound of the bucket
+			return time.Duration(h.Buckets[i] * float64(time.Second))
+		}
+	}
+	return 0
+}
+
+// pauseFlightRecorder stops the flight recorder while another execution
+// trace is recorded, when the runtime can't record both at once, and returns
+// the function resuming it once that trace is over.
+func (t *traceTriggerer) pauseFlightRecorder() (resume func()) {
+	if t.fr == nil || !flightRecorderExclusive {
+		return func() {}
+	}
+	t.frMu.Lock()
+	defer t.frMu.Unlock()
+	if t.frClosed {
+		return func() {}
+	}
+	if t.frPauses == 0 {
+		t.fr.Stop()
+	}
+	t.frPauses++
+	return func() {
+		t.frMu.Lock()
+		defer t.frMu.Unlock()
+		t.frPauses--
+		if t.frPauses > 0 || t.frClosed {
+			return
+		}
+		if err := t.fr.Start(); err != nil {
+			log.Warn("Execution trace triggers: unable to resume the flight recorder: %v", err)
+		}
+	}
+}
+
+// flightRecorderPaused returns true while the flight recorder is paused.
+func (t *traceTriggerer) flightRecorderPaused() bool {
+	t.frMu.Lock()
+	defer t.frMu.Unlock()
+	return t.frPauses > 0
+}
+
+// trigger records an execution trace, unless one was triggered less than
+// MinInterval ago or the flight recorder is paused. span is the span which
+// triggered the trace, if any.
+func (t *traceTriggerer) trigger(reason string, span *traceprof.SpanInfo) {
+	paused := t.fr != nil && t.flightRecorderPaused()
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return
+	}
+	if paused || t.recording || (!t.last.IsZero() && now().Sub(t.last) < t.cfg.MinInterval) {
+		t.mu.Unlock()
+		t.p.cfg.statsd.Count("datadog.profiling.go.trace_trigger_skipped", 1, append(t.p.cfg.tags.Slice(), "reason:"+reason), 1)
+		return
+	}
+	t.last = now()
+	t.recording = true
+	t.wg.Add(1)
+	t.mu.Unlock()
+
+	go func() {
+		defer t.wg.Done()
+		defer func() {
+			t.mu.Lock()
+			t.recording = false
+			t.mu.Unlock()
+		}()
+		if err := t.record(reason, span); err == errFlightRecorderPaused {
+			t.p.cfg.statsd.Count("datadog.profiling.go.trace_trigger_skipped", 1, append(t.p.cfg.tags.Slice(), "reason:"+reason), 1)
+		} else if err != nil && err != errProfilerStopped {
+			log.Error("Execution trace triggered by %s: %v", reason, err)
+			t.p.cfg.statsd.Count("datadog.profiling.go.collect_error", 1, append(t.p.cfg.tags.Slice(), executionTrace.Tag()), 1)
+		}
+	}()
+}
+
+// errFlightRecorderPaused is returned when a trace is triggered while the
+// flight recorder is paused.
+var errFlightRecorderPaused = errors.New("flight recorder paused")
+
+// writeFlightRecorder writes the content of the flight recorder to w, unless
+// it is paused.
+func (t *traceTriggerer) writeFlightRecorder(w io.Writer) error {
+	t.frMu.Lock()
+	defer t.frMu.Unlock()
+	if t.frPauses > 0 {
+		return errFlightRecorderPaused
+	}
+	_, err := t.fr.WriteTo(w)
+	return err
+}
+
+// record records the execution trace of a trigger and enqueues it for upload.
+func (t *traceTriggerer) record(reason string, span *traceprof.SpanInfo) error {
+	start := now()
+	var prof *profile
+	if t.fr != nil {
+		var buf bytes.Buffer
+		if err := t.writeFlightRecorder(&buf); err != nil {
+			return err
+		}
+		prof = &profile{name: executionTrace.Filename(), pt: executionTrace, data: buf.Bytes()}
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), t.cfg.Duration)
+		defer cancel()
+		go func() {
+			select {
+			case <-t.p.exit:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		var err error
+		if prof, err = t.p.captureExecutionTrace(ctx); err != nil {
+			return err
+		}
+	}
+	select {
+	case <-t.p.exit:
+		return errProfilerStopped
+	default:
+	}
+
+	bat := batch{
+		seq:   t.p.seq.Add(1) - 1,
+		host:  t.p.cfg.hostname,
+		start: start,
+		end:   now(),
+		extraTags: []string{
+			"_dd.profiler.trace_trigger:" + reason,
+			"go_execution_traced:yes",
+			pgoTag(),
+		},
+		customAttributes: t.p.cfg.customProfilerLabels,
+	}
+	if span != nil {
+		// Commas separate the tags of the uploaded profiles
+		bat.extraTags = append(bat.extraTags,
+			fmt.Sprintf("trigger_span_id:%d", span.SpanID),
+			fmt.Sprintf("trigger_trace_id:%d", span.TraceID),
+			"trigger_service:"+strings.ReplaceAll(span.Service, ",", "_"),
+		)
+	}
+	bat.addProfile(prof)
+	t.p.cfg.statsd.Count("datadog.profiling.go.trace_triggered", 1, append(t.p.cfg.tags.Slice(), "reason:"+reason), 1)
+	t.p.enqueueUpload(bat)
+	return nil
+}
@@ -0,0 +1,315 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024 Datadog, Inc.
+
+package profiler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/traceprof"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFlightRecorder writes a fixed trace.
+type fakeFlightRecorder struct{}
+
+func (fakeFlightRecorder) Start() error { return nil }
+func (fakeFlightRecorder) Stop()        {}
+func (fakeFlightRecorder) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, "flight-recorded trace")
+	return int64(n), err
+}
+
+func triggeredBatch(t *testing.T, p *profiler) batch {
+	t.Helper()
+	select {
+	case bat := <-p.out:
+		return bat
+	case <-time.After(10 * time.Second):
+		t.Fatal("no triggered trace")
+		return batch{}
+	}
+}
+
+func noTriggeredBatch(t *testing.T, p *profiler) {
+	t.Helper()
+	select {
+	case bat := <-p.out:
+		t.Fatalf("unexpected triggered trace: %v", bat.extraTags)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestTraceTriggers(t *testing.T) {
+	triggers := ExecutionTraceTriggers{
+		Spans: []SpanTraceTrigger{
+			{Service: "web", Resource: "GET /slow", MinDuration: 100 * time.Millisecond},
+			{Service: "web", Error: true},
+		},
+		Duration: 10 * time.Millisecond,
+	}
+	newTriggerer := func(t *testing.T, fr flightRecorder) *traceTriggerer {
+		p, err := unstartedProfiler(WithExecutionTraceTriggers(triggers))
+		require.NoError(t, err)
+		t.Cleanup(p.stop)
+		require.NotNil(t, p.triggers)
+		p.triggers.fr = fr
+		return p.triggers
+	}
+	slow := traceprof.SpanInfo{
+		SpanID:   1,
+		TraceID:  2,
+		Service:  "web",
+		Resource: "GET /slow",
+		Start:    time.Now().Add(-time.Second),
+		Duration: time.Second,
+	}
+
+	t.Run("flight-recorder", func(t *testing.T) {
+		tt := newTriggerer(t, fakeFlightRecorder{})
+
+		fast := slow
+		fast.Duration = time.Millisecond
+		tt.SpanFinished(fast)
+		other := slow
+		other.Resource = "GET /other"
+		tt.SpanFinished(other)
+		noTriggeredBatch(t, tt.p)
+
+		tt.SpanFinished(slow)
+		bat := triggeredBatch(t, tt.p)
+		assert.Contains(t, bat.extraTags, "_dd.profiler.trace_trigger:slow_span")
+		assert.Contains(t, bat.extraTags, "go_execution_traced:yes")
+		assert.Contains(t, bat.extraTags, "trigger_span_id:1")
+		assert.Contains(t, bat.extraTags, "trigger_trace_id:2")
+		assert.Contains(t, bat.extraTags, "trigger_service:web")
+		require.Len(t, bat.profiles, 1)
+		assert.Equal(t, "go.trace", bat.profiles[0].name)
+		assert.Equal(t, []byte("flight-recorded trace"), bat.profiles[0].data)
+
+		// Rate limited
+		failed := other
+		failed.Error = true
+		tt.SpanFinished(failed)
+		noTriggeredBatch(t, tt.p)
+
+		tt.mu.Lock()
+		tt.last = time.Time{}
+		tt.mu.Unlock()
+		tt.SpanFinished(failed)
+		bat = triggeredBatch(t, tt.p)
+		assert.Contains(t, bat.extraTags, "_dd.profiler.trace_trigger:span_error")
+	})
+
+	t.Run("in-flight", func(t *testing.T) {
+		tt := newTriggerer(t, nil)
+
+		other := slow
+		other.SpanID = 3
+		other.Service = "db"
+		tt.SpanStarted(other)
+		tt.SpanStarted(slow)
+		assert.Len(t, tt.inflight, 1)
+		// Slow spans are only caught in flight
+		tt.SpanFinished(slow)
+		assert.Empty(t, tt.inflight)
+		noTriggeredBatch(t, tt.p)
+
+		// The resource is set after the span started
+		started := slow
+		started.Resource = ""
+		tt.SpanStarted(started)
+		assert.Empty(t, tt.inflight)
+		tt.SpanResourceChanged(slow)
+		assert.Len(t, tt.inflight, 1)
+		tt.checkInflight()
+		bat := triggeredBatch(t, tt.p)
+		assert.Contains(t, bat.extraTags, "_dd.profiler.trace_trigger:slow_span")
+		assert.Contains(t, bat.extraTags, "trigger_span_id:1")
+		require.Len(t, bat.profiles, 1)
+		assert.NotEmpty(t, bat.profiles[0].data)
+		assert.Empty(t, tt.inflight)
+	})
+
+	t.Run("goroutines", func(t *testing.T) {
+		tt := newTriggerer(t, fakeFlightRecorder{})
+		tt.checkRuntime()
+		noTriggeredBatch(t, tt.p)
+
+		tt.cfg.Goroutines = 1
+		tt.checkRuntime()
+		bat := triggeredBatch(t, tt.p)
+		assert.Contains(t, bat.extraTags, "_dd.profiler.trace_trigger:goroutines")
+		assert.NotContains(t, bat.extraTags, "trigger_span_id:1")
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		tt := newTriggerer(t, fakeFlightRecorder{})
+		tt.p.wg.Add(1)
+		go func() {
+			defer tt.p.wg.Done()
+			tt.run()
+		}()
+		tt.p.stop()
+		tt.SpanFinished(slow)
+		noTriggeredBatch(t, tt.p)
+	})
+}
+
+func TestTraceTriggersGCPause(t *testing.T) {
+	p, err := unstartedProfiler(WithExecutionTraceTriggers(ExecutionTraceTriggers{GCPause: time.Nanosecond}))
+	require.NoError(t, err)
+	defer p.stop()
+	tt := p.triggers
+	tt.fr = fakeFlightRecorder{}
+
+	// The first read is the baseline
+	assert.Zero(t, tt.maxGCPause())
+	runtime.GC()
+	tt.checkRuntime()
+	bat := triggeredBatch(t, p)
+	assert.Contains(t, bat.extraTags, "_dd.profiler.trace_trigger:gc_pause")
+}
+
+func TestTraceTriggersUpload(t *testing.T) {
+	profiles := startTestProfiler(t, 1,
+		WithProfileTypes(HeapProfile),
+		WithPeriod(time.Hour),
+		WithExecutionTraceTriggers(ExecutionTraceTriggers{
+			Spans:    []SpanTraceTrigger{{Error: true}},
+			Duration: 10 * time.Millisecond,
+		}),
+	)
+	o := traceprof.GetSpanObserver()
+	require.NotNil(t, o)
+	info := traceprof.SpanInfo{SpanID: 1, TraceID: 2, Service: "web", Start: time.Now(), Error: true}
+	o.SpanStarted(info)
+	o.SpanFinished(info)
+
+	profile := <-profiles
+	assert.Contains(t, profile.tags, "_dd.profiler.trace_trigger:span_error")
+	assert.Contains(t, profile.tags, "trigger_span_id:1")
+	assert.NotEmpty(t, profile.attachments["go.trace"])
+
+	Stop()
+	assert.Nil(t, traceprof.GetSpanObserver())
+}
+
+func TestFlightRecorder(t *testing.T) {
+	fr := newFlightRecorder(time.Second, 1<<20)
+	require.NotNil(t, fr)
+	require.NoError(t, fr.Start())
+	defer fr.Stop()
+	time.Sleep(10 * time.Millisecond)
+	var buf bytes.Buffer
+	n, err := fr.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	assert.NotZero(t, n)
+}
+
+// pausableFlightRecorder records whether it is started.
+type pausableFlightRecorder struct {
+	fakeFlightRecorder
+	started bool
+}
+
+func (r *pausableFlightRecorder) Start() error { r.started = true; return nil }
+func (r *pausableFlightRecorder) Stop()        { r.started = false }
+
+func TestPauseFlightRecorder(t *testing.T) {
+	if !flightRecorderExclusive {
+		t.Skip("the flight recorder is only paused before Go 1.25")
+	}
+	p, err := unstartedProfiler(WithExecutionTraceTriggers(ExecutionTraceTriggers{
+		Spans: []SpanTraceTrigger{{Error: true}},
+	}))
+	require.NoError(t, err)
+	fr := &pausableFlightRecorder{}
+	tt := p.triggers
+	tt.fr = fr
+	tt.start()
+	require.True(t, fr.started)
+
+	resume1 := tt.pauseFlightRecorder()
+	resume2 := tt.pauseFlightRecorder()
+	assert.False(t, fr.started)
+
+	// Triggers are skipped while paused
+	tt.SpanFinished(traceprof.SpanInfo{SpanID: 1, Error: true})
+	noTriggeredBatch(t, p)
+
+	resume1()
+	assert.False(t, fr.started)
+	resume2()
+	assert.True(t, fr.started)
+
+	tt.SpanFinished(traceprof.SpanInfo{SpanID: 2, Error: true})
+	bat := triggeredBatch(t, p)
+	assert.Contains(t, bat.extraTags, "_dd.profiler.trace_trigger:span_error")
+}
+
+// Test that the periodic and on-demand execution traces are recorded along
+// with the flight recorder of the triggers.
+func TestTraceTriggersWithExecutionTraces(t *testing.T) {
+	triggers := WithExecutionTraceTriggers(ExecutionTraceTriggers{
+		Spans:    []SpanTraceTrigger{{Error: true}},
+		Duration: 10 * time.Millisecond,
+	})
+	// triggeredProfile triggers a trace with a failed span and returns it
+	triggeredProfile := func(t *testing.T, profiles <-chan profileMeta) profileMeta {
+		t.Helper()
+		info := traceprof.SpanInfo{SpanID: 1, TraceID: 2, Service: "web", Start: time.Now(), Error: true}
+		traceprof.GetSpanObserver().SpanFinished(info)
+		timeout := time.After(10 * time.Second)
+		for {
+			select {
+			case profile := <-profiles:
+				if slices.Contains(profile.tags, "_dd.profiler.trace_trigger:span_error") {
+					return profile
+				}
+			case <-timeout:
+				t.Fatal("no triggered trace")
+				return profileMeta{}
+			}
+		}
+	}
+
+	t.Run("periodic", func(t *testing.T) {
+		t.Setenv("DD_PROFILING_EXECUTION_TRACE_ENABLED", "true")
+		// Only the first profiling cycle records an execution trace
+		t.Setenv("DD_PROFILING_EXECUTION_TRACE_PERIOD", "1h")
+		profiles := startTestProfiler(t, 10, WithProfileTypes(), WithPeriod(50*time.Millisecond), triggers)
+
+		profile := <-profiles
+		assert.NotEmpty(t, profile.attachments["go.trace"])
+		assert.Contains(t, profile.tags, "go_execution_traced:yes")
+
+		profile = triggeredProfile(t, profiles)
+		assert.NotEmpty(t, profile.attachments["go.trace"])
+	})
+
+	t.Run("capture", func(t *testing.T) {
+		t.Setenv("DD_PROFILING_EXECUTION_TRACE_ENABLED", "false")
+		profiles := startTestProfiler(t, 10, WithProfileTypes(), WithPeriod(time.Hour), triggers)
+
+		c, err := CaptureNow(context.Background(), nil, 50*time.Millisecond, WithCaptureExecutionTrace())
+		require.NoError(t, err)
+		require.Len(t, c.Profiles, 1)
+		assert.Equal(t, "go.trace", c.Profiles[0].Name)
+		assert.NotEmpty(t, c.Profiles[0].Data)
+
+		profile := triggeredProfile(t, profiles)
+		assert.NotEmpty(t, profile.attachments["go.trace"])
+	})
+}
@@ -33,6 +33,7 @@ var errOldAgent = errors.New("Datadog Agent is not accepting profiles. Agent-bas
 // upload tries to upload a batch of profiles. It has retry and backoff mechanisms.
 func (p *profiler) upload(bat batch) error {
 	statsd := p.cfg.statsd
+	bat = p.prepareUpload(bat)
 	var err error
 	for i := 0; i < maxRetries; i++ {
 		select {
@@ -51,19 +52,57 @@ func (p *profiler) upload(bat batch) error {
 		}
 		if err != nil {
 			statsd.Count("datadog.profiling.go.upload_error", 1, nil, 1)
+			p.countDropped(bat, "upload_error")
 		} else {
 			statsd.Count("datadog.profiling.go.upload_success", 1, nil, 1)
-			var b int64
-			for _, p := range bat.profiles {
-				b += int64(len(p.data))
+			for _, prof := range bat.profiles {
+				statsd.Count("datadog.profiling.go.uploaded_profile_bytes", int64(len(prof.data)), []string{prof.pt.Tag()}, 1)
 			}
-			statsd.Count("datadog.profiling.go.uploaded_profile_bytes", b, nil, 1)
 		}
 		return err
 	}
+	p.countDropped(bat, "upload_error")
 	return fmt.Errorf("failed after %d retries, last error was: %v", maxRetries, err)
 }
 
+// prepareUpload returns the batch to upload, with its profiles compressed
+// with the configured upload compression and downsampled to fit within their
+// size limit, or dropped if they can't. It is done once, before the upload
+// attempts, and doesn't modify the profiles of bat.
+func (p *profiler) prepareUpload(bat batch) batch {
+	c := p.cfg.uploadCompression
+	profiles := make([]*profile, 0, len(bat.profiles))
+	for _, prof := range bat.profiles {
+		data, err := c.compress(prof.data)
+		if err != nil {
+			log.Error("Compressing %s with %s: %v; uploading it as is.", prof.name, c, err)
+			data = prof.data
+		}
+		limit := p.cfg.maxProfileSizeOf(prof.pt)
+		if limit > 0 && len(data) > limit && isGzip(prof.data) {
+			downsampled, err := downsampleProfile(prof.data, len(data), limit, c)
+			if err != nil {
+				log.Error("Dropping %s of %d bytes exceeding the limit of %d bytes: %v", prof.name, len(data), limit, err)
+				p.cfg.statsd.Count("datadog.profiling.go.dropped_profile_bytes", int64(len(data)), []string{prof.pt.Tag(), "reason:size_limit"}, 1)
+				continue
+			}
+			p.cfg.statsd.Count("datadog.profiling.go.downsampled_profiles", 1, []string{prof.pt.Tag()}, 1)
+			data = downsampled
+		}
+		profiles = append(profiles, &profile{name: prof.name, pt: prof.pt, data: data})
+	}
+	bat.profiles = profiles
+	return bat
+}
+
+// countDropped counts the bytes of the profiles of bat which were not
+// uploaded for the given reason.
+func (p *profiler) countDropped(bat batch, reason string) {
+	for _, prof := range bat.profiles {
+		p.cfg.statsd.Count("datadog.profiling.go.dropped_profile_bytes", int64(len(prof.data)), []string{prof.pt.Tag(), "reason:" + reason}, 1)
+	}
+}
+
 // retriableError is an error returned by the server which may be retried at a later time.
 type retriableError struct{ err error }
 
@@ -73,35 +112,12 @@ func (e retriableError) Error() string { return e.err.Error() }
 // doRequest makes an HTTP POST request to the Datadog Profiling API with the
 // given profile.
 func (p *profiler) doRequest(bat batch) error {
-	tags := append(p.cfg.tags.Slice(),
-		fmt.Sprintf("service:%s", p.cfg.service),
-		// The profile_seq tag can be used to identify the first profile
-		// uploaded by a given runtime-id, identify missing profiles, etc.. See
-		// PROF-5612 (internal) for more details.
-		fmt.Sprintf("profile_seq:%d", bat.seq),
-	)
-	tags = append(tags, bat.extraTags...)
-	// If the user did not configure an "env" in the client, we should omit
-	// the tag so that the agent has a chance to supply a default tag.
-	// Otherwise, the tag supplied by the client will have priority.
-	if p.cfg.env != "" {
-		tags = append(tags, fmt.Sprintf("env:%s", p.cfg.env))
-	}
-	contentType, body, err := encode(bat, tags)
+	contentType, body, err := encode(bat, p.batchTags(bat))
 	if err != nil {
 		return err
 	}
-	funcExit := make(chan struct{})
-	defer close(funcExit)
-	// uploadTimeout is guaranteed to be >= 0, see newProfiler.
-	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.uploadTimeout)
-	go func() {
-		select {
-		case <-p.exit:
-		case <-funcExit:
-		}
-		cancel()
-	}()
+	ctx, cancel := p.uploadContext()
+	defer cancel()
 	req, err := http.NewRequestWithContext(ctx, "POST", p.cfg.targetURL, body)
 	if err != nil {
 		return err
@@ -137,6 +153,41 @@ func (p *profiler) doRequest(bat batch) error {
 	return errors.New(resp.Status)
 }
 
+// batchTags returns the tags of the given batch of profiles.
+func (p *profiler) batchTags(bat batch) []string {
+	tags := append(p.cfg.tags.Slice(),
+		fmt.Sprintf("service:%s", p.cfg.service),
+		// The profile_seq tag can be used to identify the first profile
+		// uploaded by a given runtime-id, identify missing profiles, etc.. See
+		// PROF-5612 (internal) for more details.
+		fmt.Sprintf("profile_seq:%d", bat.seq),
+	)
+	tags = append(tags, bat.extraTags...)
+	// If the user did not configure an "env" in the client, we should omit
+	// the tag so that the agent has a chance to supply a default tag.
+	// Otherwise, the tag supplied by the client will have priority.
+	if p.cfg.env != "" {
+		tags = append(tags, fmt.Sprintf("env:%s", p.cfg.env))
+	}
+	return tags
+}
+
+// uploadContext returns a context bounded by the upload timeout which is
+// canceled when the profiler stops. The returned cancel function must be
+// called once the upload is done.
+func (p *profiler) uploadContext() (context.Context, context.CancelFunc) {
+	// uploadTimeout is guaranteed to be >= 0, see newProfiler.
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.uploadTimeout)
+	go func() {
+		select {
+		case <-p.exit:
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+	return ctx, cancel
+}
+
 type uploadEvent struct {
 	Start            string            `json:"start"`
 	End              string            `json:"end"`